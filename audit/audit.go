@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var events repository.AuditRepository
+
+// Init hands the audit log its repository , main() calls it once after the store is built
+func Init(repo repository.AuditRepository) {
+	events = repo
+}
+
+// actions recorded for the entities , the entity type is added in front , for example invoice.update
+const (
+	ACTION_CREATE  = "create"
+	ACTION_UPDATE  = "update"
+	ACTION_DELETE  = "delete"
+	ACTION_RESTORE = "restore"
+)
+
+// Record appends an event to the audit log , the id and the time are filled in here and events without an actor are recorded as done by the system
+func Record(ctx context.Context, event models.AuditEvent) error {
+
+	if event.Actor_type == "" {
+		event.Actor_type = "system"
+	}
+
+	event.ID = primitive.NewObjectID()
+	event.Audit_id = event.ID.Hex()
+	event.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	err := events.Insert(ctx, &event)
+
+	if err != nil {
+		// the action itself already happened , losing the audit entry is logged instead of failing the request
+		log.Println("audit event could not be recorded:", event.Action, event.Entity_type, event.Entity_id, err)
+	}
+
+	return err
+}
+
+// Filter narrows down the audit log , empty fields are not filtered on
+type Filter = repository.AuditFilter
+
+// Find returns a page of the events matching the filter , newest first , together with the number of all matching events
+func Find(ctx context.Context, filter Filter, skip int64, limit int64) ([]models.AuditEvent, int64, error) {
+	return events.Find(ctx, filter, skip, limit)
+}
+
+// Snapshot turns an entity into the document stored in Before and After , with the same field names it has in mongodb.
+// the caller removes the fields that must not end up in the log
+func Snapshot(entity interface{}) map[string]interface{} {
+
+	data, err := bson.Marshal(entity)
+
+	if err != nil {
+		return nil
+	}
+
+	var snapshot bson.M
+
+	if err := bson.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+
+	return snapshot
+}
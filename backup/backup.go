@@ -0,0 +1,391 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-restaurent-management-system/repository"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// MANIFEST_VERSION is raised when the layout of an export changes , import refuses the versions it doesn't know
+const MANIFEST_VERSION = 1
+
+const MANIFEST_FILE = "manifest.json"
+
+// Manifest is written next to the files of an export , it lists them with the number of records and a checksum
+type Manifest struct {
+	Version      int          `json:"version"`
+	Created_at   time.Time    `json:"created_at"`
+	Format       string       `json:"format"`
+	Branch_id    string       `json:"branch_id,omitempty"`
+	With_secrets bool         `json:"with_secrets"`
+	Collections  []Collection `json:"collections"`
+}
+
+type Collection struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Count  int    `json:"count"`
+	Sha256 string `json:"sha256"`
+}
+
+// ExportOptions are the flags of restaurantctl export , an empty Branch_id exports every branch
+type ExportOptions struct {
+	Format       string
+	Branch_id    string
+	With_secrets bool
+}
+
+// Result is what an import did with the records of one collection , a dry run counts what it would have done
+type Result struct {
+	Collection string
+	Created    int
+	Updated    int
+	Unchanged  int
+}
+
+// collection is implemented by kind[T] , it hides the type of the records from Export and Import
+type collection interface {
+	collectionName() string
+	export(ctx context.Context, store *repository.Store, opts ExportOptions, enc encoder) (int, error)
+	load(ctx context.Context, store *repository.Store, dec decoder, dryRun bool) (Result, error)
+	recordType() reflect.Type
+}
+
+// Export writes every collection into a file of dir and the manifest last , dir is created when it is missing.
+// a directory that already holds an export is refused so that nothing is overwritten
+func Export(ctx context.Context, store *repository.Store, dir string, opts ExportOptions) (*Manifest, error) {
+
+	if opts.Format == "" {
+		opts.Format = NDJSON
+	}
+
+	if opts.Format != NDJSON && opts.Format != CSV {
+		return nil, fmt.Errorf("unknown format %q , use %s or %s", opts.Format, NDJSON, CSV)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, MANIFEST_FILE)); err == nil {
+		return nil, fmt.Errorf("%s already holds an export", dir)
+	}
+
+	manifest := &Manifest{Version: MANIFEST_VERSION, Created_at: time.Now().UTC(), Format: opts.Format, Branch_id: opts.Branch_id, With_secrets: opts.With_secrets, Collections: []Collection{}}
+
+	for _, c := range COLLECTIONS {
+
+		exported, err := exportFile(ctx, store, c, dir, opts)
+
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", c.collectionName(), err)
+		}
+
+		manifest.Collections = append(manifest.Collections, *exported)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, os.WriteFile(filepath.Join(dir, MANIFEST_FILE), append(data, '\n'), 0o644)
+}
+
+func exportFile(ctx context.Context, store *repository.Store, c collection, dir string, opts ExportOptions) (*Collection, error) {
+
+	name := c.collectionName() + "." + opts.Format
+
+	file, err := os.Create(filepath.Join(dir, name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	// the checksum is taken while the file is written
+	hash := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(file, hash))
+
+	enc := newEncoder(opts.Format, w, c.recordType())
+
+	count, err := c.export(ctx, store, opts, enc)
+
+	if err == nil {
+		err = enc.flush()
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = file.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Collection{Name: c.collectionName(), File: name, Count: count, Sha256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// Import upserts the records of the export in dir by their business id , like food_id or menu_id.
+// a record that is missing is created , one that differs is updated and an identical one is left alone , so importing the same export again changes nothing.
+// every checksum is verified before the first write , with dryRun nothing is written at all
+func Import(ctx context.Context, store *repository.Store, dir string, dryRun bool) ([]Result, error) {
+
+	manifest, err := ReadManifest(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]collection{}
+
+	for _, c := range COLLECTIONS {
+		known[c.collectionName()] = c
+	}
+
+	for _, exported := range manifest.Collections {
+
+		if known[exported.Name] == nil {
+			return nil, fmt.Errorf("the export holds the unknown collection %q", exported.Name)
+		}
+
+		if err := verify(dir, exported); err != nil {
+			return nil, err
+		}
+	}
+
+	results := []Result{}
+
+	// the collections are imported in the order of COLLECTIONS and not of the manifest , the records that are referenced come first
+
+	for _, c := range COLLECTIONS {
+		for _, exported := range manifest.Collections {
+			if exported.Name == c.collectionName() {
+
+				result, err := importFile(ctx, store, c, dir, manifest.Format, exported, dryRun)
+
+				if err != nil {
+					return results, fmt.Errorf("importing %s: %w", exported.Name, err)
+				}
+
+				results = append(results, result)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// ReadManifest reads the manifest of the export in dir and checks that this version can import it
+func ReadManifest(dir string) (*Manifest, error) {
+
+	data, err := os.ReadFile(filepath.Join(dir, MANIFEST_FILE))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", MANIFEST_FILE, err)
+	}
+
+	if manifest.Version != MANIFEST_VERSION {
+		return nil, fmt.Errorf("the export has version %d of the manifest , this version can only import %d", manifest.Version, MANIFEST_VERSION)
+	}
+
+	if manifest.Format != NDJSON && manifest.Format != CSV {
+		return nil, fmt.Errorf("the export has the unknown format %q", manifest.Format)
+	}
+
+	return &manifest, nil
+}
+
+func verify(dir string, exported Collection) error {
+
+	// the name comes from the manifest , it is not allowed to point out of the directory
+	if filepath.Base(exported.File) != exported.File {
+		return fmt.Errorf("%q is not a file of the export", exported.File)
+	}
+
+	file, err := os.Open(filepath.Join(dir, exported.File))
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != exported.Sha256 {
+		return fmt.Errorf("the checksum of %s doesn't match the manifest", exported.File)
+	}
+
+	return nil
+}
+
+func importFile(ctx context.Context, store *repository.Store, c collection, dir string, format string, exported Collection, dryRun bool) (Result, error) {
+
+	file, err := os.Open(filepath.Join(dir, exported.File))
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	defer file.Close()
+
+	dec, err := newDecoder(format, bufio.NewReader(file), c.recordType())
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	return c.load(ctx, store, dec, dryRun)
+}
+
+func (k kind[T]) collectionName() string {
+	return k.name
+}
+
+func (k kind[T]) recordType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (k kind[T]) export(ctx context.Context, store *repository.Store, opts ExportOptions, enc encoder) (int, error) {
+
+	records, err := k.list(ctx, store, opts.Branch_id)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range records {
+
+		if k.strip != nil && !opts.With_secrets {
+			k.strip(&records[i])
+		}
+
+		if err := enc.encode(&records[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(records), nil
+}
+
+func (k kind[T]) load(ctx context.Context, store *repository.Store, dec decoder, dryRun bool) (Result, error) {
+
+	result := Result{Collection: k.name}
+
+	for line := 1; ; line++ {
+
+		var record T
+
+		more, err := dec.decode(&record)
+
+		if err != nil {
+			return result, fmt.Errorf("record %d: %w", line, err)
+		}
+
+		if !more {
+			return result, nil
+		}
+
+		if err := k.upsert(ctx, store, &record, dryRun, &result); err != nil {
+			return result, fmt.Errorf("record %d , %s: %w", line, k.id(&record), err)
+		}
+	}
+}
+
+func (k kind[T]) upsert(ctx context.Context, store *repository.Store, record *T, dryRun bool, result *Result) error {
+
+	if k.id(record) == "" {
+		return errors.New("the record has no id")
+	}
+
+	stored, err := k.get(ctx, store, k.id(record))
+
+	if errors.Is(err, repository.ErrNotFound) {
+
+		result.Created++
+
+		if dryRun {
+			return nil
+		}
+
+		return k.create(ctx, store, record)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// the stored record keeps its _id and its version , the version is raised by the update as with every other write
+
+	if k.keep != nil {
+		k.keep(record, stored)
+	}
+
+	*k.objectId(record) = *k.objectId(stored)
+	*k.version(record) = *k.version(stored)
+
+	same, err := equal(record, stored)
+
+	if err != nil {
+		return err
+	}
+
+	if same {
+		result.Unchanged++
+		return nil
+	}
+
+	result.Updated++
+
+	if dryRun {
+		return nil
+	}
+
+	return k.update(ctx, store, record)
+}
+
+// equal compares the records the way they are exported , the times of mongodb and sql only have to match to the precision JSON keeps
+func equal(a interface{}, b interface{}) (bool, error) {
+
+	left, err := json.Marshal(a)
+
+	if err != nil {
+		return false, err
+	}
+
+	right, err := json.Marshal(b)
+
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(left, right), nil
+}
@@ -0,0 +1,241 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"go-restaurent-management-system/repository/memrepo"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newRestaurant is a store with a branch , a waiter who has a password , a menu and a food
+func newRestaurant(t *testing.T) *repository.Store {
+
+	ctx := context.Background()
+	store := memrepo.NewStore()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	text := func(s string) *string { return &s }
+	price := 4.5
+
+	branch := models.Branch{ID: primitive.NewObjectID(), Name: text("Branch A"), Created_at: now, Updated_at: now, Version: 1}
+	branch.Branch_id = branch.ID.Hex()
+
+	user := models.User{ID: primitive.NewObjectID(), First_name: text("Wendy"), Last_name: text("Waiter"), Password: text("$2a$04$hash"), Email: text("waiter@test.io"),
+		Phone: text("+44 7700 900000"), Role: text(models.ROLE_WAITER), Branch_id: &branch.Branch_id, Created_at: now, Updated_at: now, Version: 1}
+	user.User_id = user.ID.Hex()
+
+	menu := models.Menu{ID: primitive.NewObjectID(), Name: "Mains", Category: "MAIN", Created_at: now, Updated_at: now, Version: 1, Branch_id: branch.Branch_id}
+	menu.Menu_id = menu.ID.Hex()
+
+	food := models.Food{ID: primitive.NewObjectID(), Name: text("Soup"), Price: &price, Food_image: text("https://example.com/soup"), Created_at: now, Updated_at: now, Version: 1,
+		Menu_id: &menu.Menu_id, Branch_id: branch.Branch_id}
+	food.Food_id = food.ID.Hex()
+
+	for _, err := range []error{
+		store.Branches.Create(ctx, &branch),
+		store.Users.Create(ctx, &user),
+		store.Menus.Create(ctx, &menu),
+		store.Foods.Create(ctx, &food),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store
+}
+
+func export(t *testing.T, store *repository.Store, opts ExportOptions) string {
+
+	dir := filepath.Join(t.TempDir(), "export")
+
+	if _, err := Export(context.Background(), store, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// totals adds up the results of every collection
+func totals(results []Result) (created int, updated int, unchanged int) {
+
+	for _, result := range results {
+		created, updated, unchanged = created+result.Created, updated+result.Updated, unchanged+result.Unchanged
+	}
+
+	return created, updated, unchanged
+}
+
+func TestImportTwiceChangesNothing(t *testing.T) {
+
+	ctx := context.Background()
+
+	for _, format := range []string{NDJSON, CSV} {
+
+		dir := export(t, newRestaurant(t), ExportOptions{Format: format})
+		target := memrepo.NewStore()
+
+		results, err := Import(ctx, target, dir, false)
+
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if created, updated, _ := totals(results); created != 4 || updated != 0 {
+			t.Fatalf("%s: the first import created %d and updated %d , want 4 and 0", format, created, updated)
+		}
+
+		// the export has no password hashes , the user waits deactivated until it sets a password
+
+		users, _, err := target.Users.List(ctx, "", 0, 0)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 1 || users[0].Password != nil || !users[0].Deactivated || users[0].Deactivated_at == nil {
+			t.Fatalf("%s: the user without a password was imported as %+v", format, users)
+		}
+
+		results, err = Import(ctx, target, dir, false)
+
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if created, updated, unchanged := totals(results); created != 0 || updated != 0 || unchanged != 4 {
+			t.Fatalf("%s: the second import created %d , updated %d and left %d alone , want 0 , 0 and 4", format, created, updated, unchanged)
+		}
+	}
+}
+
+func TestImportKeepsTheStoredSecrets(t *testing.T) {
+
+	ctx := context.Background()
+	store := newRestaurant(t)
+
+	results, err := Import(ctx, store, export(t, store, ExportOptions{}), false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created, updated, _ := totals(results); created != 0 || updated != 0 {
+		t.Fatalf("importing the export of the same store created %d and updated %d", created, updated)
+	}
+
+	user, err := store.Users.GetByEmail(ctx, "waiter@test.io")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Password == nil || *user.Password != "$2a$04$hash" || user.Deactivated {
+		t.Fatalf("the stored password was lost: %+v", user)
+	}
+}
+
+func TestDryRunWritesNothing(t *testing.T) {
+
+	ctx := context.Background()
+	target := memrepo.NewStore()
+
+	results, err := Import(ctx, target, export(t, newRestaurant(t), ExportOptions{With_secrets: true}), true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created, _, _ := totals(results); created != 4 {
+		t.Fatalf("the dry run counted %d records to create , want 4", created)
+	}
+
+	branches, err := target.Branches.List(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, _, err := target.Users.List(ctx, "", 0, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(branches) != 0 || len(users) != 0 {
+		t.Fatalf("the dry run wrote %d branches and %d users", len(branches), len(users))
+	}
+}
+
+func TestImportRefusesAChangedFile(t *testing.T) {
+
+	ctx := context.Background()
+	dir := export(t, newRestaurant(t), ExportOptions{})
+
+	path := filepath.Join(dir, "foods.ndjson")
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), "Soup", "Stew", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	target := memrepo.NewStore()
+
+	if _, err := Import(ctx, target, dir, false); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("got the error %v , want a checksum mismatch", err)
+	}
+
+	// every checksum is verified before the first write , the branches that come before the foods aren't written either
+
+	if branches, _ := target.Branches.List(ctx); len(branches) != 0 {
+		t.Fatalf("the import wrote %d branches before it found the changed file", len(branches))
+	}
+}
+
+func TestImportRefusesFilesOutsideTheExport(t *testing.T) {
+
+	dir := export(t, newRestaurant(t), ExportOptions{})
+
+	manifest, err := ReadManifest(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a copy of the menus next to the export , its checksum is right
+	if err := os.Rename(filepath.Join(dir, "menus.ndjson"), filepath.Join(dir, "..", "menus.ndjson")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range manifest.Collections {
+		if manifest.Collections[i].Name == "menus" {
+			manifest.Collections[i].File = "../menus.ndjson"
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, MANIFEST_FILE), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Import(context.Background(), memrepo.NewStore(), dir, false); err == nil || !strings.Contains(err.Error(), "is not a file of the export") {
+		t.Fatalf("got the error %v , want the file outside the export refused", err)
+	}
+}
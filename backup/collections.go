@@ -0,0 +1,261 @@
+package backup
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userRecord is a user as it is exported , the TOTP secret and the recovery codes are hidden from the JSON of the model
+// and only written here when the secrets are exported as well
+type userRecord struct {
+	models.User
+	Totp_secret    *string  `json:"totp_secret,omitempty"`
+	Totp_last_step *int64   `json:"totp_last_step,omitempty"`
+	Recovery_codes []string `json:"recovery_codes,omitempty"`
+}
+
+// kind describes how the records of one collection are read and written through the repositories , T is the record of a line of the export
+type kind[T any] struct {
+	name string
+	list func(ctx context.Context, store *repository.Store, branchId string) ([]T, error)
+	// get finds a record by its business id in every branch , deleted ones included
+	get     func(ctx context.Context, store *repository.Store, id string) (*T, error)
+	create  func(ctx context.Context, store *repository.Store, record *T) error
+	update  func(ctx context.Context, store *repository.Store, record *T) error
+	id      func(record *T) string
+	version func(record *T) *int
+	// objectId is the _id of the record , an update keeps the one that is stored
+	objectId func(record *T) *primitive.ObjectID
+	// strip removes what is only exported when the secrets are asked for , keep fills it in again from the stored record on import.
+	// both are nil for the collections without secrets
+	strip func(record *T)
+	keep  func(imported *T, stored *T)
+}
+
+// COLLECTIONS are exported and imported in this order , the records a collection refers to always come before it
+var COLLECTIONS = []collection{
+	kind[models.Branch]{
+		name: "branches",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.Branch, error) {
+			if branchId != "" {
+				branch, err := store.Branches.Get(ctx, branchId)
+				if err != nil {
+					return nil, err
+				}
+				return []models.Branch{*branch}, nil
+			}
+			return store.Branches.List(ctx)
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.Branch, error) {
+			return store.Branches.Get(ctx, id)
+		},
+		create: func(ctx context.Context, store *repository.Store, branch *models.Branch) error {
+			return store.Branches.Create(ctx, branch)
+		},
+		update: func(ctx context.Context, store *repository.Store, branch *models.Branch) error {
+			return store.Branches.Update(ctx, branch)
+		},
+		id:       func(branch *models.Branch) string { return branch.Branch_id },
+		version:  func(branch *models.Branch) *int { return &branch.Version },
+		objectId: func(branch *models.Branch) *primitive.ObjectID { return &branch.ID },
+	},
+	kind[userRecord]{
+		name: "users",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]userRecord, error) {
+			users, _, err := store.Users.List(ctx, branchId, 0, 0)
+			records := []userRecord{}
+			for _, user := range users {
+				records = append(records, fromUser(user))
+			}
+			return records, err
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*userRecord, error) {
+			user, err := store.Users.Get(ctx, "", id)
+			if err != nil {
+				return nil, err
+			}
+			record := fromUser(*user)
+			return &record, nil
+		},
+		create: func(ctx context.Context, store *repository.Store, record *userRecord) error {
+			// an export without the secrets has no password hashes , such a user is created deactivated.
+			// it can only log in once an admin activated it and it set a password through the reset link
+			if record.Password == nil && !record.Deactivated {
+				now := time.Now().UTC().Truncate(time.Second)
+				record.Deactivated, record.Deactivated_at = true, &now
+			}
+			return store.Users.Create(ctx, record.toUser())
+		},
+		update: func(ctx context.Context, store *repository.Store, record *userRecord) error {
+			user := record.toUser()
+			err := store.Users.Update(ctx, user)
+			record.Version = user.Version
+			return err
+		},
+		id:       func(record *userRecord) string { return record.User_id },
+		version:  func(record *userRecord) *int { return &record.Version },
+		objectId: func(record *userRecord) *primitive.ObjectID { return &record.ID },
+		strip:    (*userRecord).withoutSecrets,
+		keep: func(imported *userRecord, stored *userRecord) {
+			if imported.Password == nil {
+				imported.Password = stored.Password
+			}
+			// still without a password the stored user keeps its state , whether create deactivated it or an admin activated it since
+			if imported.Password == nil {
+				imported.Deactivated, imported.Deactivated_at = stored.Deactivated, stored.Deactivated_at
+			}
+			if imported.Totp_secret == nil {
+				imported.Totp_secret = stored.Totp_secret
+			}
+			if imported.Totp_last_step == nil {
+				imported.Totp_last_step = stored.Totp_last_step
+			}
+			if imported.Recovery_codes == nil {
+				imported.Recovery_codes = stored.Recovery_codes
+			}
+			// the tokens belong to the sessions of this server , they are never imported
+			imported.Token, imported.RefreshToken = stored.Token, stored.RefreshToken
+		},
+	},
+	kind[models.Menu]{
+		name: "menus",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.Menu, error) {
+			return store.Menus.List(ctx, branchId, true)
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.Menu, error) {
+			return store.Menus.Get(ctx, "", id, true)
+		},
+		create: func(ctx context.Context, store *repository.Store, menu *models.Menu) error {
+			return store.Menus.Create(ctx, menu)
+		},
+		update: func(ctx context.Context, store *repository.Store, menu *models.Menu) error {
+			return store.Menus.Update(ctx, menu)
+		},
+		id:       func(menu *models.Menu) string { return menu.Menu_id },
+		version:  func(menu *models.Menu) *int { return &menu.Version },
+		objectId: func(menu *models.Menu) *primitive.ObjectID { return &menu.ID },
+	},
+	kind[models.Food]{
+		name: "foods",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.Food, error) {
+			foods, _, err := store.Foods.List(ctx, branchId, 0, 0, true)
+			return foods, err
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.Food, error) {
+			return store.Foods.Get(ctx, "", id, true)
+		},
+		create: func(ctx context.Context, store *repository.Store, food *models.Food) error {
+			return store.Foods.Create(ctx, food)
+		},
+		update: func(ctx context.Context, store *repository.Store, food *models.Food) error {
+			return store.Foods.Update(ctx, food)
+		},
+		id:       func(food *models.Food) string { return food.Food_id },
+		version:  func(food *models.Food) *int { return &food.Version },
+		objectId: func(food *models.Food) *primitive.ObjectID { return &food.ID },
+	},
+	kind[models.Table]{
+		name: "tables",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.Table, error) {
+			return store.Tables.List(ctx, branchId, true)
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.Table, error) {
+			return store.Tables.Get(ctx, "", id, true)
+		},
+		create: func(ctx context.Context, store *repository.Store, table *models.Table) error {
+			return store.Tables.Create(ctx, table)
+		},
+		update: func(ctx context.Context, store *repository.Store, table *models.Table) error {
+			return store.Tables.Update(ctx, table)
+		},
+		id:       func(table *models.Table) string { return table.Table_id },
+		version:  func(table *models.Table) *int { return &table.Version },
+		objectId: func(table *models.Table) *primitive.ObjectID { return &table.ID },
+	},
+	kind[models.Order]{
+		name: "orders",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.Order, error) {
+			return store.Orders.List(ctx, branchId, true)
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.Order, error) {
+			return store.Orders.Get(ctx, "", id, true)
+		},
+		create: func(ctx context.Context, store *repository.Store, order *models.Order) error {
+			return store.Orders.Create(ctx, order)
+		},
+		update: func(ctx context.Context, store *repository.Store, order *models.Order) error {
+			return store.Orders.Update(ctx, order)
+		},
+		id:       func(order *models.Order) string { return order.Order_id },
+		version:  func(order *models.Order) *int { return &order.Version },
+		objectId: func(order *models.Order) *primitive.ObjectID { return &order.ID },
+	},
+	kind[models.OrderItem]{
+		name: "order_items",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.OrderItem, error) {
+			return store.OrderItems.List(ctx, branchId, true)
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.OrderItem, error) {
+			return store.OrderItems.Get(ctx, "", id, true)
+		},
+		create: func(ctx context.Context, store *repository.Store, orderItem *models.OrderItem) error {
+			return store.OrderItems.CreateMany(ctx, []models.OrderItem{*orderItem})
+		},
+		update: func(ctx context.Context, store *repository.Store, orderItem *models.OrderItem) error {
+			return store.OrderItems.Update(ctx, orderItem)
+		},
+		id:       func(orderItem *models.OrderItem) string { return orderItem.Order_item_id },
+		version:  func(orderItem *models.OrderItem) *int { return &orderItem.Version },
+		objectId: func(orderItem *models.OrderItem) *primitive.ObjectID { return &orderItem.ID },
+	},
+	kind[models.Invoice]{
+		name: "invoices",
+		list: func(ctx context.Context, store *repository.Store, branchId string) ([]models.Invoice, error) {
+			return store.Invoices.List(ctx, branchId, true)
+		},
+		get: func(ctx context.Context, store *repository.Store, id string) (*models.Invoice, error) {
+			return store.Invoices.Get(ctx, "", id, true)
+		},
+		create: func(ctx context.Context, store *repository.Store, invoice *models.Invoice) error {
+			return store.Invoices.Create(ctx, invoice)
+		},
+		update: func(ctx context.Context, store *repository.Store, invoice *models.Invoice) error {
+			return store.Invoices.Update(ctx, invoice)
+		},
+		id:       func(invoice *models.Invoice) string { return invoice.Invoice_id },
+		version:  func(invoice *models.Invoice) *int { return &invoice.Version },
+		objectId: func(invoice *models.Invoice) *primitive.ObjectID { return &invoice.ID },
+	},
+}
+
+func fromUser(user models.User) userRecord {
+
+	step := user.Totp_last_step
+
+	return userRecord{User: user, Totp_secret: user.Totp_secret, Totp_last_step: &step, Recovery_codes: user.Recovery_codes}
+}
+
+// toUser puts the secrets back into the model
+func (record *userRecord) toUser() *models.User {
+
+	user := record.User
+	user.Totp_secret = record.Totp_secret
+	user.Recovery_codes = record.Recovery_codes
+	user.Totp_last_step = 0
+
+	if record.Totp_last_step != nil {
+		user.Totp_last_step = *record.Totp_last_step
+	}
+
+	return &user
+}
+
+// withoutSecrets is how users are exported unless the secrets are asked for
+func (record *userRecord) withoutSecrets() {
+	record.Password, record.Token, record.RefreshToken = nil, nil, nil
+	record.Totp_secret, record.Totp_last_step, record.Recovery_codes = nil, nil, nil
+}
@@ -0,0 +1,325 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the formats of an export , every collection is written into a file of its own.
+// NDJSON keeps every record exactly , CSV is for spreadsheets and can't tell an empty text of a pointer field from a missing one
+const (
+	NDJSON = "ndjson"
+	CSV    = "csv"
+)
+
+type encoder interface {
+	encode(record interface{}) error
+	flush() error
+}
+
+// decode reads the next record into record , it returns false at the end of the file
+type decoder interface {
+	decode(record interface{}) (bool, error)
+}
+
+func newEncoder(format string, w io.Writer, t reflect.Type) encoder {
+
+	if format == CSV {
+		return &csvEncoder{w: csv.NewWriter(w), columns: columnsOf(t)}
+	}
+
+	return ndjsonEncoder{w}
+}
+
+func newDecoder(format string, r io.Reader, t reflect.Type) (decoder, error) {
+
+	if format == CSV {
+		return newCsvDecoder(r, columnsOf(t))
+	}
+
+	scanner := bufio.NewScanner(r)
+	// a line is a whole record , the default limit of 64KB is too small for a user with all of their recovery codes
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	return ndjsonDecoder{scanner}, nil
+}
+
+// ndjson is one JSON object per line
+
+type ndjsonEncoder struct {
+	w io.Writer
+}
+
+func (e ndjsonEncoder) encode(record interface{}) error {
+
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+func (e ndjsonEncoder) flush() error {
+	return nil
+}
+
+type ndjsonDecoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d ndjsonDecoder) decode(record interface{}) (bool, error) {
+
+	for d.scanner.Scan() {
+
+		line := strings.TrimSpace(d.scanner.Text())
+
+		// empty lines are skipped , an editor likes to leave one at the end
+		if line == "" {
+			continue
+		}
+
+		return true, json.Unmarshal([]byte(line), record)
+	}
+
+	return false, d.scanner.Err()
+}
+
+// csv has a header with the JSON names of the fields and a row for every record.
+// texts , times and ids are written as they are , every other value as its JSON and null as an empty cell
+
+type column struct {
+	name string
+	// text values are quoted again on import
+	text bool
+	// an empty cell is null for pointers , slices and maps and an empty text otherwise
+	nullable bool
+}
+
+// columnsOf lists the JSON fields of t , the fields of embedded structs are part of t like they are in its JSON
+func columnsOf(t reflect.Type) []column {
+
+	columns := []column{}
+	seen := map[string]bool{}
+
+	for i := 0; i < t.NumField(); i++ {
+
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, embedded := range columnsOf(field.Type) {
+				if !seen[embedded.name] {
+					seen[embedded.name] = true
+					columns = append(columns, embedded)
+				}
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		base := field.Type
+		kind := base.Kind()
+
+		if kind == reflect.Ptr {
+			base = base.Elem()
+		}
+
+		columns = append(columns, column{
+			name:     name,
+			text:     base.Kind() == reflect.String || base == reflect.TypeOf(time.Time{}) || base == reflect.TypeOf(primitive.ObjectID{}),
+			nullable: kind == reflect.Ptr || kind == reflect.Slice || kind == reflect.Map,
+		})
+	}
+
+	return columns
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []column
+	header  bool
+}
+
+func (e *csvEncoder) encode(record interface{}) error {
+
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	row := make([]string, len(e.columns))
+
+	for i, col := range e.columns {
+
+		value, ok := fields[col.name]
+
+		if !ok || string(value) == "null" {
+			continue
+		}
+
+		if col.text {
+
+			var text string
+
+			if err := json.Unmarshal(value, &text); err != nil {
+				return fmt.Errorf("%s: %w", col.name, err)
+			}
+
+			row[i] = text
+			continue
+		}
+
+		row[i] = string(value)
+	}
+
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) writeHeader() error {
+
+	e.header = true
+
+	names := make([]string, len(e.columns))
+
+	for i, col := range e.columns {
+		names[i] = col.name
+	}
+
+	return e.w.Write(names)
+}
+
+// flush writes the header for an empty collection as well , so that every file of an export can be opened the same way
+func (e *csvEncoder) flush() error {
+
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type csvDecoder struct {
+	r *csv.Reader
+	// columns are in the order of the header of the file , nil for a header the record doesn't know
+	columns []*column
+}
+
+func newCsvDecoder(r io.Reader, known []column) (decoder, error) {
+
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+
+	if err == io.EOF {
+		return csvDecoder{r: reader}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*column{}
+
+	for i := range known {
+		byName[known[i].name] = &known[i]
+	}
+
+	columns := make([]*column, len(header))
+
+	for i, name := range header {
+
+		if byName[name] == nil {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+
+		columns[i] = byName[name]
+	}
+
+	return csvDecoder{r: reader, columns: columns}, nil
+}
+
+func (d csvDecoder) decode(record interface{}) (bool, error) {
+
+	row, err := d.r.Read()
+
+	if err == io.EOF {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	// the row is put back together into the JSON of the record
+
+	fields := map[string]json.RawMessage{}
+
+	for i, cell := range row {
+
+		col := d.columns[i]
+
+		switch {
+		case cell == "" && (col.nullable || !col.text):
+			fields[col.name] = json.RawMessage("null")
+		case col.text:
+			quoted, err := json.Marshal(cell)
+
+			if err != nil {
+				return false, err
+			}
+
+			fields[col.name] = quoted
+		default:
+			fields[col.name] = json.RawMessage(cell)
+		}
+	}
+
+	data, err := json.Marshal(fields)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, record)
+}
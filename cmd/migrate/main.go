@@ -0,0 +1,126 @@
+// migrate applies the schema migrations of the migrations package to the database of the server's configuration :
+//
+//	go run ./cmd/migrate status          lists every migration and when it was applied
+//	go run ./cmd/migrate up [version]    applies the pending migrations , up to version when it is given
+//	go run ./cmd/migrate down [steps]    rolls back the last applied migration , or the last steps of them
+//
+// the data from before branches is given the branch named by LEGACY_BRANCH_ID , like LEGACY_BRANCH_ID=<branch_id> go run ./cmd/migrate up
+package main
+
+import (
+	"context"
+	"fmt"
+	"go-restaurent-management-system/config"
+	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/migrations"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// a migration rewrites whole collections , so it gets much longer than a request
+const MIGRATION_TIMEOUT = 30 * time.Minute
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate status | up [version] | down [steps]")
+	os.Exit(2)
+}
+
+func main() {
+
+	if len(os.Args) < 2 || len(os.Args) > 3 {
+		usage()
+	}
+
+	// the number after up is the version to stop at , after down the number of migrations to roll back
+	number := 0
+
+	if len(os.Args) == 3 {
+		parsed, err := strconv.Atoi(os.Args[2])
+
+		if err != nil || parsed < 1 {
+			usage()
+		}
+
+		number = parsed
+	}
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	client, err := database.Connect(cfg.Mongo)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(cfg.Mongo.Database)
+
+	migrations.LEGACY_BRANCH_ID = os.Getenv("LEGACY_BRANCH_ID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), MIGRATION_TIMEOUT)
+	defer cancel()
+
+	switch os.Args[1] {
+	case "status":
+		statuses, err := migrations.Statuses(ctx, db)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, status := range statuses {
+			appliedAt := "pending"
+
+			if status.Applied_at != nil {
+				appliedAt = "applied " + status.Applied_at.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%4d  %-30s  %s\n", status.Version, status.Name, appliedAt)
+		}
+
+	case "up":
+		ran, err := migrations.Up(ctx, db, number)
+
+		for _, migration := range ran {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(ran) == 0 {
+			fmt.Println("the database is up to date")
+		}
+
+	case "down":
+		if number == 0 {
+			number = 1
+		}
+
+		ran, err := migrations.Down(ctx, db, number)
+
+		for _, migration := range ran {
+			fmt.Printf("rolled back %d %s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(ran) == 0 {
+			fmt.Println("no migration has been applied")
+		}
+
+	default:
+		usage()
+	}
+
+}
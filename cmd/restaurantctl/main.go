@@ -0,0 +1,211 @@
+// restaurantctl exports the data of the server's configuration into a directory and imports it again :
+//
+//	go run ./cmd/restaurantctl export [-format ndjson|csv] [-branch id] [-with-secrets] <dir>
+//	go run ./cmd/restaurantctl import [-dry-run] <dir>
+//	go run ./cmd/restaurantctl seed [-seed n] [-branches n] [-weeks n] [-until yyyy-mm-dd] [-password text]
+//
+// export writes a file for every collection and a manifest.json with their counts and checksums.
+// users are exported without their password hash , tokens , TOTP secret and recovery codes unless -with-secrets is given ,
+// an import keeps what is stored for the ones that are left out , a user it creates without a password is deactivated
+// until an admin activates it and it sets a password through the reset link.
+// import upserts by the business ids , like food_id or menu_id , so running it twice changes nothing the second time.
+// -dry-run only reports what would be created and updated.
+// seed fills the database with a demo restaurant , the same seed and until always give the same records with the same ids.
+// the users , menus , foods and tables don't depend on until , seeding again with a later one adds the orders of the days in between
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-restaurent-management-system/backup"
+	"go-restaurent-management-system/config"
+	"go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/seed"
+	"go-restaurent-management-system/storage"
+	"log"
+	"os"
+	"time"
+)
+
+// an export or import goes through whole collections , so it gets much longer than a request
+const BACKUP_TIMEOUT = 30 * time.Minute
+
+const DATE_LAYOUT = "2006-01-02"
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: restaurantctl export [-format ndjson|csv] [-branch id] [-with-secrets] <dir>")
+	fmt.Fprintln(os.Stderr, "       restaurantctl import [-dry-run] <dir>")
+	fmt.Fprintln(os.Stderr, "       restaurantctl seed [-seed n] [-branches n] [-weeks n] [-until yyyy-mm-dd] [-password text]")
+	os.Exit(2)
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var run func(ctx context.Context, cfg *config.Config) error
+
+	switch os.Args[1] {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		flags.Usage = usage
+		format := flags.String("format", backup.NDJSON, "ndjson or csv")
+		branch := flags.String("branch", "", "only export this branch")
+		withSecrets := flags.Bool("with-secrets", false, "export the password hashes , tokens and 2FA secrets of the users as well")
+		flags.Parse(os.Args[2:])
+
+		if flags.NArg() != 1 {
+			usage()
+		}
+
+		run = func(ctx context.Context, cfg *config.Config) error {
+			return export(ctx, cfg, flags.Arg(0), backup.ExportOptions{Format: *format, Branch_id: *branch, With_secrets: *withSecrets})
+		}
+
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		flags.Usage = usage
+		dryRun := flags.Bool("dry-run", false, "only report what would be written")
+		flags.Parse(os.Args[2:])
+
+		if flags.NArg() != 1 {
+			usage()
+		}
+
+		run = func(ctx context.Context, cfg *config.Config) error {
+			return importDir(ctx, cfg, flags.Arg(0), *dryRun)
+		}
+
+	case "seed":
+		flags := flag.NewFlagSet("seed", flag.ExitOnError)
+		flags.Usage = usage
+		number := flags.Int64("seed", seed.DEFAULT_SEED, "the seed of the random numbers")
+		branches := flags.Int("branches", seed.DEFAULT_BRANCHES, "the number of branches")
+		weeks := flags.Int("weeks", seed.DEFAULT_WEEKS, "the number of weeks with orders")
+		until := flags.String("until", seed.DEFAULT_UNTIL, "the day of the last orders")
+		password := flags.String("password", seed.DEMO_PASSWORD, "the password of every user")
+		flags.Parse(os.Args[2:])
+
+		if flags.NArg() != 0 {
+			usage()
+		}
+
+		untilDate, err := time.Parse(DATE_LAYOUT, *until)
+
+		if err != nil {
+			usage()
+		}
+
+		run = func(ctx context.Context, cfg *config.Config) error {
+			return seedDemo(ctx, cfg, seed.Options{Seed: *number, Branches: *branches, Weeks: *weeks, Until: untilDate}, *password)
+		}
+
+	default:
+		usage()
+	}
+
+	cfg, err := config.Load()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), BACKUP_TIMEOUT)
+	defer cancel()
+
+	if err := run(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func export(ctx context.Context, cfg *config.Config, dir string, opts backup.ExportOptions) error {
+
+	store, closeStore, err := storage.Open(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeStore(context.Background())
+
+	manifest, err := backup.Export(ctx, store, dir, opts)
+
+	if err != nil {
+		return err
+	}
+
+	for _, exported := range manifest.Collections {
+		fmt.Printf("%s: %d records in %s\n", exported.Name, exported.Count, exported.File)
+	}
+
+	return nil
+}
+
+func importDir(ctx context.Context, cfg *config.Config, dir string, dryRun bool) error {
+
+	// a broken export is refused before the database is even opened
+	if _, err := backup.ReadManifest(dir); err != nil {
+		return err
+	}
+
+	store, closeStore, err := storage.Open(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeStore(context.Background())
+
+	results, err := backup.Import(ctx, store, dir, dryRun)
+
+	for _, result := range results {
+		fmt.Printf("%s: %d created , %d updated , %d unchanged\n", result.Collection, result.Created, result.Updated, result.Unchanged)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Println("dry run , nothing was written")
+	}
+
+	return nil
+}
+
+func seedDemo(ctx context.Context, cfg *config.Config, opts seed.Options, password string) error {
+
+	// every user gets the same password , so it is only hashed once
+	opts.Password_hash = controllers.HashPassword(password)
+
+	data, err := seed.Generate(opts)
+
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := storage.Open(cfg)
+
+	if err != nil {
+		return err
+	}
+
+	defer closeStore(context.Background())
+
+	results, err := seed.Write(ctx, store, data)
+
+	for _, result := range results {
+		fmt.Printf("%s: %d created , %d already there\n", result.Collection, result.Created, result.Skipped)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("every user logs in with the password of -password , the admin of the head office is admin@%s\n", seed.EMAIL_DOMAIN)
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateApiKey answers with the plain key , it is the only time it can be seen because only its hash is stored
+func CreateApiKey(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var apiKey models.ApiKey
+
+		if err := c.BindJSON(&apiKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(apiKey); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		for _, scope := range apiKey.Scopes {
+			if !isApiKeyScope(scope) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope " + scope})
+				return
+			}
+		}
+
+		// a key works for one branch only , admins of a branch create keys for their own branch and the head office has to pick one
+
+		if !middleware.IsHeadOffice(c) {
+			apiKey.Branch_id = c.GetString("branch_id")
+		}
+
+		if apiKey.Branch_id == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a branch_id is required"})
+			return
+		}
+
+		if !branchExists(c, ctx, store, apiKey.Branch_id) {
+			return
+		}
+
+		key, prefix, err := helper.GenerateApiKey()
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the api key"})
+			return
+		}
+
+		apiKey.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		apiKey.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		apiKey.ID = primitive.NewObjectID()
+		apiKey.Api_key_id = apiKey.ID.Hex()
+		apiKey.Prefix = prefix
+		apiKey.Key_hash = helper.HashSecret(key)
+		apiKey.Created_by = c.GetString("uid")
+		apiKey.Last_used_at = nil
+		apiKey.Revoked_at = nil
+
+		if err := store.ApiKeys.Create(ctx, &apiKey); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the api key"})
+			return
+		}
+
+		recordChange(c, ctx, "api_key", audit.ACTION_CREATE, apiKey.Api_key_id, nil, apiKeySnapshot(apiKey))
+
+		c.JSON(http.StatusOK, gin.H{"api_key": apiKey, "key": key})
+
+	}
+}
+
+func GetApiKeys(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		// the hashes are left out of the response by the json tags of the model
+
+		allApiKeys, err := store.ApiKeys.List(ctx, branchId)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the api keys"})
+			return
+		}
+
+		c.JSON(http.StatusOK, allApiKeys)
+
+	}
+}
+
+// RevokeApiKey keeps the key for reference but it is refused from now on
+func RevokeApiKey(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		apiKeyId := c.Param("api_key_id")
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		var before map[string]interface{}
+
+		if apiKey, err := store.ApiKeys.Get(ctx, branchId, apiKeyId); err == nil {
+			before = apiKeySnapshot(*apiKey)
+		}
+
+		revoked, err := store.ApiKeys.Revoke(ctx, branchId, apiKeyId, now)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the api key"})
+			return
+		}
+
+		if !revoked {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key was not found or is already revoked"})
+			return
+		}
+
+		var after map[string]interface{}
+
+		if apiKey, err := store.ApiKeys.Get(ctx, branchId, apiKeyId); err == nil {
+			after = apiKeySnapshot(*apiKey)
+		}
+
+		recordChange(c, ctx, "api_key", "revoke", apiKeyId, before, after)
+
+		c.JSON(http.StatusOK, gin.H{"message": "the api key has been revoked"})
+
+	}
+}
+
+// apiKeySnapshot leaves the key hash out of the audit log
+func apiKeySnapshot(apiKey models.ApiKey) map[string]interface{} {
+
+	snapshot := audit.Snapshot(apiKey)
+	delete(snapshot, "key_hash")
+
+	return snapshot
+}
+
+func isApiKeyScope(scope string) bool {
+
+	for _, known := range models.API_KEY_SCOPES {
+		if scope == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLog lists the audit events , for example /audit?entity=invoice&entity_id=...&from=2023-09-01&to=2023-09-30
+func GetAuditLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
+		if err != nil || recordPerPage < 1 {
+			recordPerPage = 50
+		}
+
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		// managers only see what happened in their own branch
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		filter := audit.Filter{
+			Branch_id:   branchId,
+			Entity_type: c.Query("entity"),
+			Entity_id:   c.Query("entity_id"),
+			Actor_id:    c.Query("actor"),
+			Action:      c.Query("action"),
+		}
+
+		if from := c.Query("from"); from != "" {
+			date, err := parseAuditDate(from, false)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from has to be a date (2006-01-02) or a RFC3339 time"})
+				return
+			}
+			filter.From = &date
+		}
+
+		if to := c.Query("to"); to != "" {
+			date, err := parseAuditDate(to, true)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to has to be a date (2006-01-02) or a RFC3339 time"})
+				return
+			}
+			filter.To = &date
+		}
+
+		events, total, err := audit.Find(ctx, filter, int64((page-1)*recordPerPage), int64(recordPerPage))
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the audit log"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"total_count": total, "audit_items": events})
+
+	}
+}
+
+// parseAuditDate accepts a whole day , which covers the day up to its last second when it is the end of the range
+func parseAuditDate(value string, endOfDay bool) (time.Time, error) {
+
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			return date.Add(24*time.Hour - time.Second), nil
+		}
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// fields of a user that are left out of the audit log and of every response
+var userSecretFields = []string{"password", "token", "refresh_token", "totp_secret", "totp_last_step", "recovery_codes"}
+
+// userSnapshot leaves the password , the tokens and the two factor secrets out of the audit log
+func userSnapshot(user models.User) map[string]interface{} {
+
+	snapshot := audit.Snapshot(user)
+
+	for _, field := range userSecretFields {
+		delete(snapshot, field)
+	}
+
+	return snapshot
+}
+
+// recordChange writes the audit event of a create or an update made by the caller of the request
+func recordChange(c *gin.Context, ctx context.Context, entityType string, action string, entityId string, before map[string]interface{}, after map[string]interface{}) {
+
+	audit.Record(ctx, models.AuditEvent{
+		Actor_id:    c.GetString("uid"),
+		Actor_type:  c.GetString("auth_type"),
+		Action:      entityType + "." + action,
+		Entity_type: entityType,
+		Entity_id:   entityId,
+		Before:      before,
+		After:       after,
+		Branch_id:   c.GetString("branch_id"),
+	})
+}
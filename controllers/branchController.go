@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func GetBranches(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		allBranches, err := store.Branches.List(ctx)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the branches"})
+			return
+		}
+
+		c.JSON(http.StatusOK, allBranches)
+
+	}
+}
+
+func GetBranch(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		branch, err := store.Branches.Get(ctx, c.Param("branch_id"))
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "branch was not found"})
+			return
+		}
+
+		setETag(c, branch.Version)
+
+		c.JSON(http.StatusOK, branch)
+
+	}
+}
+
+func CreateBranch(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var branch models.Branch
+
+		if err := c.BindJSON(&branch); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(branch); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		branch.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		branch.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		branch.ID = primitive.NewObjectID()
+		branch.Version = 1
+		branch.Branch_id = branch.ID.Hex()
+
+		if err := store.Branches.Create(ctx, &branch); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while creating the branch"})
+			return
+		}
+
+		recordChange(c, ctx, "branch", audit.ACTION_CREATE, branch.Branch_id, nil, audit.Snapshot(branch))
+
+		c.JSON(http.StatusOK, branch)
+
+	}
+}
+
+func UpdateBranch(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var branch models.Branch
+
+		branchId := c.Param("branch_id")
+
+		if err := c.BindJSON(&branch); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		foundBranch, err := store.Branches.Get(ctx, branchId)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "branch was not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the branch"})
+			return
+		}
+
+		if !versionMatches(c, branch.Version, foundBranch.Version, foundBranch) {
+			return
+		}
+
+		before := audit.Snapshot(foundBranch)
+
+		if branch.Name != nil {
+			foundBranch.Name = branch.Name
+		}
+
+		if branch.Address != nil {
+			foundBranch.Address = branch.Address
+		}
+
+		if branch.Phone != nil {
+			foundBranch.Phone = branch.Phone
+		}
+
+		foundBranch.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Branches.Update(ctx, foundBranch)
+
+		if err == repository.ErrVersionConflict {
+			if current, getErr := store.Branches.Get(ctx, branchId); getErr == nil {
+				respondStale(c, current.Version, current)
+				return
+			}
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the branch"})
+			return
+		}
+
+		recordChange(c, ctx, "branch", audit.ACTION_UPDATE, branchId, before, audit.Snapshot(foundBranch))
+
+		setETag(c, foundBranch.Version)
+
+		c.JSON(http.StatusOK, foundBranch)
+
+	}
+}
+
+// callerBranch works out which branch a request is about , the branch comes from the token or the API key and can't be picked by the caller.
+// the head office reads across all branches (an empty branch id) unless ?branch_id= narrows it down , but it can't change the data of a branch.
+// guests on the public routes have no token , they name the branch they are looking at with ?branch_id=
+func callerBranch(c *gin.Context, write bool) (branchId string, ok bool) {
+
+	if middleware.IsHeadOffice(c) {
+		if write {
+			c.JSON(http.StatusForbidden, gin.H{"error": "the head office can only read , changes have to be made by a member of the branch"})
+			return "", false
+		}
+
+		return c.Query("branch_id"), true
+	}
+
+	if c.GetString("auth_type") == "" {
+		if branchId = c.Query("branch_id"); branchId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the branch_id query parameter is required"})
+			return "", false
+		}
+
+		return branchId, true
+	}
+
+	if branchId = c.GetString("branch_id"); branchId == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you are not assigned to a branch"})
+		return "", false
+	}
+
+	return branchId, true
+}
+
+// branchExists is checked whenever a branch id comes from the request body instead of the token
+func branchExists(c *gin.Context, ctx context.Context, store *repository.Store, branchId string) bool {
+
+	_, err := store.Branches.Get(ctx, branchId)
+
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "branch was not found"})
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the branch"})
+		return false
+	}
+
+	return true
+}
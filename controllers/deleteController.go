@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// foods , menus , tables , orders , order items and invoices are never removed from the database , DELETE only marks them as deleted.
+// they disappear from every list and lookup , an admin can still see them with ?include_deleted=true and bring them back with the restore routes
+
+// includeDeleted reads the include_deleted query parameter , only admins may look at what was deleted
+func includeDeleted(c *gin.Context) (include bool, ok bool) {
+
+	if c.Query("include_deleted") != "true" {
+		return false, true
+	}
+
+	if c.GetString("auth_type") != middleware.AUTH_TYPE_USER || c.GetString("role") != models.ROLE_ADMIN {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can see deleted documents"})
+		return false, false
+	}
+
+	return true, true
+}
+
+// deletedNow is the deleted_at and deleted_by of a document the caller deletes
+func deletedNow(c *gin.Context) (*time.Time, *string) {
+
+	deletedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	deletedBy := c.GetString("uid")
+
+	return &deletedAt, &deletedBy
+}
+
+// deletable is what the delete and restore handlers need to know about an entity , every controller fills one in for its own
+type deletable[T any] struct {
+	// name is used in the messages , entity is the entity of the integrity rules and the audit log and param the route parameter with the id
+	name   string
+	entity string
+	param  string
+	get    func(ctx context.Context, branchId string, id string, includeDeleted bool) (*T, error)
+	update func(ctx context.Context, record *T) error
+	// stamps are the fields a delete and a restore change
+	stamps func(record *T) (deletedAt **time.Time, deletedBy **string, updatedAt *time.Time, version int)
+	// references checks the documents a record points to before it is restored , it is nil when there is nothing to check
+	references func(ctx context.Context, record *T) error
+}
+
+// softDelete marks the document of the route as deleted after the cascade rules took along what depends on it
+func softDelete[T any](kind deletable[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		id := c.Param(kind.param)
+
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
+		found, ok := getDeletable(c, ctx, kind, branchId, id, false)
+		if !ok {
+			return
+		}
+
+		_, _, _, version := kind.stamps(found)
+
+		if !versionMatches(c, 0, version, found) {
+			return
+		}
+
+		at, by := deletedNow(c)
+		before := audit.Snapshot(found)
+		original := *found
+
+		// the cascade and the document itself are deleted in one transaction , a failure leaves all of them as they were
+
+		var updateErr error
+
+		changes, err := integrity.Delete(ctx, kind.entity, branchId, id, at, by, func(ctx context.Context) error {
+
+			// a retried transaction starts again from the document that was read
+			*found = original
+
+			deletedAt, deletedBy, updatedAt, _ := kind.stamps(found)
+
+			*deletedAt, *deletedBy = at, by
+			*updatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+			updateErr = kind.update(ctx, found)
+
+			return updateErr
+		})
+
+		if deleteRefused(c, err) {
+			return
+		}
+
+		// only a stale version of the document itself answers with the current document , a failed cascade answers 500
+		if err != updateErr {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the documents that reference the " + kind.name})
+			return
+		}
+
+		if updateFailed(c, ctx, kind, branchId, id, err, "deleting") {
+			return
+		}
+
+		recordCascade(c, ctx, changes)
+		recordChange(c, ctx, kind.entity, audit.ACTION_DELETE, id, before, audit.Snapshot(found))
+
+		_, _, _, version = kind.stamps(found)
+		setETag(c, version)
+
+		c.JSON(http.StatusOK, found)
+
+	}
+}
+
+// restoreDeleted brings a deleted document back , the routes only let admins do it
+func restoreDeleted[T any](kind deletable[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		id := c.Param(kind.param)
+
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
+		found, ok := getDeletable(c, ctx, kind, branchId, id, true)
+		if !ok {
+			return
+		}
+
+		deletedAt, deletedBy, updatedAt, version := kind.stamps(found)
+
+		if *deletedAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the " + kind.name + " is not deleted"})
+			return
+		}
+
+		// a document can't come back while what it points to is gone , like a food into a deleted menu
+
+		if kind.references != nil && !referencesValid(c, kind.references(ctx, found)) {
+			return
+		}
+
+		if !versionMatches(c, 0, version, found) {
+			return
+		}
+
+		before := audit.Snapshot(found)
+
+		*deletedAt, *deletedBy = nil, nil
+		*updatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if !updateDeletable(c, ctx, kind, branchId, id, found, "restoring") {
+			return
+		}
+
+		recordChange(c, ctx, kind.entity, audit.ACTION_RESTORE, id, before, audit.Snapshot(found))
+
+		_, _, _, version = kind.stamps(found)
+		setETag(c, version)
+
+		c.JSON(http.StatusOK, found)
+
+	}
+}
+
+func getDeletable[T any](c *gin.Context, ctx context.Context, kind deletable[T], branchId string, id string, includeDeleted bool) (*T, bool) {
+
+	found, err := kind.get(ctx, branchId, id, includeDeleted)
+
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind.name + " was not found"})
+		return nil, false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the " + kind.name})
+		return nil, false
+	}
+
+	return found, true
+}
+
+// updateDeletable stores the record , a stale version answers 409 with the current document
+func updateDeletable[T any](c *gin.Context, ctx context.Context, kind deletable[T], branchId string, id string, record *T, doing string) bool {
+	return !updateFailed(c, ctx, kind, branchId, id, kind.update(ctx, record), doing)
+}
+
+// updateFailed answers for the error of kind.update , it is false when there was none
+func updateFailed[T any](c *gin.Context, ctx context.Context, kind deletable[T], branchId string, id string, err error, doing string) bool {
+
+	if err == nil {
+		return false
+	}
+
+	if err == repository.ErrVersionConflict {
+		if current, getErr := kind.get(ctx, branchId, id, true); getErr == nil {
+			_, _, _, version := kind.stamps(current)
+			respondStale(c, version, current)
+			return true
+		}
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while " + doing + " the " + kind.name})
+	return true
+}
@@ -3,9 +3,10 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
 	"go-restaurent-management-system/models"
-	"log"
+	"go-restaurent-management-system/repository"
 	"math"
 	"net/http"
 	"strconv"
@@ -13,19 +14,26 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
 var validate = validator.New()
 
-func GetFoods() gin.HandlerFunc {
+func GetFoods(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
 
 		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
 		if err != nil || recordPerPage < 1 {
@@ -34,91 +42,94 @@ func GetFoods() gin.HandlerFunc {
 		}
 
 		page, err := strconv.Atoi(c.Query("page"))
-		if err != nil {
+		if err != nil || page < 1 {
 			page = 1
 		}
 
+		// ?startindex= skips straight to an item instead of a page
+
 		startIndex := (page - 1) * recordPerPage
-		startIndex, err = strconv.Atoi(c.Query("startindex"))
-
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
-		groupStage := bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "_id", Value: "null"}}}, {Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}}, {Key: "data", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}}}}} // push : it is an opertor fucntion , it pushes the data into documents
-		// $$ROOT: it is the document or data which contains the output of the previous stage of the pipeline .
-		//project stage is used to define the values which we want to send to the frontend
-		projectStage := bson.D{
-			{
-				Key: "$project", Value: bson.D{
-					{Key: "_id", Value: 0},
-					{Key: "total_count", Value: 1},
-					{Key: "food_items", Value: bson.D{{Key: "$slice", Value: []interface{}{"$data", startIndex, recordPerPage}}}},
-				}}}
-
-		result, err := foodCollection.Aggregate(ctx, mongo.Pipeline{
-			matchStage, groupStage, projectStage,
-		})
+		if index, err := strconv.Atoi(c.Query("startindex")); err == nil && index >= 0 {
+			startIndex = index
+		}
+
+		foods, total, err := store.Foods.List(ctx, branchId, int64(startIndex), int64(recordPerPage), showDeleted)
 
-		defer cancel()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing food items"})
 			return
 		}
 
-		var allFoods []bson.M
+		// the response keeps the shape of the old aggregation , an empty list when there are no foods at all
+
+		allFoods := []gin.H{}
 
-		if err := result.All(ctx, &allFoods); err != nil {
-			log.Fatal(err)
+		if total > 0 {
+			allFoods = append(allFoods, gin.H{"total_count": total, "food_items": foods})
 		}
 
 		c.JSON(http.StatusOK, allFoods)
 	}
 }
 
-func GetFood() gin.HandlerFunc {
+func GetFood(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		foodid := c.Param("food_id")
 
-		var food models.Food
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
 
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodid}).Decode(&food)
-		defer cancel()
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		food, err := store.Foods.Get(ctx, branchId, foodid, showDeleted)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
 			return
 		}
 
+		setETag(c, food.Version)
+
 		c.JSON(http.StatusOK, food)
 
 	}
 }
 
-func CreateFood() gin.HandlerFunc {
+func CreateFood(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var food models.Food
-		var menu models.Menu
 
 		if err := c.BindJSON(&food); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-
+			return
 		}
 
 		validationErr := validate.Struct(food)
 		if validationErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
-
+			return
 		}
 
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_id}).Decode(&menu)
-		defer cancel()
-		if err != nil {
-			msg := fmt.Sprintf("menu was not found")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		branchId, ok := callerBranch(c, true)
+		if !ok {
 			return
 		}
 
@@ -127,19 +138,31 @@ func CreateFood() gin.HandlerFunc {
 		//.Format(time.RFC3339): The Format method is used on the time.Time object to convert it back to a string representation in the RFC3339 format. This is done to ensure that the timestamp is in the expected format before assigning it to the Created_at and Updated_at fields.
 		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
+		food.Version = 1
+		food.Branch_id = branchId
 		food.Food_id = food.ID.Hex() // converting the ID created in the collection into understandable hexadecimal which comprises of 0 to 9 numbers and a to f alphabets
 		var num = toFixed(*food.Price, 2)
 		food.Price = &num
 
-		result, insertErr := foodCollection.InsertOne(ctx, food)
-		if insertErr != nil {
+		if !sizesValid(c, food.Sizes) || !modifierGroupsValid(c, food.Modifier_groups) {
+			return
+		}
+
+		// the menu has to belong to the same branch as the food
+
+		if !referencesValid(c, integrity.CheckFood(ctx, &food)) {
+			return
+		}
+
+		if insertErr := store.Foods.Create(ctx, &food); insertErr != nil {
 			msg := fmt.Sprintf("food item was not created ")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
 
-		defer cancel()
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "food", audit.ACTION_CREATE, food.Food_id, nil, audit.Snapshot(food))
+
+		c.JSON(http.StatusOK, food)
 
 	}
 }
@@ -156,77 +179,170 @@ func toFixed(num float64, precision int) float64 {
 
 }
 
-func UpdateFood() gin.HandlerFunc {
+// sizesValid answers 400 for a size an order item can't have or a price that isn't positive , the prices are rounded like the price of the food
+func sizesValid(c *gin.Context, sizes map[string]models.SizePrice) bool {
+
+	for size, sizePrice := range sizes {
+
+		if size != models.SIZE_SMALL && size != models.SIZE_MEDIUM && size != models.SIZE_LARGE {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sizes: %q is not a size , use S , M or L", size)})
+			return false
+		}
+
+		if sizePrice.Price != nil {
+
+			if *sizePrice.Price <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sizes: the price of %s has to be more than 0", size)})
+				return false
+			}
+
+			var num = toFixed(*sizePrice.Price, 2)
+			sizePrice.Price = &num
+			sizes[size] = sizePrice
+		}
+	}
+
+	return true
+}
+
+// modifierGroupsValid answers 400 when the modifier groups of a food can't be ordered from , see models.ValidateModifierGroups
+func modifierGroupsValid(c *gin.Context, groups []models.ModifierGroup) bool {
+
+	if err := models.ValidateModifierGroups(groups); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "modifier_groups: " + err.Error()})
+		return false
+	}
+
+	return true
+}
+
+func UpdateFood(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-		var menu models.Menu
 		var food models.Food
 
 		foodId := c.Param("food_id")
 
 		if err := c.BindJSON(&food); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error ": err.Error()})
-			defer cancel()
 			return
 		}
 
-		var updateObj primitive.D
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
+		// only foods of the caller's branch are found , an id of another branch is a 404
+
+		foundFood, err := store.Foods.Get(ctx, branchId, foodId, false)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			return
+		}
+
+		if !versionMatches(c, food.Version, foundFood.Version, foundFood) {
+			return
+		}
+
+		before := audit.Snapshot(foundFood)
 
 		if food.Name != nil {
-			updateObj = append(updateObj, bson.E{Key: "name", Value: food.Name})
+			foundFood.Name = food.Name
 		}
 
 		if food.Price != nil {
-			updateObj = append(updateObj, bson.E{Key: "price", Value: food.Price})
+			foundFood.Price = food.Price
 		}
 
 		if food.Food_image != nil {
-			updateObj = append(updateObj, bson.E{Key: "food_image", Value: food.Food_image})
+			foundFood.Food_image = food.Food_image
 		}
 
-		if food.Menu_id != nil {
-			err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_id}).Decode(&menu)
-			defer cancel()
-			if err != nil {
-				msg := fmt.Sprintf("message : menu was not found")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		// the sizes are replaced as a whole , an empty object sells every size for the price again
+
+		if food.Sizes != nil {
+			if !sizesValid(c, food.Sizes) {
 				return
 			}
-			updateObj = append(updateObj, bson.E{Key: "menu_id", Value: food.Menu_id})
+
+			foundFood.Sizes = food.Sizes
 		}
 
-		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: food.Updated_at})
+		// the modifier groups are replaced as a whole as well , the order items that were already sold keep what they were sold with
 
-		upsert := true
+		if food.Modifier_groups != nil {
+			if !modifierGroupsValid(c, food.Modifier_groups) {
+				return
+			}
 
-		filter := bson.M{"food_id": foodId}
+			foundFood.Modifier_groups = food.Modifier_groups
+		}
+
+		if food.Menu_id != nil && (foundFood.Menu_id == nil || *food.Menu_id != *foundFood.Menu_id) {
+			foundFood.Menu_id = food.Menu_id
 
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+			if !referencesValid(c, integrity.CheckFood(ctx, foundFood)) {
+				return
+			}
 		}
 
-		result, err := foodCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
+		foundFood.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Foods.Update(ctx, foundFood)
+
+		if err == repository.ErrVersionConflict {
+			if current, getErr := store.Foods.Get(ctx, branchId, foodId, true); getErr == nil {
+				respondStale(c, current.Version, current)
+				return
+			}
+		}
 
 		if err != nil {
 			msg := fmt.Sprintf("error occured while updating the document in the collection")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			defer cancel()
 			return
 		}
 
-		defer cancel()
+		recordChange(c, ctx, "food", audit.ACTION_UPDATE, foodId, before, audit.Snapshot(foundFood))
+
+		setETag(c, foundFood.Version)
+
+		c.JSON(http.StatusOK, foundFood)
 
-		c.JSON(http.StatusOK, result)
+	}
+}
 
+// foodDeletable hooks the foods into the delete and restore handlers of deleteController.go
+func foodDeletable(store *repository.Store) deletable[models.Food] {
+	return deletable[models.Food]{
+		name:   "food",
+		entity: integrity.FOOD,
+		param:  "food_id",
+		get:    store.Foods.Get,
+		update: store.Foods.Update,
+		stamps: func(f *models.Food) (**time.Time, **string, *time.Time, int) {
+			return &f.Deleted_at, &f.Deleted_by, &f.Updated_at, f.Version
+		},
+		references: integrity.CheckFood,
 	}
 }
+
+// DeleteFood only marks the food as deleted , see deleteController.go
+func DeleteFood(store *repository.Store) gin.HandlerFunc {
+	return softDelete(foodDeletable(store))
+}
+
+// RestoreFood brings a deleted food back , only admins can do it
+func RestoreFood(store *repository.Store) gin.HandlerFunc {
+	return restoreDeleted(foodDeletable(store))
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck answers load balancers and uptime checks , it reports unavailable when the database can't be reached
+func HealthCheck(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		if err := store.Ping(ctx); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "database is not reachable"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+
+	}
+}
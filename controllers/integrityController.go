@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the references between the documents are checked by the integrity package , these helpers turn its answers into responses
+
+// referencesValid answers with 400 when the document points at something that doesn't exist
+func referencesValid(c *gin.Context, err error) bool {
+
+	if err == nil {
+		return true
+	}
+
+	var referenceErr *integrity.ReferenceError
+
+	if errors.As(err, &referenceErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the references"})
+	return false
+}
+
+// deleteRefused answers with 409 when a restrict rule didn't let the document be deleted
+func deleteRefused(c *gin.Context, err error) bool {
+
+	var restrictErr *integrity.RestrictError
+
+	if errors.As(err, &restrictErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return true
+	}
+
+	return false
+}
+
+// recordCascade writes every document the cascade rules took along to the audit log
+func recordCascade(c *gin.Context, ctx context.Context, changes []integrity.Change) {
+
+	for _, change := range changes {
+		recordChange(c, ctx, change.Entity_type, audit.ACTION_DELETE, change.Entity_id, change.Before, change.After)
+	}
+}
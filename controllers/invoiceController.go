@@ -3,17 +3,15 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
 	"go-restaurent-management-system/models"
-	"log"
+	"go-restaurent-management-system/repository"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type InvoiceViewFormat struct {
@@ -27,26 +25,27 @@ type InvoiceViewFormat struct {
 	Order_details    interface{}
 }
 
-var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoices")
-
-func GetInvoices() gin.HandlerFunc {
+func GetInvoices(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		result, err := invoiceCollection.Find(ctx, bson.M{})
-
 		defer cancel()
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the invoice documents"})
+		branchId, ok := callerBranch(c, false)
+		if !ok {
 			return
 		}
 
-		var allInvoices []bson.M
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		allInvoices, err := store.Invoices.List(ctx, branchId, showDeleted)
 
-		if err := result.All(ctx, &allInvoices); err != nil {
-			log.Fatal(err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the invoice documents"})
+			return
 		}
 
 		c.JSON(http.StatusOK, allInvoices)
@@ -54,17 +53,31 @@ func GetInvoices() gin.HandlerFunc {
 	}
 }
 
-func GetInvoice() gin.HandlerFunc {
+func GetInvoice(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		var invoice models.Invoice
+		defer cancel()
 
 		invoiceId := c.Param("invoice_id")
 
-		err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
-		defer cancel()
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		invoice, err := store.Invoices.Get(ctx, branchId, invoiceId, showDeleted)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error ": err.Error()})
 			return
@@ -72,8 +85,6 @@ func GetInvoice() gin.HandlerFunc {
 
 		var invoiceView InvoiceViewFormat
 
-		allOrderItems, err := ItemsByOrder(invoice.Order_id)
-
 		invoiceView.Order_id = invoice.Order_id
 		invoiceView.Payment_due_date = invoice.Payment_due_date
 
@@ -85,36 +96,44 @@ func GetInvoice() gin.HandlerFunc {
 
 		invoiceView.Invoice_id = invoice.Invoice_id
 		invoiceView.Payment_status = *&invoice.Payment_status
-		invoiceView.Payment_due = allOrderItems[0]["payment_due"]
-		invoiceView.Table_number = allOrderItems[0]["table_number"]
-		invoiceView.Order_details = allOrderItems[0]["order_items"]
-		//allOrderItems[0]["payment_due"] accesses the value associated with the key "payment_due" in the first map within allOrderItems.
-		//This line assigns the value of "payment_due" from the first order item map to the Payment_due field in the invoiceView struct.
+
+		// an order without items has nothing to pay , the view is sent without the order details then
+
+		summary, err := store.OrderItems.ItemsByOrder(ctx, invoice.Branch_id, invoice.Order_id)
+
+		if err != nil && err != repository.ErrNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if summary != nil {
+			invoiceView.Payment_due = summary.Payment_due
+			invoiceView.Table_number = summary.Table_number
+			invoiceView.Order_details = summary.Order_items
+		}
+
+		setETag(c, invoice.Version)
 
 		c.JSON(http.StatusOK, invoiceView)
 
 	}
 }
 
-func CreateInvoice() gin.HandlerFunc {
+func CreateInvoice(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var invoice models.Invoice
-		var order models.Order
 
 		if err := c.BindJSON(&invoice); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error ": err.Error()})
-			defer cancel()
 			return
 		}
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": invoice.Order_id}).Decode(&order)
-		if err != nil {
-			msg := fmt.Sprintf("Order is not in the order collection ")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			defer cancel()
+		branchId, ok := callerBranch(c, true)
+		if !ok {
 			return
 		}
 
@@ -127,86 +146,126 @@ func CreateInvoice() gin.HandlerFunc {
 		invoice.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.Payment_due_date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		invoice.ID = primitive.NilObjectID
+		invoice.ID = primitive.NewObjectID()
+		invoice.Version = 1
 		invoice.Invoice_id = invoice.ID.Hex()
+		invoice.Branch_id = branchId
 
-		result, err := invoiceCollection.InsertOne(ctx, invoice)
-		defer cancel()
+		// the order has to belong to the same branch as the invoice
 
-		if err != nil {
+		if !referencesValid(c, integrity.CheckInvoice(ctx, &invoice)) {
+			return
+		}
+
+		if err := store.Invoices.Create(ctx, &invoice); err != nil {
 			msg := fmt.Sprintf("invoice was not created ")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "invoice", audit.ACTION_CREATE, invoice.Invoice_id, nil, audit.Snapshot(invoice))
+
+		c.JSON(http.StatusOK, invoice)
 
 	}
 }
 
-func UpdateInvoice() gin.HandlerFunc {
+func UpdateInvoice(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var invoice models.Invoice
 
 		if err := c.BindJSON(&invoice); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-
-			defer cancel()
 			return
 		}
 
 		invoiceId := c.Param("invoice_id")
 
-		var updateObj primitive.D
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
 
-		filter := bson.M{"invoice_id": invoiceId}
+		// only invoices of the caller's branch are found , an id of another branch is a 404
 
-		if invoice.Payment_method != nil {
-			updateObj = append(updateObj, bson.E{Key: "payment_method", Value: invoice.Payment_method})
+		foundInvoice, err := store.Invoices.Get(ctx, branchId, invoiceId, false)
 
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
 		}
 
-		if invoice.Payment_status != nil {
-			updateObj = append(updateObj, bson.E{Key: "payment_status", Value: invoice.Payment_status})
-
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the invoice"})
+			return
 		}
 
-		invoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if !versionMatches(c, invoice.Version, foundInvoice.Version, foundInvoice) {
+			return
+		}
 
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: invoice.Updated_at})
+		before := audit.Snapshot(foundInvoice)
 
-		upsert := true
+		if invoice.Payment_method != nil {
+			foundInvoice.Payment_method = invoice.Payment_method
 
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
 		}
 
-		status := "PENDING"
+		if invoice.Payment_status != nil {
+			foundInvoice.Payment_status = invoice.Payment_status
 
-		if invoice.Payment_status == nil {
-			invoice.Payment_status = &status
 		}
 
-		result, err := invoiceCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
+		foundInvoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		defer cancel()
+		err = store.Invoices.Update(ctx, foundInvoice)
+
+		if err == repository.ErrVersionConflict {
+			if current, getErr := store.Invoices.Get(ctx, branchId, invoiceId, true); getErr == nil {
+				respondStale(c, current.Version, current)
+				return
+			}
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the document"})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "invoice", audit.ACTION_UPDATE, invoiceId, before, audit.Snapshot(foundInvoice))
 
+		setETag(c, foundInvoice.Version)
+
+		c.JSON(http.StatusOK, foundInvoice)
+
+	}
+}
+
+// invoiceDeletable hooks the invoices into the delete and restore handlers of deleteController.go
+func invoiceDeletable(store *repository.Store) deletable[models.Invoice] {
+	return deletable[models.Invoice]{
+		name:   "invoice",
+		entity: integrity.INVOICE,
+		param:  "invoice_id",
+		get:    store.Invoices.Get,
+		update: store.Invoices.Update,
+		stamps: func(i *models.Invoice) (**time.Time, **string, *time.Time, int) {
+			return &i.Deleted_at, &i.Deleted_by, &i.Updated_at, i.Version
+		},
+		references: integrity.CheckInvoice,
 	}
 }
+
+// DeleteInvoice only marks the invoice as deleted , see deleteController.go
+func DeleteInvoice(store *repository.Store) gin.HandlerFunc {
+	return softDelete(invoiceDeletable(store))
+}
+
+// RestoreInvoice brings a deleted invoice back , only admins can do it
+func RestoreInvoice(store *repository.Store) gin.HandlerFunc {
+	return restoreDeleted(invoiceDeletable(store))
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	helper "go-restaurent-management-system/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys of the token signer so other services can verify our tokens without holding a secret
+func GetJWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// verifiers cache the document , a rotated key has to be announced a little before it starts signing
+		c.Header("Cache-Control", "public, max-age=300")
+
+		c.JSON(http.StatusOK, gin.H{"keys": helper.JWKS()})
+
+	}
+}
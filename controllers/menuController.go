@@ -3,37 +3,38 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
 	"go-restaurent-management-system/models"
-	"log"
+	"go-restaurent-management-system/repository"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var menuCollection *mongo.Collection = database.OpenCollection(database.Client, "menu")
-
-func GetMenus() gin.HandlerFunc {
+func GetMenus(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		result, err := menuCollection.Find(context.TODO(), bson.M{})
 		defer cancel()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the menu items"})
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
 			return
 		}
 
-		var allMenus []bson.M
+		allMenus, err := store.Menus.List(ctx, branchId, showDeleted)
 
-		if err := result.All(ctx, &allMenus); err != nil {
-			log.Fatal(err)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing the menu items"})
+			return
 		}
 
 		c.JSON(http.StatusOK, allMenus)
@@ -41,62 +42,84 @@ func GetMenus() gin.HandlerFunc {
 	}
 }
 
-func GetMenu() gin.HandlerFunc {
+func GetMenu(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
 		menuId := c.Param("menu_id")
-		var menu models.Menu
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		menu, err := store.Menus.Get(ctx, branchId, menuId, showDeleted)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
+
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting the menu item"})
-			defer cancel()
 			return
 		}
 
-		c.JSON(http.StatusOK, menu)
+		setETag(c, menu.Version)
 
-		defer cancel()
+		c.JSON(http.StatusOK, menu)
 
 	}
 }
 
-func CreateMenu() gin.HandlerFunc {
+func CreateMenu(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var menu models.Menu
 
 		if err := c.BindJSON(&menu); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			defer cancel()
 			return
 		}
 
 		validationErr := validate.Struct(menu)
 		if validationErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
-			defer cancel()
 			return
 
 		}
 
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
 		menu.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.ID = primitive.NewObjectID()
+		menu.Version = 1
 		menu.Menu_id = menu.ID.Hex()
+		menu.Branch_id = branchId
 
-		result, insertErr := menuCollection.InsertOne(ctx, menu)
-		if insertErr != nil {
+		if insertErr := store.Menus.Create(ctx, &menu); insertErr != nil {
 			msg := fmt.Sprintf("error occuring while creating a menu item in the menu collection")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			defer cancel()
 			return
 		}
-		defer cancel()
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "menu", audit.ACTION_CREATE, menu.Menu_id, nil, audit.Snapshot(menu))
+
+		c.JSON(http.StatusOK, menu)
 
 	}
 }
@@ -105,73 +128,112 @@ func inTimeSpan(start, end, check time.Time) bool {
 	return start.After(time.Now()) && end.After(start)
 }
 
-func UpdateMenu() gin.HandlerFunc {
+func UpdateMenu(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 		var menu models.Menu
 
 		if err := c.BindJSON(&menu); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error ": err.Error()})
-			defer cancel()
 			return
 		}
 
 		menuId := c.Param("menu_id")
 
-		filter := bson.M{"menu_id": menuId}
-
-		var updateObj primitive.D //he primitive package is used for working with BSON (Binary JSON) data types, which are used for representing data in MongoDB documents.
-		//The primitive.D type you've mentioned is used to represent a BSON document as an ordered list of key-value pairs, similar to a dictionary or map in other programming languages.
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
 
 		if menu.Start_Date != nil && menu.End_Date != nil {
 			if !inTimeSpan(*menu.Start_Date, *menu.End_Date, time.Now()) {
 				msg := "Kindly retype the time"
 
 				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-				defer cancel()
 				return
 			}
 
-			updateObj = append(updateObj, bson.E{Key: "start_date", Value: menu.Start_Date})
-			updateObj = append(updateObj, bson.E{Key: "end_date", Value: menu.End_Date})
+			// only menus of the caller's branch are found , an id of another branch is a 404
+
+			foundMenu, err := store.Menus.Get(ctx, branchId, menuId, false)
+
+			if err == repository.ErrNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+				return
+			}
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while getting the menu item"})
+				return
+			}
+
+			if !versionMatches(c, menu.Version, foundMenu.Version, foundMenu) {
+				return
+			}
+
+			before := audit.Snapshot(foundMenu)
+
+			foundMenu.Start_Date = menu.Start_Date
+			foundMenu.End_Date = menu.End_Date
 
 			if menu.Name != "" {
-				updateObj = append(updateObj, bson.E{Key: "name", Value: menu.Name})
+				foundMenu.Name = menu.Name
 			}
 
 			if menu.Category != "" {
-				updateObj = append(updateObj, bson.E{Key: "category", Value: menu.Category})
+				foundMenu.Category = menu.Category
 			}
 
-			menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-			updateObj = append(updateObj, bson.E{Key: "updated_at", Value: menu.Updated_at})
+			foundMenu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-			upsert := true
+			err = store.Menus.Update(ctx, foundMenu)
 
-			opt := options.UpdateOptions{
-				Upsert: &upsert,
+			if err == repository.ErrVersionConflict {
+				if current, getErr := store.Menus.Get(ctx, branchId, menuId, true); getErr == nil {
+					respondStale(c, current.Version, current)
+					return
+				}
 			}
 
-			result, err := menuCollection.UpdateOne(
-				ctx,
-				filter,
-				bson.D{
-					{Key: "$set", Value: updateObj},
-				},
-				&opt,
-			)
-
 			if err != nil {
 				msg := "Menu Update Failed"
 
 				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-				defer cancel()
 				return
 			}
 
-			c.JSON(http.StatusOK, result)
+			recordChange(c, ctx, "menu", audit.ACTION_UPDATE, menuId, before, audit.Snapshot(foundMenu))
+
+			setETag(c, foundMenu.Version)
+
+			c.JSON(http.StatusOK, foundMenu)
 		}
 
 	}
 }
+
+// menuDeletable hooks the menus into the delete and restore handlers of deleteController.go
+func menuDeletable(store *repository.Store) deletable[models.Menu] {
+	return deletable[models.Menu]{
+		name:   "menu",
+		entity: integrity.MENU,
+		param:  "menu_id",
+		get:    store.Menus.Get,
+		update: store.Menus.Update,
+		stamps: func(m *models.Menu) (**time.Time, **string, *time.Time, int) {
+			return &m.Deleted_at, &m.Deleted_by, &m.Updated_at, m.Version
+		},
+	}
+}
+
+// DeleteMenu only marks the menu as deleted , see deleteController.go
+func DeleteMenu(store *repository.Store) gin.HandlerFunc {
+	return softDelete(menuDeletable(store))
+}
+
+// RestoreMenu brings a deleted menu back , only admins can do it
+func RestoreMenu(store *repository.Store) gin.HandlerFunc {
+	return restoreDeleted(menuDeletable(store))
+}
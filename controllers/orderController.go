@@ -3,38 +3,39 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
 	"go-restaurent-management-system/models"
-	"log"
+	"go-restaurent-management-system/repository"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var orderCollection *mongo.Collection = database.OpenCollection(database.Client, "orders")
-
-func GetOrders() gin.HandlerFunc {
+func GetOrders(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-		result, err := orderCollection.Find(ctx, bson.M{})
-		if err != nil {
-			msg := fmt.Sprintf("error occured while finding the orders in the orderCollection")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
 
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
 		}
-		defer cancel()
 
-		var allOrders []bson.M
+		allOrders, err := store.Orders.List(ctx, branchId, showDeleted)
 
-		if err := result.All(ctx, &allOrders); err != nil {
-			log.Fatal(err)
+		if err != nil {
+			msg := fmt.Sprintf("error occured while finding the orders in the orderCollection")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
 		}
 
 		c.JSON(http.StatusOK, allOrders)
@@ -42,115 +43,153 @@ func GetOrders() gin.HandlerFunc {
 	}
 }
 
-func GetOrder() gin.HandlerFunc {
+func GetOrder(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		orderId := c.Param("order_id")
 
-		var order models.Order
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		order, err := store.Orders.Get(ctx, branchId, orderId, showDeleted)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
-		defer cancel()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding for the order "})
 			return
 
 		}
 
+		setETag(c, order.Version)
+
 		c.JSON(http.StatusOK, order)
 
 	}
 }
 
-func CreateOrder() gin.HandlerFunc {
+func CreateOrder(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-		var table models.Table
 		var order models.Order
 
-		if order.Table_id != nil {
+		if err := c.BindJSON(&order); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-			err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table)
-			defer cancel()
-			if err != nil {
-				msg := fmt.Sprintf("error occured while finding the table ")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-				return
-			}
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
+		// an order without a date was taken just now
+
+		if order.Order_date.IsZero() {
+			order.Order_date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		}
 
 		order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 		order.ID = primitive.NewObjectID()
+		order.Version = 1
 		order.Order_id = order.ID.Hex()
+		order.Branch_id = branchId
 
-		result, err := orderCollection.InsertOne(ctx, order)
-		defer cancel()
+		if !referencesValid(c, integrity.CheckOrder(ctx, &order)) {
+			return
+		}
 
-		if err != nil {
+		if err := store.Orders.Create(ctx, &order); err != nil {
 			msg := fmt.Sprintf("error occured while inserting document")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "order", audit.ACTION_CREATE, order.Order_id, nil, audit.Snapshot(order))
+
+		c.JSON(http.StatusOK, order)
 
 	}
 }
 
-func UpdateOrder() gin.HandlerFunc {
+func UpdateOrder(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
 		var order models.Order
 
 		if err := c.BindJSON(&order); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-
+			return
 		}
 
 		orderId := c.Param("order_id")
 
-		var updateObj primitive.D
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
 
-		if order.Table_id != nil {
-			err := orderCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&order)
-			defer cancel()
-			if err != nil {
-				msg := fmt.Sprintf("error:Menu was not found")
-				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-				return
+		// only orders of the caller's branch are found , an id of another branch is a 404
 
-			}
-			updateObj = append(updateObj, bson.E{Key: "menu", Value: order.Table_id})
+		foundOrder, err := store.Orders.Get(ctx, branchId, orderId, false)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
 		}
 
-		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: order.Updated_at})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding for the order "})
+			return
+		}
+
+		if !versionMatches(c, order.Version, foundOrder.Version, foundOrder) {
+			return
+		}
 
-		upsert := true
+		before := audit.Snapshot(foundOrder)
 
-		filter := bson.M{"order_id": orderId}
+		// the order is moved to another table of the same branch
 
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		if order.Table_id != nil {
+			foundOrder.Table_id = order.Table_id
+
+			if !referencesValid(c, integrity.CheckOrder(ctx, foundOrder)) {
+				return
+			}
 		}
 
-		result, err := orderCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
+		foundOrder.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		defer cancel()
+		err = store.Orders.Update(ctx, foundOrder)
+
+		if err == repository.ErrVersionConflict {
+			if current, getErr := store.Orders.Get(ctx, branchId, orderId, true); getErr == nil {
+				respondStale(c, current.Version, current)
+				return
+			}
+		}
 
 		if err != nil {
 			msg := fmt.Sprintf("error occured while updating the document")
@@ -158,24 +197,36 @@ func UpdateOrder() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
-
-	}
-}
-
-func OrderItemOrderCreator(order models.Order) string {
+		recordChange(c, ctx, "order", audit.ACTION_UPDATE, orderId, before, audit.Snapshot(foundOrder))
 
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		setETag(c, foundOrder.Version)
 
-	order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-	order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		c.JSON(http.StatusOK, foundOrder)
 
-	order.ID = primitive.NewObjectID()
-	order.Order_id = order.ID.Hex()
+	}
+}
 
-	orderCollection.InsertOne(ctx, order)
+// orderDeletable hooks the orders into the delete and restore handlers of deleteController.go
+func orderDeletable(store *repository.Store) deletable[models.Order] {
+	return deletable[models.Order]{
+		name:   "order",
+		entity: integrity.ORDER,
+		param:  "order_id",
+		get:    store.Orders.Get,
+		update: store.Orders.Update,
+		stamps: func(o *models.Order) (**time.Time, **string, *time.Time, int) {
+			return &o.Deleted_at, &o.Deleted_by, &o.Updated_at, o.Version
+		},
+		references: integrity.CheckOrder,
+	}
+}
 
-	defer cancel()
+// DeleteOrder only marks the order as deleted , see deleteController.go
+func DeleteOrder(store *repository.Store) gin.HandlerFunc {
+	return softDelete(orderDeletable(store))
+}
 
-	return order.Order_id
+// RestoreOrder brings a deleted order back , only admins can do it
+func RestoreOrder(store *repository.Store) gin.HandlerFunc {
+	return restoreDeleted(orderDeletable(store))
 }
@@ -3,17 +3,15 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
 	"go-restaurent-management-system/models"
-	"log"
+	"go-restaurent-management-system/repository"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrderItemPack struct {
@@ -21,27 +19,34 @@ type OrderItemPack struct {
 	Order_items []models.OrderItem
 }
 
-var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderItem")
+// OrderWithItems is what CreateOrderItem answers with , the new order together with the items created for it
+type OrderWithItems struct {
+	models.Order
+	Order_items []models.OrderItem `json:"order_items"`
+}
 
-func GetOrderItems() gin.HandlerFunc {
+func GetOrderItems(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-		result, err := orderItemCollection.Find(ctx, bson.M{}) // empty bson.M{} indicates querying for all the records present in the collection .
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
 
-		if err != nil {
-			msg := fmt.Sprintf("error occured while finding the orders in the orderItem 	 Collection")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			defer cancel()
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
 			return
 		}
-		defer cancel()
 
-		var allOrderItems []bson.M
+		allOrderItems, err := store.OrderItems.List(ctx, branchId, showDeleted)
 
-		if err := result.All(ctx, &allOrderItems); err != nil {
-			log.Fatal(err)
+		if err != nil {
+			msg := fmt.Sprintf("error occured while finding the orders in the orderItem 	 Collection")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
 		}
 
 		c.JSON(http.StatusOK, allOrderItems)
@@ -49,12 +54,20 @@ func GetOrderItems() gin.HandlerFunc {
 	}
 }
 
-func GetOrderItemsByOrder() gin.HandlerFunc {
+func GetOrderItemsByOrder(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
 		orderId := c.Param("order_id")
 
-		allOrderItems, err := ItemsByOrder(orderId)
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		allOrderItems, err := ItemsByOrder(ctx, store, orderId, branchId)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -66,102 +79,68 @@ func GetOrderItemsByOrder() gin.HandlerFunc {
 	}
 }
 
-func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	foodMatchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "order_id", Value: id}}}}                                                                                                               // matchStage : obtains the documents which are matched with the given query
-	foodLookUpStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "food"}, {Key: "localfield", Value: "food_id"}, {Key: "foreignfield", Value: "food_id"}, {Key: "as", Value: "food"}}}} // lookup stage : it is used to run the query to obtain the fiels of other document using the foreign key field of current document .
-	// from : used to get the data from the other documents
-	foodUnwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}} // afte lookup stage , the result comes as array , with which mongodb won't run . Therefore  , unwind stage is used to convert array form into some other form
-	// path : this keyword gets the document on which unwinding has to be performed
-
-	orderLookUpStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "order"}, {Key: "localfield", Value: "order_id"}, {Key: "foreignfield", Value: "order_id"}, {Key: "as", Value: "order"}}}}
-	orderUnwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$order"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	tableLookUpStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "table"}, {Key: "localfield", Value: "order.table_id"}, {Key: "foreignfield", Value: "table_id"}, {Key: "as", Value: "table"}}}}
-	tableUnwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$table"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-	// projectStage is basically to manage the fields to be sent to frontend
-	projectStage := bson.D{
-
-		{Key: "$project", Value: bson.D{
-			{Key: "id", Value: 0},
-			{Key: "amount", Value: "$food.price"},
-			{Key: "totat_count", Value: 1},
-			{Key: "food_name", Value: "$food.name"},
-			{Key: "food_image", Value: "$food.food_image"},
-			{Key: "table_number", Value: "$table.table_number"},
-			{Key: "table_id", Value: "$table.table_id"},
-			{Key: "order_id", Value: "$order.order_id"},
-			{Key: "price", Value: "$food.price"},
-			{Key: "quantity", Value: 1},
-		}}}
-
-	groupStage := bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "order_id", Value: "$order_id"}, {Key: "table_id", Value: "$table_id"}, {Key: "table_number", Value: "$table_number"}}}, {Key: "payment_due", Value: bson.D{{Key: "$sum", Value: "$amount"}}}, {Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}}, {Key: "order_items", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}}}}}
-
-	projectStage2 := bson.D{
-		{Key: "$project", Value: bson.D{
-
-			{Key: "id", Value: 0},
-			{Key: "payment_due", Value: 1},
-			{Key: "total_count", Value: 1},
-			{Key: "table_number", Value: "$_id.table_number"},
-			{Key: "order_items", Value: 1},
-		}}}
-
-	result, err := orderItemCollection.Aggregate(ctx, mongo.Pipeline{
-		foodMatchStage,
-		foodLookUpStage,
-		foodUnwindStage,
-		orderLookUpStage,
-		orderUnwindStage,
-		tableLookUpStage,
-		tableUnwindStage,
-		projectStage,
-		groupStage,
-		projectStage2})
+// ItemsByOrder only reads the order items of the branch , an empty branch id is the head office reading any branch.
+// the summary comes back in a list like the old aggregation returned it , an order without items is an empty list
+func ItemsByOrder(ctx context.Context, store *repository.Store, id string, branchId string) (OrderItems []repository.OrderSummary, err error) {
 
-	if err != nil {
-		panic(err)
-	}
+	summary, err := store.OrderItems.ItemsByOrder(ctx, branchId, id)
 
-	if err = result.All(ctx, &OrderItems); err != nil {
-		panic(err)
+	if err == repository.ErrNotFound {
+		return []repository.OrderSummary{}, nil
 	}
 
-	defer cancel()
+	if err != nil {
+		return nil, err
+	}
 
-	return OrderItems, err
+	return []repository.OrderSummary{*summary}, nil
 
 }
 
-func GetOrderItem() gin.HandlerFunc {
+func GetOrderItem(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		orderItemId := c.Param("order_item_id")
 
-		var orderItem models.OrderItem
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		orderItem, err := store.OrderItems.Get(ctx, branchId, orderItemId, showDeleted)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
 
-		err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "orderitem is not found in the orderitem collection"})
-			defer cancel()
 			return
 		}
 
-		defer cancel()
+		setETag(c, orderItem.Version)
 
 		c.JSON(http.StatusOK, orderItem)
 
 	}
 }
 
-func CreateOrderItem() gin.HandlerFunc {
+// CreateOrderItem opens a new order with all of its items , the whole pack is checked before anything is written
+// and the order and the items are stored together , so a failure never leaves an order without its items behind
+func CreateOrderItem(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var orderItemPack OrderItemPack
 		var order models.Order
@@ -170,54 +149,140 @@ func CreateOrderItem() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": err.Error(),
 			})
+			return
+		}
+
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
+		if len(orderItemPack.Order_items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "an order needs at least one order item"})
+			return
 		}
 
-		orderItemsToBeInserted := []interface{}{} // It appears to be creating an empty slice named orderItemsToBeInserted with the type []interface{
 		order.Order_date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.ID = primitive.NewObjectID()
+		order.Version = 1
+		order.Order_id = order.ID.Hex()
 		order.Table_id = orderItemPack.Table_id
+		order.Branch_id = branchId
 
-		order_id := OrderItemOrderCreator(order)
+		orderItemsToBeInserted := []models.OrderItem{}
 
-		for _, orderItem := range orderItemPack.Order_items {
-			orderItem.Order_id = order_id
+		for i, orderItem := range orderItemPack.Order_items {
+			orderItem.Order_id = order.Order_id
 
-			validationErr := validate.Struct(orderItem)
-
-			if validationErr != nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": validationErr.Error(),
-				})
+			if validationErr := validate.Struct(orderItem); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("order item %d: %s", i, validationErr.Error())})
+				return
 			}
 
 			orderItem.ID = primitive.NewObjectID()
+			orderItem.Version = 1
 			orderItem.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.Order_item_id = orderItem.ID.Hex()
-			var num = toFixed(*orderItem.Unit_price, 2)
-
-			orderItem.Unit_price = &num
+			orderItem.Branch_id = branchId
 
 			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
 
 		}
 
-		insertedItems, err := orderItemCollection.InsertMany(ctx, orderItemsToBeInserted)
-		defer cancel()
+		// the table and every food have to belong to the same branch , nothing is stored when one of them doesn't
+
+		if !referencesValid(c, integrity.CheckNewOrder(ctx, &order, orderItemsToBeInserted)) {
+			return
+		}
+
+		// the unit prices come from the foods and the sizes , a price the client sends is not used
+
+		for i := range orderItemsToBeInserted {
+			if !priceOrderItem(c, ctx, store, &orderItemsToBeInserted[i], fmt.Sprintf("order item %d: ", i)) {
+				return
+			}
+		}
+
+		err := store.Orders.CreateWithItems(ctx, &order, orderItemsToBeInserted)
+
+		if err == repository.ErrDuplicate {
+			c.JSON(http.StatusConflict, gin.H{"error": "the order or one of its items already exists"})
+			return
+		}
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while creating the order and its items"})
 			return
 		}
 
-		c.JSON(http.StatusOK, insertedItems)
+		recordChange(c, ctx, "order", audit.ACTION_CREATE, order.Order_id, nil, audit.Snapshot(order))
+
+		for _, orderItem := range orderItemsToBeInserted {
+			recordChange(c, ctx, "order_item", audit.ACTION_CREATE, orderItem.Order_item_id, nil, audit.Snapshot(orderItem))
+		}
+
+		c.JSON(http.StatusOK, OrderWithItems{Order: order, Order_items: orderItemsToBeInserted})
 
 	}
 }
 
-func UpdateOrderItem() gin.HandlerFunc {
+// priceOrderItem sets the unit price of the item to the price of its food in its size with the price deltas of its modifiers ,
+// it answers 400 when the food isn't sold in that size or the modifiers don't fit the food. prefix tells the items of a pack apart in the error
+func priceOrderItem(c *gin.Context, ctx context.Context, store *repository.Store, orderItem *models.OrderItem, prefix string) bool {
+
+	if orderItem.Food_id == nil || orderItem.Quantity == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": prefix + "the item needs a food_id and a quantity to be priced"})
+		return false
+	}
+
+	food, err := store.Foods.Get(ctx, orderItem.Branch_id, *orderItem.Food_id, false)
+
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": prefix + "food_id: the food was not found"})
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+		return false
+	}
+
+	price, ok := food.PriceOf(*orderItem.Quantity)
+
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%sthe food is not available in size %s", prefix, *orderItem.Quantity)})
+		return false
+	}
+
+	// the picked modifiers are checked against the groups of the food and add their price deltas
+
+	modifiers, delta, err := models.ResolveModifiers(food.Modifier_groups, orderItem.Modifiers)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": prefix + "modifiers: " + err.Error()})
+		return false
+	}
+
+	if price+delta < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": prefix + "modifiers: the price deltas make the price negative"})
+		return false
+	}
+
+	var num = toFixed(price+delta, 2)
+	orderItem.Unit_price = &num
+	orderItem.Modifiers = modifiers
+
+	return true
+}
+
+func UpdateOrderItem(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var orderItem models.OrderItem
 
@@ -225,50 +290,110 @@ func UpdateOrderItem() gin.HandlerFunc {
 
 		if err := c.BindJSON(&orderItem); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			defer cancel()
 			return
 		}
 
-		filter := bson.M{"order_item_id": orderItemId}
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
+
+		// only order items of the caller's branch are found , an id of another branch is a 404
+
+		foundOrderItem, err := store.OrderItems.Get(ctx, branchId, orderItemId, false)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
 
-		var updateObj primitive.D
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-		if orderItem.Unit_price != nil {
-			updateObj = append(updateObj, bson.E{Key: "unit_price", Value: *orderItem.Unit_price})
+		if !versionMatches(c, orderItem.Version, foundOrderItem.Version, foundOrderItem) {
+			return
 		}
 
+		before := audit.Snapshot(foundOrderItem)
+
 		if orderItem.Quantity != nil {
-			updateObj = append(updateObj, bson.E{Key: "quantity", Value: *orderItem.Quantity})
+			if validationErr := validate.Var(*orderItem.Quantity, "eq=S|eq=M|eq=L"); validationErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "quantity has to be S , M or L"})
+				return
+			}
+
+			foundOrderItem.Quantity = orderItem.Quantity
 		}
 
 		if orderItem.Food_id != nil {
-			updateObj = append(updateObj, bson.E{Key: "food_id", Value: orderItem.Food_id})
+			foundOrderItem.Food_id = orderItem.Food_id
+
+			if !referencesValid(c, integrity.CheckOrderItem(ctx, foundOrderItem)) {
+				return
+			}
 		}
 
-		orderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: orderItem.Updated_at})
+		// the modifiers are replaced as a whole , a new food needs modifiers that fit it
 
-		upsert := true
+		if orderItem.Modifiers != nil {
+			foundOrderItem.Modifiers = orderItem.Modifiers
+		}
+
+		// a new food , size or modifier is priced again , the unit price itself can't be changed
 
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		if orderItem.Quantity != nil || orderItem.Food_id != nil || orderItem.Modifiers != nil {
+			if !priceOrderItem(c, ctx, store, foundOrderItem, "") {
+				return
+			}
 		}
 
-		result, err := orderItemCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
+		foundOrderItem.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.OrderItems.Update(ctx, foundOrderItem)
+
+		if err == repository.ErrVersionConflict {
+			if current, getErr := store.OrderItems.Get(ctx, branchId, orderItemId, true); getErr == nil {
+				respondStale(c, current.Version, current)
+				return
+			}
+		}
 
-		defer cancel()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "order_item", audit.ACTION_UPDATE, orderItemId, before, audit.Snapshot(foundOrderItem))
+
+		setETag(c, foundOrderItem.Version)
+
+		c.JSON(http.StatusOK, foundOrderItem)
 	}
 }
+
+// orderItemDeletable hooks the order items into the delete and restore handlers of deleteController.go
+func orderItemDeletable(store *repository.Store) deletable[models.OrderItem] {
+	return deletable[models.OrderItem]{
+		name:   "order item",
+		entity: integrity.ORDER_ITEM,
+		param:  "order_item_id",
+		get:    store.OrderItems.Get,
+		update: store.OrderItems.Update,
+		stamps: func(i *models.OrderItem) (**time.Time, **string, *time.Time, int) {
+			return &i.Deleted_at, &i.Deleted_by, &i.Updated_at, i.Version
+		},
+		references: integrity.CheckOrderItem,
+	}
+}
+
+// DeleteOrderItem only marks the order item as deleted , see deleteController.go
+func DeleteOrderItem(store *repository.Store) gin.HandlerFunc {
+	return softDelete(orderItemDeletable(store))
+}
+
+// RestoreOrderItem brings a deleted order item back , only admins can do it
+func RestoreOrderItem(store *repository.Store) gin.HandlerFunc {
+	return restoreDeleted(orderItemDeletable(store))
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/notifier"
+	"go-restaurent-management-system/repository"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var mailer notifier.Notifier = notifier.New()
+
+// a reset token can only be used within this time after it was requested
+const passwordResetValidity = time.Hour
+
+type ForgotPasswordRequest struct {
+	Email *string `json:"email" validate:"email,required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    *string `json:"token" validate:"required"`
+	Password *string `json:"password" validate:"required,min=6"`
+}
+
+func ForgotPassword(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request ForgotPasswordRequest
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		// the requests are throttled before the email is looked up , so a flood of requests neither fills an inbox nor the mail relay
+
+		clientIp := c.ClientIP()
+
+		wait, err := helper.PasswordResetRetryAfter(ctx, *request.Email, clientIp)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the reset requests"})
+			return
+		}
+
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many password reset requests , try again later"})
+			return
+		}
+
+		if err := helper.RecordPasswordResetRequest(ctx, *request.Email, clientIp); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while recording the reset request"})
+			return
+		}
+
+		// the answer is the same whether the email exists or not , otherwise this endpoint could be used to find out who works here.
+		// that includes the failures that can only happen for an existing account , they are logged and the caller gets the same 200
+		response := gin.H{"message": "if the email belongs to an account , a reset link has been sent to it"}
+
+		foundUser, err := store.Users.GetByEmail(ctx, *request.Email)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the user"})
+			return
+		}
+
+		token, err := helper.GenerateSecret(32)
+
+		if err != nil {
+			log.Println("password reset token could not be generated:", err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// only the newest reset token of a user can be used
+
+		if err := store.PasswordResets.InvalidateAll(ctx, foundUser.User_id, now); err != nil {
+			log.Println("old password reset tokens could not be invalidated:", err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		reset := models.PasswordReset{
+			ID:         primitive.NewObjectID(),
+			Token_hash: helper.HashSecret(token),
+			User_id:    foundUser.User_id,
+			Expires_at: now.Add(passwordResetValidity),
+			Created_at: now,
+		}
+
+		if err := store.PasswordResets.Create(ctx, &reset); err != nil {
+			log.Println("password reset token could not be stored:", err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		message := notifier.Message{
+			To:      *foundUser.Email,
+			Subject: "Reset your password",
+			Body:    passwordResetBody(token),
+		}
+
+		if err := mailer.Send(ctx, message); err != nil {
+			log.Println("password reset mail could not be sent:", err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+
+	}
+}
+
+func ResetPassword(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request ResetPasswordRequest
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		// the token is marked as used in the same call that finds it , so it can't be redeemed twice
+
+		reset, err := store.PasswordResets.Redeem(ctx, helper.HashSecret(*request.Token), now)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the reset token is invalid or has expired"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the reset token"})
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, "", reset.User_id)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the user of this reset token no longer exists"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the user"})
+			return
+		}
+
+		password := HashPassword(*request.Password)
+
+		foundUser.Password = &password
+		foundUser.Updated_at = now
+
+		err = store.Users.Update(ctx, foundUser)
+
+		// the caller isn't logged in , so the user isn't sent back like on the other conflicts
+
+		if err == repository.ErrVersionConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "the account was changed at the same time , please request a new reset link"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the password"})
+			return
+		}
+
+		// whoever knew the old password must not stay logged in
+
+		if err := helper.Revocations.RevokeAllTokens(ctx, reset.User_id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the sessions"})
+			return
+		}
+
+		if err := helper.ClearTokens(reset.User_id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while removing the stored tokens"})
+			return
+		}
+
+		audit.Record(ctx, models.AuditEvent{
+			Actor_id:    reset.User_id,
+			Actor_type:  "user",
+			Action:      "user.reset_password",
+			Entity_type: "user",
+			Entity_id:   reset.User_id,
+		})
+
+		c.JSON(http.StatusOK, gin.H{"message": "the password has been reset"})
+
+	}
+}
+
+// passwordResetBody links to the frontend when PASSWORD_RESET_URL is set , otherwise it only contains the token
+func passwordResetBody(token string) string {
+
+	body := "A password reset was requested for your account. It is valid for one hour.\r\n\r\n"
+
+	if resetUrl := os.Getenv("PASSWORD_RESET_URL"); resetUrl != "" {
+		return body + "Open " + resetUrl + "?token=" + token + " to choose a new password.\r\n"
+	}
+
+	return body + "Your reset token is " + token + "\r\n"
+}
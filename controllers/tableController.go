@@ -3,40 +3,39 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/integrity"
 	"go-restaurent-management-system/models"
-	"log"
+	"go-restaurent-management-system/repository"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "tables")
-
-func GetTables() gin.HandlerFunc {
+func GetTables(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-		result, err := tableCollection.Find(ctx, bson.M{}) // empty bson.M{} indicates querying for all the records present in the collection .
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
 
-		if err != nil {
-			msg := fmt.Sprintf("error occured while finding the tables in the orderItem Collection")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			defer cancel()
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
 			return
 		}
-		defer cancel()
 
-		var allTables []bson.M
+		allTables, err := store.Tables.List(ctx, branchId, showDeleted)
 
-		if err := result.All(ctx, &allTables); err != nil {
-			log.Fatal(err)
+		if err != nil {
+			msg := fmt.Sprintf("error occured while finding the tables in the orderItem Collection")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
 		}
 
 		c.JSON(http.StatusOK, allTables)
@@ -44,40 +43,59 @@ func GetTables() gin.HandlerFunc {
 	}
 }
 
-func GetTable() gin.HandlerFunc {
+func GetTable(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		tableId := c.Param("table_id")
 
-		var table models.Table
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		showDeleted, ok := includeDeleted(c)
+		if !ok {
+			return
+		}
+
+		table, err := store.Tables.Get(ctx, branchId, tableId, showDeleted)
 
-		err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table) // empty bson.M{} indicates querying for all the records present in the collection .
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
 
 		if err != nil {
 			msg := fmt.Sprintf("error occured while finding the table in the table 	 Collection")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-			defer cancel()
 			return
 		}
-		defer cancel()
+
+		setETag(c, table.Version)
 
 		c.JSON(http.StatusOK, table)
 
 	}
 }
 
-func CreateTable() gin.HandlerFunc {
+func CreateTable(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var table models.Table
 
 		if err := c.BindJSON(&table); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			defer cancel()
+			return
+		}
+
+		branchId, ok := callerBranch(c, true)
+		if !ok {
 			return
 		}
 
@@ -85,10 +103,16 @@ func CreateTable() gin.HandlerFunc {
 		table.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 		table.ID = primitive.NewObjectID()
+		table.Version = 1
 		table.Table_id = table.ID.Hex()
+		table.Branch_id = branchId
 
-		result, insertErr := tableCollection.InsertOne(ctx, table)
-		defer cancel()
+		insertErr := store.Tables.Create(ctx, &table)
+
+		if insertErr == repository.ErrDuplicate {
+			c.JSON(http.StatusConflict, gin.H{"error": "this table number is already used in the branch , a deleted table keeps its number until it is restored"})
+			return
+		}
 
 		if insertErr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while inserting document into the collection "})
@@ -96,64 +120,109 @@ func CreateTable() gin.HandlerFunc {
 
 		}
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "table", audit.ACTION_CREATE, table.Table_id, nil, audit.Snapshot(table))
+
+		c.JSON(http.StatusOK, table)
 
 	}
 }
 
-func UpdateTable() gin.HandlerFunc {
+func UpdateTable(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var table models.Table
 
 		tableId := c.Param("table_id")
-		filter := bson.M{"table_id": tableId}
-
-		var updateObj primitive.D
 
 		if err := c.BindJSON(&table); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			defer cancel()
 			return
 		}
 
-		table.ID = primitive.NewObjectID()
-		table.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		branchId, ok := callerBranch(c, true)
+		if !ok {
+			return
+		}
 
-		if table.Number_of_guests != nil {
-			updateObj = append(updateObj, bson.E{Key: "number_of_guests", Value: *table.Number_of_guests})
+		// only tables of the caller's branch are found , an id of another branch is a 404
 
+		foundTable, err := store.Tables.Get(ctx, branchId, tableId, false)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
 		}
 
-		if table.Table_number != nil {
-			updateObj = append(updateObj, bson.E{Key: "table_number", Value: table.Table_number})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the table"})
+			return
+		}
+
+		if !versionMatches(c, table.Version, foundTable.Version, foundTable) {
+			return
 		}
 
-		upsert := true
+		before := audit.Snapshot(foundTable)
 
-		opts := options.UpdateOptions{
-			Upsert: &upsert,
+		if table.Number_of_guests != nil {
+			foundTable.Number_of_guests = table.Number_of_guests
 		}
 
-		result, err := tableCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opts,
-		)
+		if table.Table_number != nil {
+			foundTable.Table_number = table.Table_number
+		}
 
-		defer cancel()
+		err = store.Tables.Update(ctx, foundTable)
+
+		if err == repository.ErrVersionConflict {
+			if current, getErr := store.Tables.Get(ctx, branchId, tableId, true); getErr == nil {
+				respondStale(c, current.Version, current)
+				return
+			}
+		}
+
+		if err == repository.ErrDuplicate {
+			c.JSON(http.StatusConflict, gin.H{"error": "this table number is already used in the branch , a deleted table keeps its number until it is restored"})
+			return
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the document"})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		recordChange(c, ctx, "table", audit.ACTION_UPDATE, tableId, before, audit.Snapshot(foundTable))
+
+		setETag(c, foundTable.Version)
 
+		c.JSON(http.StatusOK, foundTable)
+
+	}
+}
+
+// tableDeletable hooks the tables into the delete and restore handlers of deleteController.go
+func tableDeletable(store *repository.Store) deletable[models.Table] {
+	return deletable[models.Table]{
+		name:   "table",
+		entity: integrity.TABLE,
+		param:  "table_id",
+		get:    store.Tables.Get,
+		update: store.Tables.Update,
+		stamps: func(t *models.Table) (**time.Time, **string, *time.Time, int) {
+			return &t.Deleted_at, &t.Deleted_by, &t.Updated_at, t.Version
+		},
 	}
 }
+
+// DeleteTable only marks the table as deleted , see deleteController.go
+func DeleteTable(store *repository.Store) gin.HandlerFunc {
+	return softDelete(tableDeletable(store))
+}
+
+// RestoreTable brings a deleted table back , only admins can do it
+func RestoreTable(store *repository.Store) gin.HandlerFunc {
+	return restoreDeleted(tableDeletable(store))
+}
@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"context"
+	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// how many single use recovery codes a user gets when two factor authentication is turned on
+const recoveryCodeCount = 10
+
+type TwoFactorCodeRequest struct {
+	Code *string `json:"code" validate:"required"`
+}
+
+type LoginSecondFactorRequest struct {
+	Challenge_token *string `json:"challenge_token" validate:"required"`
+	Code            *string `json:"code" validate:"required"`
+}
+
+// EnrollTwoFactor creates a new TOTP secret for the logged in user , it only becomes active after ConfirmTwoFactor has seen a valid code
+func EnrollTwoFactor(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		foundUser, err := store.Users.Get(ctx, "", c.GetString("uid"))
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		if foundUser.Two_factor_enabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two factor authentication is already enabled"})
+			return
+		}
+
+		secret, err := helper.GenerateTOTPSecret()
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the secret"})
+			return
+		}
+
+		foundUser.Totp_secret = &secret
+		foundUser.Two_factor_enabled = false
+		foundUser.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Users.Update(ctx, foundUser)
+
+		if err == repository.ErrVersionConflict {
+			respondUserConflict(c, ctx, store, foundUser.User_id)
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the secret"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"secret":      secret,
+			"otpauth_uri": helper.TOTPURI(secret, totpIssuer(), *foundUser.Email),
+		})
+
+	}
+}
+
+// ConfirmTwoFactor turns two factor authentication on once the user proves the authenticator app produces the right codes , the recovery codes are only shown here
+func ConfirmTwoFactor(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request TwoFactorCodeRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, "", c.GetString("uid"))
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		if foundUser.Two_factor_enabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two factor authentication is already enabled"})
+			return
+		}
+
+		if foundUser.Totp_secret == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "two factor authentication has to be enrolled first"})
+			return
+		}
+
+		step, ok := helper.ValidateTOTP(*foundUser.Totp_secret, *request.Code, time.Now())
+
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the code is not valid"})
+			return
+		}
+
+		recoveryCodes := make([]string, 0, recoveryCodeCount)
+		hashedCodes := make([]string, 0, recoveryCodeCount)
+
+		for i := 0; i < recoveryCodeCount; i++ {
+			code, err := helper.GenerateSecret(5)
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the recovery codes"})
+				return
+			}
+
+			recoveryCodes = append(recoveryCodes, code)
+			hashedCodes = append(hashedCodes, helper.HashSecret(code))
+		}
+
+		foundUser.Two_factor_enabled = true
+		foundUser.Totp_last_step = step
+		foundUser.Recovery_codes = hashedCodes
+		foundUser.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Users.Update(ctx, foundUser)
+
+		if err == repository.ErrVersionConflict {
+			respondUserConflict(c, ctx, store, foundUser.User_id)
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while enabling two factor authentication"})
+			return
+		}
+
+		recordChange(c, ctx, "user", "enable_two_factor", foundUser.User_id, nil, nil)
+
+		c.JSON(http.StatusOK, gin.H{"two_factor_enabled": true, "recovery_codes": recoveryCodes})
+
+	}
+}
+
+// DisableTwoFactor needs a current code or a recovery code , a stolen access token alone can't turn the second factor off
+func DisableTwoFactor(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request TwoFactorCodeRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, "", c.GetString("uid"))
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		if !foundUser.Two_factor_enabled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "two factor authentication is not enabled"})
+			return
+		}
+
+		valid, err := verifySecondFactor(ctx, store, *foundUser, *request.Code)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the code"})
+			return
+		}
+
+		if !valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the code is not valid"})
+			return
+		}
+
+		// the secrets are dropped , enrolling again starts with a new secret
+
+		foundUser.Two_factor_enabled = false
+		foundUser.Totp_secret = nil
+		foundUser.Totp_last_step = 0
+		foundUser.Recovery_codes = nil
+		foundUser.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Users.Update(ctx, foundUser)
+
+		if err == repository.ErrVersionConflict {
+			respondUserConflict(c, ctx, store, foundUser.User_id)
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while disabling two factor authentication"})
+			return
+		}
+
+		recordChange(c, ctx, "user", "disable_two_factor", foundUser.User_id, nil, nil)
+
+		c.JSON(http.StatusOK, gin.H{"two_factor_enabled": false})
+
+	}
+}
+
+// LoginSecondFactor is the second step of Login() for users with two factor authentication , the tokens are only issued here
+func LoginSecondFactor(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request LoginSecondFactorRequest
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		claims, msg := helper.ValidateToken(*request.Challenge_token)
+
+		if msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+
+		if claims.Token_type != helper.CHALLENGE_TOKEN || claims.Uid == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the token is not a login challenge"})
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, "", claims.Uid)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the user of this challenge was not found"})
+			return
+		}
+
+		if foundUser.Deactivated {
+			c.JSON(http.StatusForbidden, gin.H{"error": "the account has been deactivated"})
+			return
+		}
+
+		if !foundUser.Two_factor_enabled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "two factor authentication is not enabled"})
+			return
+		}
+
+		// guessing codes counts as failed logins just like guessing passwords
+
+		clientIp := c.ClientIP()
+
+		if !loginAllowed(c, ctx, *foundUser.Email, clientIp) {
+			return
+		}
+
+		valid, err := verifySecondFactor(ctx, store, *foundUser, *request.Code)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the code"})
+			return
+		}
+
+		if !valid {
+			loginFailed(c, ctx, *foundUser.Email, clientIp, foundUser.User_id, "the code is not valid")
+			return
+		}
+
+		issueTokens(c, ctx, *foundUser)
+
+	}
+}
+
+// verifySecondFactor accepts a TOTP code or one of the recovery codes.
+// both are consumed by the repository in one step , a TOTP code can't be replayed within its window and a recovery code works only once
+func verifySecondFactor(ctx context.Context, store *repository.Store, foundUser models.User, code string) (bool, error) {
+
+	if foundUser.Totp_secret != nil {
+		if step, ok := helper.ValidateTOTP(*foundUser.Totp_secret, code, time.Now()); ok {
+			return store.Users.UseTotpStep(ctx, foundUser.User_id, step)
+		}
+	}
+
+	return store.Users.UseRecoveryCode(ctx, foundUser.User_id, helper.HashSecret(code))
+}
+
+// totpIssuer is the name authenticator apps show next to the code
+func totpIssuer() string {
+
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+
+	return "Restaurent"
+}
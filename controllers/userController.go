@@ -3,27 +3,34 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/audit"
 	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/middleware"
 	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
-
-func GetUsers() gin.HandlerFunc {
+func GetUsers(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		// managers only see the staff of their own branch , the head office sees everybody
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
 
 		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage")) // this is related to the skip and limit concept , which is mainly used in pagination .
 		// when we are getting bulk amount of data from database , we use pagination concept to send data to the frontend , in the pagination concept skip tells from where the data should be sent and limit restrcits the amount of data to be sent to the frontend.
@@ -40,81 +47,72 @@ func GetUsers() gin.HandlerFunc {
 		}
 
 		startIndex := (page - 1) * recordPerPage
-		startIndex, _ = strconv.Atoi(c.Query("startindex"))
-
-		matchStage := bson.D{{Key: "$match ", Value: bson.D{{}}}}
-		projectStage := bson.D{
-			{Key: "$project", Value: bson.D{
-				{Key: "id", Value: 0},
-				{Key: "total_count", Value: 1},
-				{Key: "user_items", Value: bson.D{{Key: "$slice", Value: []interface{}{"$data", startIndex, recordPerPage}}}}, //{"user_items", bson.D{{"$slice", []interface{}{"$data", startIndex, recordPerPage}}}}: This projection includes the user_items field in the output documents, and it uses the $slice operator to limit the array contained in the user_items field to a specific range. The $slice operator takes three arguments: the source array (in this case, $data), the starting index (specified by startIndex), and the number of elements to include (specified by recordPerPage). This is used for paginating the user_items array.
-			}}}
-
-		//This specific line of code is defining a projection for a MongoDB query or aggregation operation, and it's using the $slice operator to limit the elements in an array field called user_items
-		//Key: "$slice": This part specifies that you want to use the $slice operator to modify the user_items field. The $slice operator is used to retrieve a portion (or slice) of an array.
-		//Value: []interface{}{"$data", startIndex, recordPerPage}: This part specifies the arguments for the $slice operator. It's an array of three elements:
-
-		//"data": This is the source array from which you want to retrieve a slice. It appears to be a reference to another field named data. In MongoDB, $data suggests that it's a field reference and not a literal array.
-		//startIndex: This variable represents the starting index of the slice within the data array. It indicates where the slice should begin.
-		//startIndex: This variable represents the starting index of the slice within the data array. It indicates where the slice should begin.
-
-		result, err := userCollection.Aggregate(ctx, mongo.Pipeline{
-			matchStage,
-			projectStage,
-		})
 
-		defer cancel()
+		if index, err := strconv.Atoi(c.Query("startindex")); err == nil && index >= 0 {
+			startIndex = index
+		}
+
+		users, total, err := store.Users.List(ctx, branchId, int64(startIndex), int64(recordPerPage))
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing  all the user documents in the usercolleciton"})
 			return
 		}
 
-		var allUsers []bson.M
+		// secrets never leave the server , not even for admins
 
-		if err := result.All(ctx, allUsers); err != nil {
-			log.Fatal(err)
+		for i := range users {
+			hideUserSecrets(&users[i])
 		}
 
-		defer cancel()
+		// the response keeps the shape of the old aggregation , an empty list when there are no users at all
+
+		allUsers := []gin.H{}
+
+		if total > 0 {
+			allUsers = append(allUsers, gin.H{"total_count": total, "user_items": users})
+		}
 
 		c.JSON(http.StatusOK, allUsers)
 
 	}
 }
 
-func GetUser() gin.HandlerFunc {
+func GetUser(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		var user models.User
+		defer cancel()
 
 		userId := c.Param("user_id")
 
-		err := userCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		user, err := store.Users.Get(ctx, branchId, userId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			defer cancel()
 			return
 		}
 
-		defer cancel()
+		hideUserSecrets(user)
+
+		setETag(c, user.Version)
 
 		c.JSON(http.StatusOK, user)
 
 	}
 }
 
-func Login() gin.HandlerFunc {
+func Login(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 
 		var user models.User
 
-		var foundUser models.User
-
 		// convert the incoming request's jsong into golang readable format
 		if err := c.BindJSON(&user); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -122,45 +120,252 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
+		defer cancel()
+
+		if user.Email == nil || user.Password == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
+			return
+		}
+
+		// callers with too many failed attempts are turned away before the password is hashed
+
+		clientIp := c.ClientIP()
+
+		if !loginAllowed(c, ctx, *user.Email, clientIp) {
+			return
+		}
+
 		// find a user with the emailid and check whether the user already exits
 
-		err := userCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&foundUser)
+		foundUser, err := store.Users.GetByEmail(ctx, *user.Email)
 
-		defer cancel()
+		if err == repository.ErrNotFound {
+			// an unknown email takes as long as a wrong password , otherwise the time of the answer tells which emails have an account
+			VerifyPassword(DUMMY_PASSWORD_HASH, *user.Password)
+			loginFailed(c, ctx, *user.Email, clientIp, "", "login or password is incorrect")
+			return
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the document"})
 			return
 		}
 
-		// verifying the password of the user
+		// verifying the password of the user , a user without one , like one an import created , has to set it through a reset link first
 
-		passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
+		passwordIsValid, msg := checkPassword(foundUser, *user.Password)
 
 		if passwordIsValid != true {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			loginFailed(c, ctx, *user.Email, clientIp, foundUser.User_id, msg)
+			return
+		}
+
+		// the status is only revealed to someone who knows the password
+
+		if foundUser.Deactivated {
+			c.JSON(http.StatusForbidden, gin.H{"error": "the account has been deactivated or is waiting for an admin's approval"})
 			return
 		}
 
-		// generate all tokens
+		// with two factor authentication the password alone is not enough , the client gets a short lived challenge token
+		// and has to send it to /users/login/2fa together with a code from the authenticator app
+
+		if foundUser.Two_factor_enabled {
+			challengeToken, err := helper.GenerateChallengeToken(foundUser.User_id)
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the challenge token"})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": challengeToken})
+			return
+		}
+
+		issueTokens(c, ctx, *foundUser)
+
+	}
+}
+
+// loginAllowed answers 429 with a Retry-After header while the email or the ip of the caller is held back
+func loginAllowed(c *gin.Context, ctx context.Context, email string, clientIp string) bool {
+
+	wait, err := helper.LoginRetryAfter(ctx, email, clientIp)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the failed attempts"})
+		return false
+	}
+
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts , try again later"})
+		return false
+	}
+
+	return true
+}
+
+// loginFailed counts a failed attempt for the email and the ip and answers 401 with msg.
+// a failure that can't be counted answers 500 , otherwise a broken counter would hand out unlimited guesses
+func loginFailed(c *gin.Context, ctx context.Context, email string, clientIp string, userId string, msg string) {
+
+	if err := helper.RecordLoginFailure(ctx, email, clientIp, userId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while recording the failed attempt"})
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+}
+
+// issueTokens is the last step of every login , it signs a new token pair , stores it and answers with the user.
+// the failed attempts of the email are only forgotten here , a correct password with a missing second factor doesn't clear them
+func issueTokens(c *gin.Context, ctx context.Context, foundUser models.User) {
 
-		token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
+	// users created before roles existed have no role , they can still log in but every role protected route will answer 403
 
-		// update all the tokens
+	role := ""
 
-		helper.UpdateAllTokens(token, refreshToken, foundUser.User_id)
+	if foundUser.Role != nil {
+		role = *foundUser.Role
+	}
 
-		// return status ok
+	// generate all tokens
 
-		c.JSON(http.StatusOK, foundUser)
+	token, refreshToken, err := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, role, userBranch(foundUser))
 
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the tokens"})
+		return
 	}
+
+	// update all the tokens
+
+	helper.UpdateAllTokens(token, refreshToken, foundUser.User_id)
+
+	if err := helper.ResetLoginFailures(ctx, *foundUser.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while resetting the failed attempts"})
+		return
+	}
+
+	// the new token pair is the only secret in the answer , foundUser is a copy so the password hash is only left out of the response
+
+	foundUser.Password = nil
+	foundUser.Token = &token
+	foundUser.RefreshToken = &refreshToken
+
+	// return status ok
+
+	c.JSON(http.StatusOK, foundUser)
+}
+
+// hideUserSecrets blanks the password and the tokens before a user is sent out , the two factor secrets are never marshalled to json
+func hideUserSecrets(user *models.User) {
+
+	user.Password = nil
+	user.Token = nil
+	user.RefreshToken = nil
 }
 
-func SignUp() gin.HandlerFunc {
+// userBranch is the branch put into the tokens of the user , it is empty for the head office
+func userBranch(user models.User) string {
+
+	if user.Branch_id == nil {
+		return ""
+	}
+
+	return *user.Branch_id
+}
+
+type RefreshRequest struct {
+	Refresh_token *string `json:"refresh_token" validate:"required"`
+}
+
+// RefreshToken exchanges a refresh token for a new token pair , the presented refresh token is rotated out and can't be used a second time
+func RefreshToken(store *repository.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request RefreshRequest
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		claims, msg := helper.ValidateToken(*request.Refresh_token)
+
+		if msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+
+		if claims.Token_type != helper.REFRESH_TOKEN || claims.Uid == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the token is not a refresh token"})
+			return
+		}
+
+		if msg := helper.CheckRevocation(claims); msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, "", claims.Uid)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the user of this refresh token was not found"})
+			return
+		}
+
+		if foundUser.Deactivated {
+			c.JSON(http.StatusForbidden, gin.H{"error": "the account has been deactivated"})
+			return
+		}
+
+		role := ""
+
+		if foundUser.Role != nil {
+			role = *foundUser.Role
+		}
+
+		token, refreshToken, err := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, role, userBranch(*foundUser))
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the tokens"})
+			return
+		}
+
+		// only the latest refresh token is stored , a token that was already rotated no longer matches and is rejected
+
+		rotated, err := helper.RotateTokens(token, refreshToken, foundUser.User_id, *request.Refresh_token)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the new tokens"})
+			return
+		}
+
+		if !rotated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "the refresh token has already been used"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+
+	}
+}
+
+func SignUp(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
 		var user models.User
 
@@ -168,74 +373,267 @@ func SignUp() gin.HandlerFunc {
 
 		if err := c.BindJSON(&user); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err})
-			defer cancel()
 			return
 		}
 
+		// staff members are waiters unless the admin creating them asks for another role , signup is public so only a logged in admin may pick the role
+
+		if user.Role == nil {
+			role := models.ROLE_WAITER
+			user.Role = &role
+		}
+
+		if *user.Role != models.ROLE_WAITER && c.GetString("role") != models.ROLE_ADMIN {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only an admin can create a user with the role " + *user.Role})
+			return
+		}
+
+		// admins of a branch can only add staff to their own branch , the head office and new sign ups pick the branch.
+		// only admins may be left without a branch , they belong to the head office
+
+		if c.GetString("auth_type") != "" && !middleware.IsHeadOffice(c) {
+			branchId := c.GetString("branch_id")
+			user.Branch_id = &branchId
+		}
+
+		if user.Branch_id != nil && *user.Branch_id == "" {
+			user.Branch_id = nil
+		}
+
+		if user.Branch_id == nil && *user.Role != models.ROLE_ADMIN {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a branch_id is required for the role " + *user.Role})
+			return
+		}
+
+		if user.Branch_id != nil && !branchExists(c, ctx, store, *user.Branch_id) {
+			return
+		}
+
+		// the account status and two factor authentication can't be chosen by the caller.
+		// anybody can sign up without a token , so only accounts created by an admin are active right away.
+		// the others start deactivated and an admin approves them with /users/:user_id/reactivate
+
+		needsApproval := c.GetString("role") != models.ROLE_ADMIN
+
+		user.Deactivated = false
+		user.Deactivated_at = nil
+		user.Two_factor_enabled = false
+
+		if needsApproval {
+			now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			user.Deactivated = true
+			user.Deactivated_at = &now
+		}
+
 		// validate the data using the struct method
 
 		validationErr := validate.Struct(user)
 
 		if validationErr != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
-			defer cancel()
 			return
 		}
 
-		// checking whether the email is already existing in the database
+		// an email or a phone number can only belong to one user
 
-		emailCount, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
-		defer cancel()
+		emailTaken, err := store.Users.EmailTaken(ctx, *user.Email, "")
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
 			return
 		}
 
-		// hash password
-		password := HashPassword(*user.Password)
-		user.Password = &password
-
-		phoneCount, err := userCollection.CountDocuments(ctx, bson.M{"phone": user.Phone})
+		phoneTaken, err := store.Users.PhoneTaken(ctx, *user.Phone, "")
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error ": "error occurre while checking for the phone "})
 			return
 		}
 
-		if emailCount > 0 && phoneCount > 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "This email or phone already exists in the database"})
+		if emailTaken || phoneTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": "This email or phone already exists in the database"})
 			return
 		}
 
+		// hash password
+		password := HashPassword(*user.Password)
+		user.Password = &password
+
 		user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 		user.ID = primitive.NewObjectID()
+		user.Version = 1
 		user.User_id = user.ID.Hex()
 
-		// generate token and refresh token
+		// generate token and refresh token , an account waiting for approval gets them when it logs in after the approval
+
+		if !needsApproval {
+			token, refreshToken, err := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, *user.Role, userBranch(user))
 
-		token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the tokens"})
+				return
+			}
 
-		user.Token = &token
-		user.RefreshToken = &refreshToken
+			user.Token = &token
+			user.RefreshToken = &refreshToken
+		}
 
 		// inserting the new user into the database
 
-		result, insertionErr := userCollection.InsertOne(ctx, user)
+		// two sign ups with the same email can both pass the check above , the unique index lets only one of them in
+
+		insertionErr := store.Users.Create(ctx, &user)
+
+		if insertionErr == repository.ErrDuplicate {
+			c.JSON(http.StatusConflict, gin.H{"error": "This email or phone already exists in the database"})
+			return
+		}
 
 		if insertionErr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while inserting user data into the database "})
 			return
 		}
 
+		// a user signing up on their own is the actor of the event , otherwise it is the admin who created them
+
+		actorId, actorType := user.User_id, "user"
+
+		if uid := c.GetString("uid"); uid != "" {
+			actorId, actorType = uid, c.GetString("auth_type")
+		}
+
+		audit.Record(ctx, models.AuditEvent{
+			Actor_id:    actorId,
+			Actor_type:  actorType,
+			Action:      "user." + audit.ACTION_CREATE,
+			Entity_type: "user",
+			Entity_id:   user.User_id,
+			After:       userSnapshot(user),
+			Branch_id:   userBranch(user),
+		})
+
+		hideUserSecrets(&user)
+
+		c.JSON(http.StatusOK, user)
+
+	}
+}
+
+// Logout revokes the token used for this request and the stored refresh token of the user
+func Logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
-		c.JSON(http.StatusOK, result)
+
+		tokenId := c.GetString("token_id")
+		expiresAt := time.Unix(c.GetInt64("token_expires_at"), 0)
+
+		if tokenId != "" {
+			if err := helper.Revocations.RevokeToken(ctx, tokenId, expiresAt); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the token"})
+				return
+			}
+		}
+
+		if err := helper.ClearTokens(c.GetString("uid")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while removing the stored tokens"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+
+	}
+}
+
+// RevokeUserSessions invalidates every token that was issued to the user so far , the user has to log in again on every device
+func RevokeUserSessions(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.Param("user_id")
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		_, err := store.Users.Get(ctx, branchId, userId)
+
+		if err == repository.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the user"})
+			return
+		}
+
+		if err := helper.Revocations.RevokeAllTokens(ctx, userId); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the sessions"})
+			return
+		}
+
+		if err := helper.ClearTokens(userId); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while removing the stored tokens"})
+			return
+		}
+
+		recordChange(c, ctx, "user", "revoke_sessions", userId, nil, nil)
+
+		c.JSON(http.StatusOK, gin.H{"message": "all sessions of the user have been revoked"})
+
+	}
+}
+
+// UnlockUser clears the failed login attempts of the user's email , the lockout ends immediately
+func UnlockUser(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.Param("user_id")
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, branchId, userId)
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		if err := helper.ResetLoginFailures(ctx, *foundUser.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while unlocking the user"})
+			return
+		}
+
+		audit.Record(ctx, models.AuditEvent{
+			Actor_id:    c.GetString("uid"),
+			Action:      "login.unlocked",
+			Entity_type: "user",
+			Entity_id:   foundUser.User_id,
+			Branch_id:   userBranch(*foundUser),
+		})
+
+		c.JSON(http.StatusOK, gin.H{"message": "the user has been unlocked"})
 
 	}
 }
 
+// DUMMY_PASSWORD_HASH is compared against when there is no password to compare with , it has the cost of HashPassword so both take as long.
+// nobody knows the password behind it
+const DUMMY_PASSWORD_HASH = "$2a$14$TfCbYoD0k5THAZgpbhRHBukJBGItLCZL4qcigcP4RHxi4k/oPyYJ."
+
 func HashPassword(password string) string {
 
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14) //The 14 signifies the cost factor for the bcrypt password hashing algorithm. Bcrypt is designed to be slow and computationally expensive, which makes it resistant to brute-force and dictionary attacks. The cost factor determines how many iterations of the underlying Blowfish encryption algorithm are applied to hash the password. Higher cost factors result in more iterations and, therefore, slower hash generation.
@@ -249,6 +647,17 @@ func HashPassword(password string) string {
 
 }
 
+// checkPassword is VerifyPassword for a stored user , a user without a password is compared against DUMMY_PASSWORD_HASH so it takes as long and then refused
+func checkPassword(foundUser *models.User, providedPassword string) (bool, string) {
+
+	if foundUser.Password == nil {
+		VerifyPassword(DUMMY_PASSWORD_HASH, providedPassword)
+		return false, "login or password is incorrect"
+	}
+
+	return VerifyPassword(*foundUser.Password, providedPassword)
+}
+
 func VerifyPassword(userPassword string, providedPassword string) (bool, string) {
 
 	err := bcrypt.CompareHashAndPassword([]byte(userPassword), []byte(providedPassword))
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// an unknown email is only as slow as a wrong password while the dummy hash has the cost of the real ones
+func TestDummyPasswordHashHasTheCostOfHashPassword(t *testing.T) {
+
+	dummyCost, err := bcrypt.Cost([]byte(DUMMY_PASSWORD_HASH))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cost, err := bcrypt.Cost([]byte(HashPassword("any password")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dummyCost != cost {
+		t.Errorf("the dummy hash has the cost %d , HashPassword uses %d", dummyCost, cost)
+	}
+}
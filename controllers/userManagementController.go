@@ -0,0 +1,413 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateUserRequest holds the fields an admin can change , fields that are not sent are left as they are
+type UpdateUserRequest struct {
+	First_name *string `json:"first_name" validate:"omitempty,min=2,max=100"`
+	Last_name  *string `json:"last_name" validate:"omitempty,min=2,max=100"`
+	Email      *string `json:"email" validate:"omitempty,email"`
+	Avatar     *string `json:"avatar"`
+	Phone      *string `json:"phone"`
+	Role       *string `json:"role" validate:"omitempty,eq=ADMIN|eq=MANAGER|eq=WAITER|eq=CHEF|eq=CASHIER"`
+	Branch_id  *string `json:"branch_id"`
+	// the version of the user the change is based on , If-Match can be sent instead
+	Version int `json:"version"`
+}
+
+type ChangePasswordRequest struct {
+	Old_password *string `json:"old_password" validate:"required"`
+	New_password *string `json:"new_password" validate:"required,min=6"`
+}
+
+// UpdateUser lets admins edit the profile and the role of a user , a role or branch change logs the user out everywhere because both are part of the token.
+// only the head office can move a user to another branch
+func UpdateUser(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request UpdateUserRequest
+
+		userId := c.Param("user_id")
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		branchId, ok := callerBranch(c, false)
+		if !ok {
+			return
+		}
+
+		foundUser, err := store.Users.Get(ctx, branchId, userId)
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		current := *foundUser
+		hideUserSecrets(&current)
+
+		if !versionMatches(c, request.Version, current.Version, current) {
+			return
+		}
+
+		before := userSnapshot(*foundUser)
+
+		roleChanged := request.Role != nil && (foundUser.Role == nil || *foundUser.Role != *request.Role)
+		branchChanged := request.Branch_id != nil && *request.Branch_id != userBranch(*foundUser)
+
+		if request.First_name != nil {
+			foundUser.First_name = request.First_name
+		}
+
+		if request.Last_name != nil {
+			foundUser.Last_name = request.Last_name
+		}
+
+		if request.Avatar != nil {
+			foundUser.Avatar = request.Avatar
+		}
+
+		if request.Email != nil {
+			taken, err := store.Users.EmailTaken(ctx, *request.Email, userId)
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
+				return
+			}
+
+			if taken {
+				c.JSON(http.StatusConflict, gin.H{"error": "this email already belongs to another user"})
+				return
+			}
+
+			foundUser.Email = request.Email
+		}
+
+		if request.Phone != nil {
+			taken, err := store.Users.PhoneTaken(ctx, *request.Phone, userId)
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the phone"})
+				return
+			}
+
+			if taken {
+				c.JSON(http.StatusConflict, gin.H{"error": "this phone already belongs to another user"})
+				return
+			}
+
+			foundUser.Phone = request.Phone
+		}
+
+		if roleChanged {
+			foundUser.Role = request.Role
+		}
+
+		if branchChanged {
+			if !middleware.IsHeadOffice(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only the head office can move a user to another branch"})
+				return
+			}
+
+			if *request.Branch_id == "" {
+				foundUser.Branch_id = nil
+			} else {
+				if !branchExists(c, ctx, store, *request.Branch_id) {
+					return
+				}
+
+				foundUser.Branch_id = request.Branch_id
+			}
+		}
+
+		// only admins may be left without a branch
+
+		role := ""
+
+		if foundUser.Role != nil {
+			role = *foundUser.Role
+		}
+
+		if userBranch(*foundUser) == "" && role != models.ROLE_ADMIN {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a branch_id is required for the role " + role})
+			return
+		}
+
+		foundUser.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Users.Update(ctx, foundUser)
+
+		if err == repository.ErrVersionConflict {
+			respondUserConflict(c, ctx, store, userId)
+			return
+		}
+
+		if err == repository.ErrDuplicate {
+			c.JSON(http.StatusConflict, gin.H{"error": "this email or phone already belongs to another user"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the user"})
+			return
+		}
+
+		recordChange(c, ctx, "user", audit.ACTION_UPDATE, userId, before, userSnapshot(*foundUser))
+
+		if (roleChanged || branchChanged) && !revokeAllSessions(c, ctx, userId) {
+			return
+		}
+
+		respondWithUser(c, ctx, store, userId)
+
+	}
+}
+
+// ChangePassword is self service , the old password has to be right and every session of the user ends afterwards
+func ChangePassword(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request ChangePasswordRequest
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if validationErr := validate.Struct(request); validationErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+			return
+		}
+
+		userId := c.GetString("uid")
+
+		foundUser, err := store.Users.Get(ctx, "", userId)
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+
+		// a stolen token must not be enough to guess the password , so wrong guesses count as failed logins
+
+		clientIp := c.ClientIP()
+
+		if !loginAllowed(c, ctx, *foundUser.Email, clientIp) {
+			return
+		}
+
+		if passwordIsValid, msg := checkPassword(foundUser, *request.Old_password); !passwordIsValid {
+			loginFailed(c, ctx, *foundUser.Email, clientIp, foundUser.User_id, msg)
+			return
+		}
+
+		password := HashPassword(*request.New_password)
+
+		foundUser.Password = &password
+		foundUser.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		err = store.Users.Update(ctx, foundUser)
+
+		if err == repository.ErrVersionConflict {
+			respondUserConflict(c, ctx, store, userId)
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the password"})
+			return
+		}
+
+		recordChange(c, ctx, "user", "change_password", userId, nil, nil)
+
+		if !revokeAllSessions(c, ctx, userId) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "the password has been changed , please log in again"})
+
+	}
+}
+
+// DeactivateUser locks a member of staff out immediately without deleting any of their history
+func DeactivateUser(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.Param("user_id")
+
+		if userId == c.GetString("uid") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "you can't deactivate your own account"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if !setDeactivated(c, ctx, store, userId, func(user *models.User) {
+			user.Deactivated = true
+			user.Deactivated_at = &now
+			user.Updated_at = now
+		}, "deactivate") {
+			return
+		}
+
+		if !revokeAllSessions(c, ctx, userId) {
+			return
+		}
+
+		respondWithUser(c, ctx, store, userId)
+
+	}
+}
+
+func ReactivateUser(store *repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.Param("user_id")
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if !setDeactivated(c, ctx, store, userId, func(user *models.User) {
+			user.Deactivated = false
+			user.Deactivated_at = nil
+			user.Updated_at = now
+		}, "reactivate") {
+			return
+		}
+
+		respondWithUser(c, ctx, store, userId)
+
+	}
+}
+
+// setDeactivated only reaches the users of the caller's branch , the head office reaches everybody.
+// the change is written to the audit log under the given action
+func setDeactivated(c *gin.Context, ctx context.Context, store *repository.Store, userId string, change func(user *models.User), action string) bool {
+
+	branchId, ok := callerBranch(c, false)
+	if !ok {
+		return false
+	}
+
+	foundUser, err := store.Users.Get(ctx, branchId, userId)
+
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the user"})
+		return false
+	}
+
+	current := *foundUser
+	hideUserSecrets(&current)
+
+	if !versionMatches(c, 0, current.Version, current) {
+		return false
+	}
+
+	before := userSnapshot(*foundUser)
+
+	change(foundUser)
+
+	err = store.Users.Update(ctx, foundUser)
+
+	if err == repository.ErrVersionConflict {
+		respondUserConflict(c, ctx, store, userId)
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the user"})
+		return false
+	}
+
+	recordChange(c, ctx, "user", action, userId, before, userSnapshot(*foundUser))
+
+	return true
+}
+
+// revokeAllSessions ends every session of the user , the tokens in use and the stored refresh token
+func revokeAllSessions(c *gin.Context, ctx context.Context, userId string) bool {
+
+	if err := helper.Revocations.RevokeAllTokens(ctx, userId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the sessions"})
+		return false
+	}
+
+	if err := helper.ClearTokens(userId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while removing the stored tokens"})
+		return false
+	}
+
+	return true
+}
+
+// respondWithUser answers with the current state of the user , without the password and the tokens
+func respondWithUser(c *gin.Context, ctx context.Context, store *repository.Store, userId string) {
+
+	foundUser, err := store.Users.Get(ctx, "", userId)
+
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the user"})
+		return
+	}
+
+	hideUserSecrets(foundUser)
+
+	setETag(c, foundUser.Version)
+
+	c.JSON(http.StatusOK, foundUser)
+}
+
+// respondUserConflict answers a change that lost the race against another change of the same user , with the user as it is now
+func respondUserConflict(c *gin.Context, ctx context.Context, store *repository.Store, userId string) {
+
+	foundUser, err := store.Users.Get(ctx, "", userId)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while finding the user"})
+		return
+	}
+
+	hideUserSecrets(foundUser)
+
+	respondStale(c, foundUser.Version, foundUser)
+}
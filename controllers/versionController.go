@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// every document carries a version that is raised with each update , the ETag of a response is that version in quotes.
+// new documents start at version 1 , a document without a version was written before there were versions and counts as 0.
+// a client sends the version it read back with If-Match (or as the version field of the body) and its change is only
+// applied to that version , a change based on an older one is answered with 409 and the current document
+
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// versionMatches checks the version the client read against the stored one , If-Match wins over the body.
+// a client that sends neither overwrites whatever is stored
+func versionMatches(c *gin.Context, bodyVersion int, currentVersion int, current interface{}) bool {
+
+	ifMatch := c.GetHeader("If-Match")
+
+	if ifMatch == "" || ifMatch == "*" {
+		if bodyVersion == 0 || bodyVersion == currentVersion {
+			return true
+		}
+
+		respondStale(c, currentVersion, current)
+		return false
+	}
+
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match has to be the ETag of the document"})
+			return false
+		}
+
+		if version == currentVersion {
+			return true
+		}
+	}
+
+	respondStale(c, currentVersion, current)
+	return false
+}
+
+// respondStale answers a change that was based on an old version , the client merges its change into current and tries again
+func respondStale(c *gin.Context, currentVersion int, current interface{}) {
+
+	setETag(c, currentVersion)
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "the document was changed in the meantime , apply your change to the current version and send it again",
+		"current": current,
+	})
+}
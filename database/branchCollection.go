@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BranchCollection only sees the documents of one branch , every filter and pipeline it is given is narrowed to the branch before it reaches mongodb.
+// an empty branch id leaves them as they are , which is how the head office reads across all branches
+type BranchCollection struct {
+	collection *mongo.Collection
+	branchId   string
+}
+
+func InBranch(collection *mongo.Collection, branchId string) BranchCollection {
+	return BranchCollection{collection: collection, branchId: branchId}
+}
+
+// scope copies the filter so that the caller's map is never changed
+func (b BranchCollection) scope(filter bson.M) bson.M {
+
+	scoped := bson.M{}
+
+	for key, value := range filter {
+		scoped[key] = value
+	}
+
+	if b.branchId != "" {
+		scoped["branch_id"] = b.branchId
+	}
+
+	return scoped
+}
+
+func (b BranchCollection) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return b.collection.Find(ctx, b.scope(filter), opts...)
+}
+
+func (b BranchCollection) FindOne(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	return b.collection.FindOne(ctx, b.scope(filter), opts...)
+}
+
+func (b BranchCollection) CountDocuments(ctx context.Context, filter bson.M, opts ...*options.CountOptions) (int64, error) {
+	return b.collection.CountDocuments(ctx, b.scope(filter), opts...)
+}
+
+func (b BranchCollection) UpdateOne(ctx context.Context, filter bson.M, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return b.collection.UpdateOne(ctx, b.scope(filter), update, opts...)
+}
+
+// Aggregate puts a $match on the branch in front of the pipeline , so the later stages never see documents of other branches
+func (b BranchCollection) Aggregate(ctx context.Context, pipeline mongo.Pipeline, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+
+	if b.branchId != "" {
+		pipeline = append(mongo.Pipeline{bson.D{{Key: "$match", Value: bson.D{{Key: "branch_id", Value: b.branchId}}}}}, pipeline...)
+	}
+
+	return b.collection.Aggregate(ctx, pipeline, opts...)
+}
@@ -3,42 +3,47 @@ package database
 import (
 	"context"
 	"fmt"
+	"go-restaurent-management-system/config"
 	"log"
-	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func DBinstance() *mongo.Client {
+// Connect builds the client from the configuration and pings the server , a wrong uri or a server that can't be reached stops the startup
+// instead of failing the first request. main() passes the database on to the packages and disconnects the client on shutdown
+func Connect(cfg config.Mongo) (*mongo.Client, error) {
 
-	mongodb := "mongodb://localhost:27017"
-	fmt.Println(mongodb)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	clientOptions := options.Client().
+		ApplyURI(cfg.Uri).
+		SetMinPoolSize(cfg.Min_pool_size).
+		SetMaxPoolSize(cfg.Max_pool_size).
+		SetConnectTimeout(cfg.Connect_timeout.Duration).
+		SetServerSelectionTimeout(cfg.Server_selection_timeout.Duration)
 
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Connect_timeout.Duration)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongodb))
+	client, err := mongo.Connect(ctx, clientOptions)
+
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("connecting to mongodb: %w", err)
 	}
 
-	if err != nil {
-		log.Fatal(err)
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("mongodb is not reachable: %w", err)
 	}
 
-	fmt.Println("Connected to MongoDB")
+	log.Println("Connected to MongoDB")
 
-	return client
+	return client, nil
 
 }
 
-var Client *mongo.Client = DBinstance()
-
-func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
+func OpenCollection(db *mongo.Database, collectionName string) *mongo.Collection {
 
-	var collection *mongo.Collection = client.Database("restaurent").Collection(collectionName)
+	var collection *mongo.Collection = db.Collection(collectionName)
 
 	return collection
 
@@ -3,12 +3,14 @@ module go-restaurent-management-system
 go 1.18
 
 require (
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gin-gonic/gin v1.9.1
 	github.com/go-playground/validator/v10 v10.14.0
+	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.16
 	go.mongodb.org/mongo-driver v1.12.1
 	golang.org/x/crypto v0.9.0
-	)
+)
 
 require (
 	github.com/bytedance/sonic v1.9.1 // indirect
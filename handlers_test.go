@@ -0,0 +1,1038 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/config"
+	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/integrity"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"go-restaurent-management-system/repository/memrepo"
+	"go-restaurent-management-system/repository/sqlrepo"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// the handler tests run the whole router , middleware included , against a fresh store for every test
+
+const TEST_PASSWORD = "test-password"
+
+// the staff every test starts with , the head office admin has no branch
+const (
+	HEAD_OFFICE = "office@test.io"
+	ADMIN_A     = "admin.a@test.io"
+	MANAGER_A   = "manager.a@test.io"
+	WAITER_A    = "waiter.a@test.io"
+	MANAGER_B   = "manager.b@test.io"
+)
+
+type testServer struct {
+	t        *testing.T
+	store    *repository.Store
+	router   *gin.Engine
+	branch_a string
+	branch_b string
+	user_ids map[string]string
+	// every request comes from this address , failed logins are counted per ip as well
+	remote_addr string
+}
+
+// HANDLER_TESTS run against every backend , see TestHandlersMemrepo and TestHandlersSqlite
+var HANDLER_TESTS = []struct {
+	name string
+	run  func(s *testServer)
+}{
+	{"authentication", testAuthentication},
+	{"signup needs approval", testSignUpNeedsApproval},
+	{"roles", testRoles},
+	{"branch scoping", testBranchScoping},
+	{"version conflicts", testVersionConflicts},
+	{"soft delete", testSoftDelete},
+	{"integrity", testIntegrity},
+	{"order creation", testOrderCreation},
+	{"sizes", testSizes},
+	{"modifiers", testModifiers},
+	{"login lockout", testLoginLockout},
+	{"password reset throttle", testPasswordResetThrottle},
+	{"api keys", testApiKeys},
+}
+
+func TestHandlersMemrepo(t *testing.T) {
+	runHandlerTests(t, func(t *testing.T) *repository.Store { return memrepo.NewStore() })
+}
+
+// the same tests against a sqlite file , every test gets a database of its own
+func TestHandlersSqlite(t *testing.T) {
+	runHandlerTests(t, func(t *testing.T) *repository.Store {
+
+		ctx := context.Background()
+
+		db, err := sqlrepo.Open(ctx, config.BACKEND_SQLITE, config.Sql{Dsn: filepath.Join(t.TempDir(), "restaurent.db")})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { db.Close() })
+
+		if err := sqlrepo.EnsureSchema(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+
+		return sqlrepo.NewStore(db)
+	})
+}
+
+func runHandlerTests(t *testing.T, newStore func(t *testing.T) *repository.Store) {
+
+	for _, test := range HANDLER_TESTS {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			test.run(newTestServer(t, newStore(t)))
+		})
+	}
+}
+
+func newTestServer(t *testing.T, store *repository.Store) *testServer {
+
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	t.Setenv("SECRET_KEY", "test-secret-key-0123456789abcdef")
+	t.Setenv("JWT_SIGNING_KEY_FILE", "")
+
+	helper.Init(store)
+	audit.Init(store.Audit)
+
+	if err := integrity.Init(store, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := helper.LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	router, err := newRouter(store)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, store: store, router: router, user_ids: map[string]string{}, remote_addr: "192.0.2.1:1234"}
+
+	s.branch_a = s.createBranch("Branch A")
+	s.branch_b = s.createBranch("Branch B")
+
+	s.createUser(HEAD_OFFICE, models.ROLE_ADMIN, "")
+	s.createUser(ADMIN_A, models.ROLE_ADMIN, s.branch_a)
+	s.createUser(MANAGER_A, models.ROLE_MANAGER, s.branch_a)
+	s.createUser(WAITER_A, models.ROLE_WAITER, s.branch_a)
+	s.createUser(MANAGER_B, models.ROLE_MANAGER, s.branch_b)
+
+	return s
+}
+
+func (s *testServer) createBranch(name string) string {
+
+	now := time.Now().UTC().Truncate(time.Second)
+	branch := models.Branch{ID: primitive.NewObjectID(), Name: &name, Created_at: now, Updated_at: now, Version: 1}
+	branch.Branch_id = branch.ID.Hex()
+
+	if err := s.store.Branches.Create(context.Background(), &branch); err != nil {
+		s.t.Fatal(err)
+	}
+
+	return branch.Branch_id
+}
+
+// createUser stores the user directly , the cheapest bcrypt cost keeps the logins of the tests fast
+func (s *testServer) createUser(email string, role string, branchId string) {
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(TEST_PASSWORD), bcrypt.MinCost)
+
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	password, name, phone := string(hash), "Test", fmt.Sprintf("+44 7700 %06d", len(s.user_ids))
+	user := models.User{ID: primitive.NewObjectID(), First_name: &name, Last_name: &name, Password: &password, Email: &email, Phone: &phone, Role: &role, Created_at: now, Updated_at: now, Version: 1}
+	user.User_id = user.ID.Hex()
+
+	if branchId != "" {
+		user.Branch_id = &branchId
+	}
+
+	if err := s.store.Users.Create(context.Background(), &user); err != nil {
+		s.t.Fatal(err)
+	}
+
+	s.user_ids[email] = user.User_id
+}
+
+// request sends body as JSON , headers are pairs of name and value
+func (s *testServer) request(method string, path string, token string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+
+	s.t.Helper()
+
+	var reader io.Reader = http.NoBody
+
+	if body != nil {
+		data, err := json.Marshal(body)
+
+		if err != nil {
+			s.t.Fatal(err)
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.RemoteAddr = s.remote_addr
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	return w
+}
+
+// expect fails the test when the response doesn't have the status
+func (s *testServer) expect(w *httptest.ResponseRecorder, status int, what string) {
+
+	s.t.Helper()
+
+	if w.Code != status {
+		s.t.Fatalf("%s: got %d , want %d: %s", what, w.Code, status, w.Body.String())
+	}
+}
+
+func decode[T any](s *testServer, w *httptest.ResponseRecorder) T {
+
+	s.t.Helper()
+
+	var value T
+
+	if err := json.Unmarshal(w.Body.Bytes(), &value); err != nil {
+		s.t.Fatalf("decoding %s: %v", w.Body.String(), err)
+	}
+
+	return value
+}
+
+// login returns the access and the refresh token of the user
+func (s *testServer) login(email string) (string, string) {
+
+	s.t.Helper()
+
+	w := s.request(http.MethodPost, "/users/login", "", gin.H{"email": email, "password": TEST_PASSWORD})
+	s.expect(w, http.StatusOK, "login of "+email)
+
+	user := decode[models.User](s, w)
+
+	return *user.Token, *user.RefreshToken
+}
+
+func (s *testServer) token(email string) string {
+
+	s.t.Helper()
+
+	token, _ := s.login(email)
+	return token
+}
+
+func (s *testServer) createMenu(token string) models.Menu {
+
+	s.t.Helper()
+
+	w := s.request(http.MethodPost, "/menus", token, gin.H{"name": "Mains", "category": "MAIN"})
+	s.expect(w, http.StatusOK, "create menu")
+
+	return decode[models.Menu](s, w)
+}
+
+func (s *testServer) createFood(token string, menuId string, name string, price float64) models.Food {
+
+	s.t.Helper()
+
+	w := s.request(http.MethodPost, "/foods", token, gin.H{"name": name, "price": price, "food_image": "https://example.com/" + name, "menu_id": menuId})
+	s.expect(w, http.StatusOK, "create food")
+
+	return decode[models.Food](s, w)
+}
+
+func (s *testServer) createTable(token string, number int) models.Table {
+
+	s.t.Helper()
+
+	w := s.request(http.MethodPost, "/tables", token, gin.H{"number_of_guests": 4, "table_number": number})
+	s.expect(w, http.StatusOK, "create table")
+
+	return decode[models.Table](s, w)
+}
+
+// createdOrder is the answer of POST /orderitems , the order with the items that were created for it
+type createdOrder struct {
+	Order_id    string             `json:"order_id"`
+	Branch_id   string             `json:"branch_id"`
+	Order_items []models.OrderItem `json:"order_items"`
+}
+
+func (s *testServer) createOrder(token string, tableId string, items ...gin.H) createdOrder {
+
+	s.t.Helper()
+
+	w := s.request(http.MethodPost, "/orderitems", token, gin.H{"table_id": tableId, "order_items": items})
+	s.expect(w, http.StatusOK, "create order")
+
+	return decode[createdOrder](s, w)
+}
+
+func testAuthentication(s *testServer) {
+
+	s.expect(s.request(http.MethodGet, "/foods", "", nil), http.StatusUnauthorized, "no token")
+	s.expect(s.request(http.MethodGet, "/foods", "not-a-token", nil), http.StatusUnauthorized, "broken token")
+
+	w := s.request(http.MethodPost, "/users/login", "", gin.H{"email": WAITER_A, "password": "wrong-password"})
+	s.expect(w, http.StatusUnauthorized, "wrong password")
+
+	w = s.request(http.MethodPost, "/users/login", "", gin.H{"email": "nobody@test.io", "password": TEST_PASSWORD})
+	s.expect(w, http.StatusUnauthorized, "unknown email")
+
+	// a user without a password , like one an import created , is refused like a wrong password
+
+	s.createUser("imported@test.io", models.ROLE_WAITER, s.branch_a)
+	importedToken := s.token("imported@test.io")
+
+	imported, err := s.store.Users.GetByEmail(context.Background(), "imported@test.io")
+
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	imported.Password = nil
+
+	if err := s.store.Users.Update(context.Background(), imported); err != nil {
+		s.t.Fatal(err)
+	}
+
+	s.expect(s.request(http.MethodPost, "/users/login", "", gin.H{"email": "imported@test.io", "password": TEST_PASSWORD}), http.StatusUnauthorized, "login without a stored password")
+	s.expect(s.request(http.MethodPost, "/users/password/change", importedToken, gin.H{"old_password": TEST_PASSWORD, "new_password": "new-password"}), http.StatusUnauthorized, "password change without a stored password")
+
+	token, refreshToken := s.login(WAITER_A)
+
+	// the password hash is never sent , not even to its owner
+
+	w = s.request(http.MethodPost, "/users/login", "", gin.H{"email": MANAGER_A, "password": TEST_PASSWORD})
+	s.expect(w, http.StatusOK, "login")
+
+	if loggedIn := decode[map[string]interface{}](s, w); loggedIn["password"] != nil || loggedIn["token"] == nil {
+		s.t.Fatalf("the login answer has the password hash or no token: %s", w.Body.String())
+	}
+
+	s.expect(s.request(http.MethodGet, "/foods", token, nil), http.StatusOK, "list foods with a token")
+
+	// a refresh token is no access token , and it can only be exchanged once
+
+	s.expect(s.request(http.MethodGet, "/foods", refreshToken, nil), http.StatusUnauthorized, "refresh token as access token")
+
+	w = s.request(http.MethodPost, "/users/refresh", "", gin.H{"refresh_token": refreshToken})
+	s.expect(w, http.StatusOK, "refresh")
+
+	refreshed := decode[map[string]interface{}](s, w)
+
+	if refreshed["token"] == nil || refreshed["token"] == token {
+		s.t.Fatalf("refresh didn't hand out a new access token: %v", refreshed)
+	}
+
+	w = s.request(http.MethodPost, "/users/refresh", "", gin.H{"refresh_token": refreshToken})
+	s.expect(w, http.StatusUnauthorized, "refresh token used twice")
+
+	// after the logout the token is revoked
+
+	s.expect(s.request(http.MethodPost, "/users/logout", token, nil), http.StatusOK, "logout")
+	s.expect(s.request(http.MethodGet, "/foods", token, nil), http.StatusUnauthorized, "revoked token")
+}
+
+func testSignUpNeedsApproval(s *testServer) {
+
+	signUp := gin.H{"first_name": "New", "last_name": "Waiter", "email": "new@test.io", "password": TEST_PASSWORD, "phone": "+44 7700 999999", "branch_id": s.branch_a}
+
+	// only an admin may hand out another role than waiter
+
+	withRole := gin.H{"role": models.ROLE_ADMIN}
+	for key, value := range signUp {
+		withRole[key] = value
+	}
+
+	s.expect(s.request(http.MethodPost, "/users/signup", "", withRole), http.StatusForbidden, "signup as admin")
+
+	w := s.request(http.MethodPost, "/users/signup", "", signUp)
+	s.expect(w, http.StatusOK, "signup")
+
+	user := decode[models.User](s, w)
+
+	if !user.Deactivated || user.Token != nil {
+		s.t.Fatalf("a signup without an admin token has to wait for approval: %s", w.Body.String())
+	}
+
+	w = s.request(http.MethodPost, "/users/login", "", gin.H{"email": "new@test.io", "password": TEST_PASSWORD})
+	s.expect(w, http.StatusForbidden, "login before the approval")
+
+	s.expect(s.request(http.MethodPost, "/users/"+user.User_id+"/reactivate", s.token(ADMIN_A), nil), http.StatusOK, "approval")
+
+	s.login("new@test.io")
+}
+
+func testRoles(s *testServer) {
+
+	waiter, manager := s.token(WAITER_A), s.token(MANAGER_A)
+
+	s.expect(s.request(http.MethodPost, "/menus", waiter, gin.H{"name": "Mains", "category": "MAIN"}), http.StatusForbidden, "waiter creates a menu")
+	s.expect(s.request(http.MethodGet, "/menus", waiter, nil), http.StatusOK, "waiter lists the menus")
+
+	s.createMenu(manager)
+
+	s.expect(s.request(http.MethodGet, "/users/", waiter, nil), http.StatusForbidden, "waiter lists the users")
+	s.expect(s.request(http.MethodGet, "/users/", manager, nil), http.StatusOK, "manager lists the users")
+	s.expect(s.request(http.MethodPost, "/users/"+s.user_ids[WAITER_A]+"/deactivate", manager, nil), http.StatusForbidden, "manager deactivates a user")
+	s.expect(s.request(http.MethodPost, "/branches", manager, gin.H{"name": "Branch C"}), http.StatusForbidden, "manager opens a branch")
+}
+
+func testBranchScoping(s *testServer) {
+
+	managerA, managerB, office := s.token(MANAGER_A), s.token(MANAGER_B), s.token(HEAD_OFFICE)
+
+	menu := s.createMenu(managerA)
+	food := s.createFood(managerA, menu.Menu_id, "Soup", 4.5)
+
+	if food.Branch_id != s.branch_a {
+		s.t.Fatalf("the food was stored in %q instead of the branch of its creator", food.Branch_id)
+	}
+
+	// another branch can't see , change or use the food
+
+	s.expect(s.request(http.MethodGet, "/foods/"+food.Food_id, managerB, nil), http.StatusNotFound, "other branch reads the food")
+	s.expect(s.request(http.MethodPatch, "/foods/"+food.Food_id, managerB, gin.H{"name": "Stolen"}), http.StatusNotFound, "other branch changes the food")
+	s.expect(s.request(http.MethodPost, "/foods", managerB, gin.H{"name": "Bread", "price": 2, "food_image": "x", "menu_id": menu.Menu_id}), http.StatusBadRequest, "other branch uses the menu")
+
+	foods := decode[[]map[string]interface{}](s, s.request(http.MethodGet, "/foods", managerB, nil))
+
+	if len(foods) != 0 {
+		s.t.Fatalf("branch B sees the foods of branch A: %v", foods)
+	}
+
+	// the head office reads everything but can't change a branch
+
+	s.expect(s.request(http.MethodGet, "/foods/"+food.Food_id, office, nil), http.StatusOK, "head office reads the food")
+	s.expect(s.request(http.MethodPatch, "/foods/"+food.Food_id, office, gin.H{"name": "Changed"}), http.StatusForbidden, "head office changes the food")
+	s.expect(s.request(http.MethodPost, "/menus", office, gin.H{"name": "Mains", "category": "MAIN"}), http.StatusForbidden, "head office creates a menu")
+
+	w := s.request(http.MethodGet, "/foods?branch_id="+s.branch_b, office, nil)
+	s.expect(w, http.StatusOK, "head office lists branch B")
+
+	if foods := decode[[]map[string]interface{}](s, w); len(foods) != 0 {
+		s.t.Fatalf("?branch_id= of branch B lists the foods of branch A: %v", foods)
+	}
+}
+
+func testVersionConflicts(s *testServer) {
+
+	manager := s.token(MANAGER_A)
+
+	menu := s.createMenu(manager)
+	food := s.createFood(manager, menu.Menu_id, "Soup", 4.5)
+
+	w := s.request(http.MethodPatch, "/foods/"+food.Food_id, manager, gin.H{"price": 5}, "If-Match", `"1"`)
+	s.expect(w, http.StatusOK, "update of version 1")
+
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		s.t.Fatalf("got the ETag %s after the update , want \"2\"", etag)
+	}
+
+	// a second client still holding version 1 is turned away with the current document
+
+	w = s.request(http.MethodPatch, "/foods/"+food.Food_id, manager, gin.H{"price": 6}, "If-Match", `"1"`)
+	s.expect(w, http.StatusConflict, "stale update")
+
+	conflict := decode[struct{ Current models.Food }](s, w)
+
+	if *conflict.Current.Price != 5 || conflict.Current.Version != 2 {
+		s.t.Fatalf("the 409 doesn't carry the current food: %s", w.Body.String())
+	}
+
+	// clients that can't send headers put the version they read into the body
+
+	s.expect(s.request(http.MethodPatch, "/foods/"+food.Food_id, manager, gin.H{"price": 6, "version": 1}), http.StatusConflict, "stale update through the body")
+
+	if current := decode[models.Food](s, s.request(http.MethodGet, "/foods/"+food.Food_id, manager, nil)); *current.Price != 5 {
+		s.t.Fatalf("a stale update through the body was stored")
+	}
+
+	s.expect(s.request(http.MethodDelete, "/foods/"+food.Food_id, manager, nil, "If-Match", `"1"`), http.StatusConflict, "stale delete")
+	s.expect(s.request(http.MethodPatch, "/foods/"+food.Food_id, manager, gin.H{"price": 6}, "If-Match", `"2"`), http.StatusOK, "update of version 2")
+	s.expect(s.request(http.MethodPatch, "/foods/"+food.Food_id, manager, gin.H{"price": 7, "version": 3}), http.StatusOK, "update of version 3 through the body")
+}
+
+func testSoftDelete(s *testServer) {
+
+	manager, waiter, admin := s.token(MANAGER_A), s.token(WAITER_A), s.token(ADMIN_A)
+
+	menu := s.createMenu(manager)
+	food := s.createFood(manager, menu.Menu_id, "Soup", 4.5)
+
+	// the menu still has a food , the restrict rule keeps it
+
+	s.expect(s.request(http.MethodDelete, "/menus/"+menu.Menu_id, manager, nil), http.StatusConflict, "delete of a menu with foods")
+
+	w := s.request(http.MethodDelete, "/foods/"+food.Food_id, manager, nil)
+	s.expect(w, http.StatusOK, "delete")
+
+	if deleted := decode[models.Food](s, w); deleted.Deleted_at == nil || deleted.Deleted_by == nil || *deleted.Deleted_by != s.user_ids[MANAGER_A] {
+		s.t.Fatalf("the deleted food isn't marked: %s", w.Body.String())
+	}
+
+	s.expect(s.request(http.MethodGet, "/foods/"+food.Food_id, manager, nil), http.StatusNotFound, "read of a deleted food")
+	s.expect(s.request(http.MethodDelete, "/foods/"+food.Food_id, manager, nil), http.StatusNotFound, "second delete")
+	s.expect(s.request(http.MethodGet, "/foods?include_deleted=true", waiter, nil), http.StatusForbidden, "waiter looks at deleted foods")
+	s.expect(s.request(http.MethodGet, "/foods/"+food.Food_id+"?include_deleted=true", admin, nil), http.StatusOK, "admin looks at the deleted food")
+
+	// nothing holds the menu anymore
+
+	s.expect(s.request(http.MethodDelete, "/menus/"+menu.Menu_id, manager, nil), http.StatusOK, "delete of the empty menu")
+
+	// the food can't come back into a deleted menu , the menu comes back first
+
+	s.expect(s.request(http.MethodPost, "/foods/"+food.Food_id+"/restore", manager, nil), http.StatusForbidden, "manager restores")
+	s.expect(s.request(http.MethodPost, "/foods/"+food.Food_id+"/restore", admin, nil), http.StatusBadRequest, "restore into a deleted menu")
+	s.expect(s.request(http.MethodPost, "/menus/"+menu.Menu_id+"/restore", admin, nil), http.StatusOK, "restore of the menu")
+	s.expect(s.request(http.MethodPost, "/foods/"+food.Food_id+"/restore", admin, nil), http.StatusOK, "restore of the food")
+	s.expect(s.request(http.MethodPost, "/foods/"+food.Food_id+"/restore", admin, nil), http.StatusBadRequest, "restore of a food that isn't deleted")
+	s.expect(s.request(http.MethodGet, "/foods/"+food.Food_id, manager, nil), http.StatusOK, "read of the restored food")
+}
+
+func testOrderCreation(s *testServer) {
+
+	manager, waiter := s.token(MANAGER_A), s.token(WAITER_A)
+
+	menu := s.createMenu(manager)
+	soup := s.createFood(manager, menu.Menu_id, "Soup", 4.5)
+	bread := s.createFood(manager, menu.Menu_id, "Bread", 2)
+	table := s.createTable(manager, 7)
+
+	// the unit price of the client is ignored , the items are priced from the foods
+
+	w := s.request(http.MethodPost, "/orderitems", waiter, gin.H{
+		"table_id": table.Table_id,
+		"order_items": []gin.H{
+			{"food_id": soup.Food_id, "quantity": models.SIZE_MEDIUM, "unit_price": 0.01},
+			{"food_id": bread.Food_id, "quantity": models.SIZE_MEDIUM},
+		},
+	})
+	s.expect(w, http.StatusOK, "create order")
+
+	created := decode[struct {
+		Order_id    string             `json:"order_id"`
+		Branch_id   string             `json:"branch_id"`
+		Order_items []models.OrderItem `json:"order_items"`
+	}](s, w)
+
+	if created.Branch_id != s.branch_a || len(created.Order_items) != 2 {
+		s.t.Fatalf("the order wasn't stored with its items in branch A: %s", w.Body.String())
+	}
+
+	if *created.Order_items[0].Unit_price != 4.5 || *created.Order_items[1].Unit_price != 2 {
+		s.t.Fatalf("the items weren't priced from the foods: %s", w.Body.String())
+	}
+
+	w = s.request(http.MethodGet, "/orderItems-order/"+created.Order_id, waiter, nil)
+	s.expect(w, http.StatusOK, "order summary")
+
+	summary := decode[[]struct {
+		Payment_due float64 `json:"payment_due"`
+		Total_count int     `json:"total_count"`
+	}](s, w)
+
+	if len(summary) != 1 || summary[0].Payment_due != 6.5 || summary[0].Total_count != 2 {
+		s.t.Fatalf("got the summary %s , want 2 items and 6.5 to pay", w.Body.String())
+	}
+
+	// a food of another branch fails the whole order , neither the order nor its items are stored
+
+	menuB := s.createMenu(s.token(MANAGER_B))
+	foodB := s.createFood(s.token(MANAGER_B), menuB.Menu_id, "Pie", 3)
+
+	ordersBefore, err := s.store.Orders.List(context.Background(), s.branch_a, true)
+
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	w = s.request(http.MethodPost, "/orderitems", waiter, gin.H{
+		"table_id": table.Table_id,
+		"order_items": []gin.H{
+			{"food_id": soup.Food_id, "quantity": models.SIZE_MEDIUM},
+			{"food_id": foodB.Food_id, "quantity": models.SIZE_MEDIUM},
+		},
+	})
+	s.expect(w, http.StatusBadRequest, "order with a food of another branch")
+
+	s.expect(s.request(http.MethodPost, "/orderitems", waiter, gin.H{"table_id": table.Table_id, "order_items": []gin.H{}}), http.StatusBadRequest, "order without items")
+
+	ordersAfter, err := s.store.Orders.List(context.Background(), s.branch_a, true)
+
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	if len(ordersAfter) != len(ordersBefore) {
+		s.t.Fatalf("a refused order was stored anyway")
+	}
+}
+
+// invoiceView is the answer of GET /invoices/:invoice_id
+type invoiceView struct {
+	Payment_due   float64
+	Order_details []repository.OrderSummaryItem
+}
+
+func (s *testServer) invoiceOf(token string, orderId string) invoiceView {
+
+	s.t.Helper()
+
+	w := s.request(http.MethodPost, "/invoices", token, gin.H{"order_id": orderId})
+	s.expect(w, http.StatusOK, "create invoice")
+
+	w = s.request(http.MethodGet, "/invoices/"+decode[models.Invoice](s, w).Invoice_id, token, nil)
+	s.expect(w, http.StatusOK, "read invoice")
+
+	return decode[invoiceView](s, w)
+}
+
+// the price of a size and whether it is sold come from the sizes of the food , never from the client
+func testSizes(s *testServer) {
+
+	manager, waiter := s.token(MANAGER_A), s.token(WAITER_A)
+
+	menu := s.createMenu(manager)
+	pizza := s.createFood(manager, menu.Menu_id, "Pizza", 10)
+	table := s.createTable(manager, 5)
+
+	w := s.request(http.MethodPatch, "/foods/"+pizza.Food_id, manager, gin.H{"sizes": gin.H{
+		models.SIZE_LARGE: gin.H{"price": 14},
+		models.SIZE_SMALL: gin.H{"available": false},
+	}})
+	s.expect(w, http.StatusOK, "set the sizes")
+
+	order := s.createOrder(waiter, table.Table_id,
+		gin.H{"food_id": pizza.Food_id, "quantity": models.SIZE_LARGE, "unit_price": 0.01},
+		gin.H{"food_id": pizza.Food_id, "quantity": models.SIZE_MEDIUM},
+	)
+
+	if *order.Order_items[0].Unit_price != 14 || *order.Order_items[1].Unit_price != 10 {
+		s.t.Fatalf("got the unit prices %v and %v , want 14 for the large and 10 for the medium pizza", *order.Order_items[0].Unit_price, *order.Order_items[1].Unit_price)
+	}
+
+	// a size that isn't sold is refused , when ordering and when changing an item
+
+	w = s.request(http.MethodPost, "/orderitems", waiter, gin.H{"table_id": table.Table_id, "order_items": []gin.H{{"food_id": pizza.Food_id, "quantity": models.SIZE_SMALL}}})
+	s.expect(w, http.StatusBadRequest, "order of a size that isn't sold")
+
+	medium := order.Order_items[1]
+
+	s.expect(s.request(http.MethodPatch, "/orderitems/"+medium.Order_item_id, waiter, gin.H{"quantity": models.SIZE_SMALL}), http.StatusBadRequest, "item changed to a size that isn't sold")
+
+	// the unit price of the client is ignored when an item is changed as well
+
+	w = s.request(http.MethodPatch, "/orderitems/"+medium.Order_item_id, waiter, gin.H{"unit_price": 0.01})
+	s.expect(w, http.StatusOK, "item changed with a unit price")
+
+	if changed := decode[models.OrderItem](s, w); *changed.Unit_price != 10 {
+		s.t.Fatalf("the unit price of the client was stored: %s", w.Body.String())
+	}
+
+	invoice := s.invoiceOf(manager, order.Order_id)
+
+	if invoice.Payment_due != 24 || len(invoice.Order_details) != 2 || *invoice.Order_details[0].Price != 14 || *invoice.Order_details[1].Price != 10 {
+		s.t.Fatalf("got the invoice %+v , want 14 and 10 and 24 to pay", invoice)
+	}
+}
+
+// the modifiers a guest picks add the price deltas of the food to the unit price , nested ones included
+func testModifiers(s *testServer) {
+
+	manager, waiter := s.token(MANAGER_A), s.token(WAITER_A)
+
+	menu := s.createMenu(manager)
+	burger := s.createFood(manager, menu.Menu_id, "Burger", 9)
+	table := s.createTable(manager, 9)
+
+	w := s.request(http.MethodPatch, "/foods/"+burger.Food_id, manager, gin.H{"modifier_groups": []gin.H{
+		{"group_id": "side", "name": "Side", "required": true, "max_selections": 1, "options": []gin.H{
+			{"option_id": "fries", "name": "Fries", "groups": []gin.H{
+				{"group_id": "sauce", "name": "Sauce", "required": true, "max_selections": 1, "options": []gin.H{
+					{"option_id": "aioli", "name": "Aioli", "price_delta": 0.5},
+				}},
+			}},
+			{"option_id": "salad", "name": "Salad", "price_delta": 1},
+		}},
+		{"group_id": "extras", "name": "Extras", "options": []gin.H{
+			{"option_id": "bacon", "name": "Bacon", "price_delta": 2},
+			{"option_id": "egg", "name": "Egg", "price_delta": 1.5, "available": false},
+		}},
+	}})
+	s.expect(w, http.StatusOK, "set the modifier groups")
+
+	fries := gin.H{"group_id": "side", "option_id": "fries", "modifiers": []gin.H{{"group_id": "sauce", "option_id": "aioli"}}}
+
+	order := s.createOrder(waiter, table.Table_id,
+		gin.H{"food_id": burger.Food_id, "quantity": models.SIZE_MEDIUM, "modifiers": []gin.H{fries, {"group_id": "extras", "option_id": "bacon", "price_delta": -9}}},
+		gin.H{"food_id": burger.Food_id, "quantity": models.SIZE_MEDIUM, "modifiers": []gin.H{{"group_id": "side", "option_id": "salad"}}},
+	)
+
+	if *order.Order_items[0].Unit_price != 11.5 || *order.Order_items[1].Unit_price != 10 {
+		s.t.Fatalf("got the unit prices %v and %v , want 11.5 and 10", *order.Order_items[0].Unit_price, *order.Order_items[1].Unit_price)
+	}
+
+	refused := []struct {
+		what      string
+		modifiers []gin.H
+	}{
+		{"item without the required side", []gin.H{}},
+		{"item with a modifier that isn't available", []gin.H{fries, {"group_id": "extras", "option_id": "egg"}}},
+		{"item with two sides", []gin.H{fries, {"group_id": "side", "option_id": "salad"}}},
+		{"item with the same extra twice", []gin.H{fries, {"group_id": "extras", "option_id": "bacon"}, {"group_id": "extras", "option_id": "bacon"}}},
+		{"item without the sauce of the fries", []gin.H{{"group_id": "side", "option_id": "fries"}}},
+	}
+
+	for _, r := range refused {
+		w = s.request(http.MethodPost, "/orderitems", waiter, gin.H{"table_id": table.Table_id, "order_items": []gin.H{{"food_id": burger.Food_id, "quantity": models.SIZE_MEDIUM, "modifiers": r.modifiers}}})
+		s.expect(w, http.StatusBadRequest, r.what)
+	}
+
+	invoice := s.invoiceOf(manager, order.Order_id)
+
+	if invoice.Payment_due != 21.5 || len(invoice.Order_details) != 2 {
+		s.t.Fatalf("got the invoice %+v , want 2 items and 21.5 to pay", invoice)
+	}
+
+	first := invoice.Order_details[0]
+
+	if *first.Price != 11.5 || len(first.Modifiers) != 2 || first.Modifiers[0].Name != "Fries" || len(first.Modifiers[0].Modifiers) != 1 ||
+		first.Modifiers[0].Modifiers[0].Price_delta != 0.5 || first.Modifiers[1].Price_delta != 2 {
+		s.t.Fatalf("the invoice lists the first item as %+v", first)
+	}
+}
+
+func testLoginLockout(s *testServer) {
+
+	wrongPassword := func(email string) {
+		s.t.Helper()
+		s.expect(s.request(http.MethodPost, "/users/login", "", gin.H{"email": email, "password": "wrong-password"}), http.StatusUnauthorized, "wrong password of "+email)
+	}
+
+	lockedOut := func(email string, minimum int) {
+		s.t.Helper()
+
+		// the right password doesn't help while the caller is held back
+		w := s.request(http.MethodPost, "/users/login", "", gin.H{"email": email, "password": TEST_PASSWORD})
+		s.expect(w, http.StatusTooManyRequests, "login of "+email+" while held back")
+
+		if wait, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || wait < minimum {
+			s.t.Fatalf("got the Retry-After %q , want at least %d seconds", w.Header().Get("Retry-After"), minimum)
+		}
+	}
+
+	// a few failures hold the email back , other staff behind the same ip can still log in
+
+	for i := 0; i < 3; i++ {
+		wrongPassword(WAITER_A)
+	}
+
+	lockedOut(WAITER_A, 1)
+	s.login(MANAGER_A)
+
+	// the email is held back from every ip
+
+	s.remote_addr = "198.51.100.7:1234"
+	lockedOut(WAITER_A, 1)
+
+	// reaching the limit locks the email for a long time and is written to the audit log.
+	// the failures are recorded directly , the handler would make the test wait out every backoff
+	for i := 3; i < 10; i++ {
+		if err := helper.RecordLoginFailure(context.Background(), WAITER_A, "203.0.113.9", s.user_ids[WAITER_A]); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+
+	lockedOut(WAITER_A, 60)
+
+	_, count, err := audit.Find(context.Background(), audit.Filter{Action: "login.locked", Entity_id: s.user_ids[WAITER_A]}, 0, 10)
+
+	if err != nil || count != 1 {
+		s.t.Fatalf("got %d login.locked events (%v) , want 1", count, err)
+	}
+
+	s.expect(s.request(http.MethodPost, "/users/"+s.user_ids[WAITER_A]+"/unlock", s.token(ADMIN_A), nil), http.StatusOK, "unlock")
+	s.login(WAITER_A)
+
+	// an ip is held back after many failures over any emails , the accounts behind other ips are not affected.
+	// every email stays below its own backoff , and they all exist so the failures don't pay for the dummy hash of an unknown email
+
+	s.remote_addr = "192.0.2.50:1234"
+
+	for i := 0; i < 10; i++ {
+		email := fmt.Sprintf("guest%d@test.io", i)
+		s.createUser(email, models.ROLE_WAITER, s.branch_a)
+
+		wrongPassword(email)
+		wrongPassword(email)
+	}
+
+	lockedOut(MANAGER_B, 1)
+
+	s.remote_addr = "192.0.2.51:1234"
+	s.login(MANAGER_B)
+}
+
+// only unknown emails are used , they are throttled like the known ones and no mail is written
+func testPasswordResetThrottle(s *testServer) {
+
+	forgot := func(email string, status int) {
+		s.t.Helper()
+
+		w := s.request(http.MethodPost, "/users/password/forgot", "", gin.H{"email": email})
+		s.expect(w, status, "password reset of "+email)
+
+		if status == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			s.t.Fatalf("the 429 has no Retry-After")
+		}
+	}
+
+	// one request a minute per email , from any ip
+
+	forgot("nobody@test.io", http.StatusOK)
+	forgot("nobody@test.io", http.StatusTooManyRequests)
+
+	s.remote_addr = "198.51.100.7:1234"
+	forgot("nobody@test.io", http.StatusTooManyRequests)
+
+	// an ip is held back after many requests over any emails , other ips are not affected
+
+	for i := 1; i < 20; i++ {
+		forgot(fmt.Sprintf("guess%d@test.io", i), http.StatusOK)
+	}
+
+	forgot("guess20@test.io", http.StatusOK)
+	forgot("guess21@test.io", http.StatusTooManyRequests)
+
+	s.remote_addr = "198.51.100.8:1234"
+	forgot("guess21@test.io", http.StatusOK)
+}
+
+func testApiKeys(s *testServer) {
+
+	admin, managerA, managerB := s.token(ADMIN_A), s.token(MANAGER_A), s.token(MANAGER_B)
+
+	s.expect(s.request(http.MethodPost, "/apikeys", admin, gin.H{"name": "Till", "scopes": []string{"foods:read", "tips:write"}}), http.StatusBadRequest, "key with an unknown scope")
+	s.expect(s.request(http.MethodPost, "/apikeys", managerA, gin.H{"name": "Till", "scopes": []string{"foods:read"}}), http.StatusForbidden, "manager creates a key")
+
+	// the key of a branch admin is always for their own branch
+
+	w := s.request(http.MethodPost, "/apikeys", admin, gin.H{"name": "Kitchen display", "scopes": []string{"foods:read", "menus:write"}, "branch_id": s.branch_b})
+	s.expect(w, http.StatusOK, "create key")
+
+	created := decode[struct {
+		Api_key models.ApiKey `json:"api_key"`
+		Key     string        `json:"key"`
+	}](s, w)
+
+	if created.Api_key.Branch_id != s.branch_a || created.Key == "" {
+		s.t.Fatalf("the key wasn't created for branch A: %s", w.Body.String())
+	}
+
+	withKey := func(method string, path string, body interface{}) *httptest.ResponseRecorder {
+		s.t.Helper()
+		return s.request(method, path, "", body, "Authorization", "ApiKey "+created.Key)
+	}
+
+	foodA := s.createFood(managerA, s.createMenu(managerA).Menu_id, "Soup", 4.5)
+	foodB := s.createFood(managerB, s.createMenu(managerB).Menu_id, "Pie", 3)
+
+	// the scopes of the key open the routes that permit them
+
+	w = withKey(http.MethodGet, "/foods", nil)
+	s.expect(w, http.StatusOK, "key lists the foods")
+
+	foods := decode[[]struct {
+		Food_items []models.Food `json:"food_items"`
+	}](s, w)
+
+	if len(foods) != 1 || len(foods[0].Food_items) != 1 || foods[0].Food_items[0].Food_id != foodA.Food_id {
+		s.t.Fatalf("the key doesn't only see the foods of its branch: %s", w.Body.String())
+	}
+
+	s.expect(withKey(http.MethodGet, "/foods/"+foodA.Food_id, nil), http.StatusOK, "key reads a food of its branch")
+	s.expect(withKey(http.MethodGet, "/foods/"+foodB.Food_id, nil), http.StatusNotFound, "key reads a food of another branch")
+
+	w = withKey(http.MethodPost, "/menus", gin.H{"name": "Drinks", "category": "DRINKS"})
+	s.expect(w, http.StatusOK, "key creates a menu")
+
+	if menu := decode[models.Menu](s, w); menu.Branch_id != s.branch_a {
+		s.t.Fatalf("the menu of the key wasn't stored in its branch: %s", w.Body.String())
+	}
+
+	// every other scope is refused , and so are the routes only staff may use
+
+	s.expect(withKey(http.MethodPost, "/foods", gin.H{"name": "Bread", "price": 2, "food_image": "x", "menu_id": foodA.Menu_id}), http.StatusForbidden, "key without foods:write")
+	s.expect(withKey(http.MethodGet, "/tables", nil), http.StatusForbidden, "key without tables:read")
+	s.expect(withKey(http.MethodGet, "/users/", nil), http.StatusForbidden, "key lists the users")
+	s.expect(withKey(http.MethodGet, "/apikeys", nil), http.StatusForbidden, "key lists the keys")
+	s.expect(withKey(http.MethodPost, "/users/logout", nil), http.StatusForbidden, "key logs out")
+
+	// a revoked or unknown key is no key at all
+
+	s.expect(s.request(http.MethodDelete, "/apikeys/"+created.Api_key.Api_key_id, admin, nil), http.StatusOK, "revoke")
+	s.expect(withKey(http.MethodGet, "/foods", nil), http.StatusUnauthorized, "revoked key")
+	s.expect(s.request(http.MethodGet, "/foods", "", nil, "Authorization", "ApiKey not-a-key"), http.StatusUnauthorized, "unknown key")
+}
+
+// the references of new and changed documents and the rules of a delete , see the integrity package
+func testIntegrity(s *testServer) {
+
+	ctx := context.Background()
+	manager, managerB, waiter := s.token(MANAGER_A), s.token(MANAGER_B), s.token(WAITER_A)
+
+	menu := s.createMenu(manager)
+	soup := s.createFood(manager, menu.Menu_id, "Soup", 4.5)
+	table := s.createTable(manager, 3)
+
+	menuB := s.createMenu(managerB)
+	pie := s.createFood(managerB, menuB.Menu_id, "Pie", 3)
+	tableB := s.createTable(managerB, 3)
+
+	// a reference has to be a document of the same branch that is not deleted
+
+	s.expect(s.request(http.MethodPost, "/foods", manager, gin.H{"name": "Bread", "price": 2, "food_image": "x", "menu_id": "missing"}), http.StatusBadRequest, "food in a menu that doesn't exist")
+	s.expect(s.request(http.MethodPatch, "/foods/"+soup.Food_id, manager, gin.H{"menu_id": menuB.Menu_id}), http.StatusBadRequest, "food moved into the menu of another branch")
+	s.expect(s.request(http.MethodPost, "/orders", waiter, gin.H{"table_id": tableB.Table_id}), http.StatusBadRequest, "order at the table of another branch")
+
+	w := s.request(http.MethodPost, "/orders", managerB, gin.H{"table_id": tableB.Table_id})
+	s.expect(w, http.StatusOK, "order of branch B")
+	orderB := decode[models.Order](s, w)
+
+	s.expect(s.request(http.MethodPost, "/invoices", manager, gin.H{"order_id": orderB.Order_id}), http.StatusBadRequest, "invoice of the order of another branch")
+
+	order := s.createOrder(waiter, table.Table_id, gin.H{"food_id": soup.Food_id, "quantity": models.SIZE_MEDIUM}, gin.H{"food_id": soup.Food_id, "quantity": models.SIZE_LARGE})
+
+	s.expect(s.request(http.MethodPatch, "/orderitems/"+order.Order_items[0].Order_item_id, waiter, gin.H{"food_id": pie.Food_id}), http.StatusBadRequest, "item changed to the food of another branch")
+
+	s.expect(s.request(http.MethodDelete, "/tables/"+table.Table_id, manager, nil), http.StatusOK, "delete of a table , its orders are kept")
+	s.expect(s.request(http.MethodPost, "/orders", waiter, gin.H{"table_id": table.Table_id}), http.StatusBadRequest, "order at a deleted table")
+
+	if _, err := s.store.Orders.Get(ctx, s.branch_a, order.Order_id, false); err != nil {
+		s.t.Fatalf("the order of the deleted table is gone: %v", err)
+	}
+
+	// order.invoices restricts , nothing is deleted while the order has an invoice
+
+	w = s.request(http.MethodPost, "/invoices", manager, gin.H{"order_id": order.Order_id})
+	s.expect(w, http.StatusOK, "create invoice")
+	invoice := decode[models.Invoice](s, w)
+
+	s.expect(s.request(http.MethodDelete, "/orders/"+order.Order_id, manager, nil), http.StatusConflict, "delete of an order with an invoice")
+
+	if items, _ := s.store.OrderItems.ListByOrder(ctx, s.branch_a, order.Order_id); len(items) != 2 {
+		s.t.Fatalf("the refused delete took %d of the 2 items along", 2-len(items))
+	}
+
+	// order.order_items cascades , the items are deleted with the order by the same user at the same time
+
+	s.expect(s.request(http.MethodDelete, "/invoices/"+invoice.Invoice_id, manager, nil), http.StatusOK, "delete of the invoice")
+
+	w = s.request(http.MethodDelete, "/orders/"+order.Order_id, manager, nil)
+	s.expect(w, http.StatusOK, "delete of the order")
+	deleted := decode[models.Order](s, w)
+
+	for _, item := range order.Order_items {
+		stored, err := s.store.OrderItems.Get(ctx, s.branch_a, item.Order_item_id, true)
+
+		if err != nil {
+			s.t.Fatal(err)
+		}
+
+		if stored.Deleted_at == nil || !stored.Deleted_at.Equal(*deleted.Deleted_at) || *stored.Deleted_by != s.user_ids[MANAGER_A] {
+			s.t.Fatalf("the item %s wasn't deleted with the order: %+v", item.Order_item_id, stored)
+		}
+	}
+
+	events, _, err := s.store.Audit.Find(ctx, repository.AuditFilter{Entity_type: "order_item", Action: "order_item." + audit.ACTION_DELETE}, 0, 0)
+
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		s.t.Fatalf("got %d audit events of the cascaded items , want 2", len(events))
+	}
+
+	// what fails inside a transaction is taken back as a whole
+
+	failed := fmt.Errorf("failed on purpose")
+
+	err = s.store.Transaction(ctx, func(ctx context.Context) error {
+
+		food, err := s.store.Foods.Get(ctx, s.branch_a, soup.Food_id, false)
+
+		if err != nil {
+			return err
+		}
+
+		price := 99.0
+		food.Price = &price
+
+		if err := s.store.Foods.Update(ctx, food); err != nil {
+			return err
+		}
+
+		if err := s.store.Foods.Create(ctx, &models.Food{ID: primitive.NewObjectID(), Food_id: "rolled-back", Menu_id: &menu.Menu_id, Branch_id: s.branch_a, Version: 1}); err != nil {
+			return err
+		}
+
+		return failed
+	})
+
+	if err != failed {
+		s.t.Fatalf("got the error %v from the transaction , want the one of fn", err)
+	}
+
+	if food, err := s.store.Foods.Get(ctx, s.branch_a, soup.Food_id, false); err != nil || *food.Price != 4.5 || food.Version != soup.Version {
+		s.t.Fatalf("the change of a failed transaction was kept: %+v %v", food, err)
+	}
+
+	if _, err := s.store.Foods.Get(ctx, s.branch_a, "rolled-back", true); err != repository.ErrNotFound {
+		s.t.Fatalf("the food of a failed transaction was kept: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+)
+
+// every key starts with this prefix so that it is easy to recognise in logs and secret scanners
+const API_KEY_PREFIX = "rk_"
+
+// GenerateApiKey returns the plain key that is shown once to the admin and the prefix which is kept to recognise the key in listings
+func GenerateApiKey() (key string, prefix string, err error) {
+
+	secret, err := GenerateSecret(24)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	key = API_KEY_PREFIX + secret
+
+	return key, key[:len(API_KEY_PREFIX)+8], nil
+}
+
+// ValidateApiKey finds the key by its hash , revoked keys are rejected
+func ValidateApiKey(key string) (apiKey *models.ApiKey, msg string) {
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	foundKey, err := apiKeys.GetByHash(ctx, HashSecret(key))
+
+	if err == repository.ErrNotFound {
+		return nil, "the api key is invalid"
+	}
+
+	if err != nil {
+		return nil, "error occured while checking the api key"
+	}
+
+	if foundKey.Revoked_at != nil {
+		return nil, "the api key has been revoked"
+	}
+
+	// the last use is only informational , a failed write must not block the request
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	apiKeys.Touch(ctx, foundKey.Api_key_id, now)
+
+	return foundKey, ""
+}
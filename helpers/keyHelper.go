@@ -0,0 +1,309 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// jwtKey is one key of the key ring , private keys only exist for the key that currently signs
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	// a key with an until is refused from then on , it is only set for the HS256 secret of LEGACY_HS256_UNTIL
+	until time.Time
+}
+
+type keyRing struct {
+	signing      *jwtKey
+	verification map[string]*jwtKey
+}
+
+var keys keyRing
+
+// LoadKeys reads the JWT keys from the environment , main() refuses to start when it fails.
+//
+//	JWT_SIGNING_KEY_FILE       PEM private key (RSA for RS256 , Ed25519 for EdDSA) that signs new tokens
+//	JWT_SIGNING_KEY_ID         kid of the signing key , derived from the public key when empty
+//	JWT_VERIFICATION_KEYS_DIR  directory of <kid>.pem public keys that are still accepted , used while rotating keys
+//	SECRET_KEY                 HS256 secret of older setups , it signs and verifies only when no signing key file is given
+//	LEGACY_HS256_UNTIL         RFC 3339 time , with a signing key file the tokens of SECRET_KEY are still accepted until then.
+//	                           without it they are refused as soon as the signing key file is configured
+func LoadKeys() error {
+
+	ring := keyRing{verification: map[string]*jwtKey{}}
+	secret := os.Getenv("SECRET_KEY")
+
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		key, err := loadSigningKey(path, os.Getenv("JWT_SIGNING_KEY_ID"))
+
+		if err != nil {
+			return err
+		}
+
+		ring.signing = key
+		ring.verification[key.id] = key
+
+		legacyUntil, err := legacyHS256Until()
+
+		if err != nil {
+			return err
+		}
+
+		if secret != "" && !legacyUntil.IsZero() {
+			ring.verification[""] = &jwtKey{method: jwt.SigningMethodHS256, verifyKey: []byte(secret), until: legacyUntil}
+		}
+	} else if secret != "" {
+		ring.signing = &jwtKey{method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+		ring.verification[""] = ring.signing
+	}
+
+	if ring.signing == nil {
+		return errors.New("no JWT signing key is configured , set JWT_SIGNING_KEY_FILE (RS256 or EdDSA) or SECRET_KEY (HS256)")
+	}
+
+	if dir := os.Getenv("JWT_VERIFICATION_KEYS_DIR"); dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+
+		if err != nil {
+			return err
+		}
+
+		for _, path := range paths {
+			kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+
+			// the signing key is already known with its private part
+			if _, found := ring.verification[kid]; found {
+				continue
+			}
+
+			key, err := loadVerificationKey(path, kid)
+
+			if err != nil {
+				return err
+			}
+
+			ring.verification[kid] = key
+		}
+	}
+
+	keys = ring
+
+	return nil
+}
+
+// legacyHS256Until is the time of LEGACY_HS256_UNTIL , zero when it isn't set
+func legacyHS256Until() (time.Time, error) {
+
+	value := os.Getenv("LEGACY_HS256_UNTIL")
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	until, err := time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("LEGACY_HS256_UNTIL has to be an RFC 3339 time like 2024-01-31T00:00:00Z: %w", err)
+	}
+
+	return until, nil
+}
+
+func signToken(claims jwt.Claims) (string, error) {
+
+	if keys.signing == nil {
+		return "", errors.New("the JWT keys have not been loaded")
+	}
+
+	token := jwt.NewWithClaims(keys.signing.method, claims)
+
+	if keys.signing.id != "" {
+		token.Header["kid"] = keys.signing.id
+	}
+
+	return token.SignedString(keys.signing.signKey)
+}
+
+// verificationKey is the key function of jwt.ParseWithClaims , the algorithm has to be the one of the key so an RSA public key can't be used as an HMAC secret
+func verificationKey(token *jwt.Token) (interface{}, error) {
+
+	kid, _ := token.Header["kid"].(string)
+
+	key, found := keys.verification[kid]
+
+	if !found {
+		return nil, fmt.Errorf("the token was signed with the unknown key %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	if !key.until.IsZero() && time.Now().After(key.until) {
+		return nil, fmt.Errorf("tokens signed with the legacy HS256 secret are no longer accepted")
+	}
+
+	return key.verifyKey, nil
+}
+
+func loadSigningKey(path string, kid string) (*jwtKey, error) {
+
+	block, err := readPEM(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKey interface{}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return newJwtKey(kid, jwt.SigningMethodRS256, privateKey, &privateKey.PublicKey)
+	case ed25519.PrivateKey:
+		return newJwtKey(kid, jwt.SigningMethodEdDSA, privateKey, privateKey.Public())
+	}
+
+	return nil, fmt.Errorf("%s: only RSA and Ed25519 private keys are supported", path)
+}
+
+func loadVerificationKey(path string, kid string) (*jwtKey, error) {
+
+	block, err := readPEM(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey interface{}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		return newJwtKey(kid, jwt.SigningMethodRS256, nil, publicKey)
+	case ed25519.PublicKey:
+		return newJwtKey(kid, jwt.SigningMethodEdDSA, nil, publicKey)
+	}
+
+	return nil, fmt.Errorf("%s: only RSA and Ed25519 public keys are supported", path)
+}
+
+// newJwtKey derives the kid from the public key when none is configured , so every instance holding the same key announces the same kid
+func newJwtKey(kid string, method jwt.SigningMethod, signKey interface{}, verifyKey interface{}) (*jwtKey, error) {
+
+	if kid == "" {
+		der, err := x509.MarshalPKIXPublicKey(verifyKey)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(der)
+		kid = hex.EncodeToString(sum[:8])
+	}
+
+	return &jwtKey{id: kid, method: method, signKey: signKey, verifyKey: verifyKey}, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	return block, nil
+}
+
+// JSONWebKey is one entry of the JWKS document (RFC 7517) , the fields that don't apply to the key type are left out
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS lists the public keys other services need to verify our tokens , HMAC secrets are never published
+func JWKS() []JSONWebKey {
+
+	jwks := []JSONWebKey{}
+
+	for _, key := range keys.verification {
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, JSONWebKey{
+				Kty: "RSA",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, JSONWebKey{
+				Kty: "OKP",
+				Kid: key.id,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+
+	return jwks
+}
@@ -0,0 +1,289 @@
+package helpers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-secret-key-0123456789abcdef"
+
+// testKeys are an RSA key that signs and an Ed25519 key of the previous rotation that is still accepted
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func writePEM(t *testing.T, path string, blockType string, der []byte) {
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setUpKeyRing writes the keys into files and points the environment at them , LEGACY_HS256_UNTIL is left to the tests
+func setUpKeyRing(t *testing.T) testKeys {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	verificationDir := filepath.Join(dir, "verification")
+
+	if err := os.Mkdir(verificationDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, filepath.Join(dir, "signing.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	edDer, err := x509.MarshalPKIXPublicKey(edPublic)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, filepath.Join(verificationDir, "ed-old.pem"), "PUBLIC KEY", edDer)
+
+	t.Setenv("JWT_SIGNING_KEY_FILE", filepath.Join(dir, "signing.pem"))
+	t.Setenv("JWT_SIGNING_KEY_ID", "rsa-1")
+	t.Setenv("JWT_VERIFICATION_KEYS_DIR", verificationDir)
+	t.Setenv("SECRET_KEY", testSecret)
+	t.Setenv("LEGACY_HS256_UNTIL", "")
+
+	return testKeys{rsa: rsaKey, ed25519: edPrivate}
+}
+
+func testClaims() *SignedDetails {
+	return &SignedDetails{Uid: "user", Token_type: ACCESS_TOKEN, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}}
+}
+
+// signWith signs the claims with any key and kid , like a token of another setup or a forged one
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+
+	token := jwt.NewWithClaims(method, testClaims())
+
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func verify(signed string) error {
+
+	_, err := jwt.ParseWithClaims(signed, &SignedDetails{}, verificationKey)
+	return err
+}
+
+func TestLoadKeysSignsWithTheKeyFile(t *testing.T) {
+
+	setUpKeyRing(t)
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := signToken(testClaims())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := new(jwt.Parser).ParseUnverified(signed, &SignedDetails{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.Header["kid"] != "rsa-1" || token.Method.Alg() != "RS256" {
+		t.Errorf("got the kid %v and %s , want rsa-1 and RS256", token.Header["kid"], token.Method.Alg())
+	}
+
+	if err := verify(signed); err != nil {
+		t.Errorf("our own token was refused: %v", err)
+	}
+}
+
+func TestVerificationKeyPicksTheKeyByKid(t *testing.T) {
+
+	ring := setUpKeyRing(t)
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		signed string
+		ok     bool
+	}{
+		{"key of the previous rotation", signWith(t, jwt.SigningMethodEdDSA, "ed-old", ring.ed25519), true},
+		{"signing key", signWith(t, jwt.SigningMethodRS256, "rsa-1", ring.rsa), true},
+		{"unknown kid", signWith(t, jwt.SigningMethodRS256, "rsa-2", ring.rsa), false},
+		{"key under the kid of another key", signWith(t, jwt.SigningMethodRS256, "ed-old", ring.rsa), false},
+		// the public key is no secret , a token signed with it as an HMAC key must not pass
+		{"HS256 under the kid of the RSA key", signWith(t, jwt.SigningMethodHS256, "rsa-1", x509.MarshalPKCS1PublicKey(&ring.rsa.PublicKey)), false},
+		{"HS256 without LEGACY_HS256_UNTIL", signWith(t, jwt.SigningMethodHS256, "", []byte(testSecret)), false},
+	}
+
+	for _, c := range cases {
+		if err := verify(c.signed); (err == nil) != c.ok {
+			t.Errorf("%s: got the error %v , want it accepted %v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestLegacyHS256Until(t *testing.T) {
+
+	setUpKeyRing(t)
+
+	legacy := signWith(t, jwt.SigningMethodHS256, "", []byte(testSecret))
+
+	t.Setenv("LEGACY_HS256_UNTIL", time.Now().Add(time.Hour).Format(time.RFC3339))
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(legacy); err != nil {
+		t.Errorf("a legacy token was refused before LEGACY_HS256_UNTIL: %v", err)
+	}
+
+	// only the algorithm of the secret is accepted without a kid
+	if err := verify(signWith(t, jwt.SigningMethodHS512, "", []byte(testSecret))); err == nil {
+		t.Errorf("an HS512 token was accepted for the HS256 secret")
+	}
+
+	// new tokens are still signed with the key file
+	if signed, _ := signToken(testClaims()); signed == "" || verify(signed) != nil {
+		t.Errorf("the key file doesn't sign anymore")
+	}
+
+	t.Setenv("LEGACY_HS256_UNTIL", time.Now().Add(-time.Minute).Format(time.RFC3339))
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(legacy); err == nil {
+		t.Errorf("a legacy token was accepted after LEGACY_HS256_UNTIL")
+	}
+
+	t.Setenv("LEGACY_HS256_UNTIL", "next week")
+
+	if err := LoadKeys(); err == nil {
+		t.Errorf("a LEGACY_HS256_UNTIL that is no RFC 3339 time was accepted")
+	}
+}
+
+func TestLoadKeysWithOnlyTheSecret(t *testing.T) {
+
+	t.Setenv("JWT_SIGNING_KEY_FILE", "")
+	t.Setenv("JWT_VERIFICATION_KEYS_DIR", "")
+	t.Setenv("SECRET_KEY", testSecret)
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := signToken(testClaims())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(signed); err != nil {
+		t.Errorf("a token of the secret was refused: %v", err)
+	}
+
+	if jwks := JWKS(); len(jwks) != 0 {
+		t.Errorf("the HS256 secret was published: %v", jwks)
+	}
+
+	t.Setenv("SECRET_KEY", "")
+
+	if err := LoadKeys(); err == nil {
+		t.Errorf("LoadKeys didn't fail without any key")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+
+	ring := setUpKeyRing(t)
+	t.Setenv("LEGACY_HS256_UNTIL", time.Now().Add(time.Hour).Format(time.RFC3339))
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := JWKS()
+
+	// sorted by kid , the legacy HMAC secret is left out
+	if len(jwks) != 2 || jwks[0].Kid != "ed-old" || jwks[1].Kid != "rsa-1" {
+		t.Fatalf("got %+v , want the keys ed-old and rsa-1", jwks)
+	}
+
+	ed := jwks[0]
+	edPublic := ring.ed25519.Public().(ed25519.PublicKey)
+
+	if ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.Use != "sig" || ed.X != base64.RawURLEncoding.EncodeToString(edPublic) || ed.N != "" {
+		t.Errorf("the Ed25519 key is published as %+v", ed)
+	}
+
+	rsaKey := jwks[1]
+
+	n, err := base64.RawURLEncoding.DecodeString(rsaKey.N)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rsaKey.Kty != "RSA" || rsaKey.Alg != "RS256" || rsaKey.Use != "sig" || rsaKey.E != "AQAB" || new(big.Int).SetBytes(n).Cmp(ring.rsa.N) != 0 || rsaKey.X != "" {
+		t.Errorf("the RSA key is published as %+v", rsaKey)
+	}
+}
+
+func TestSigningKeyIdIsDerivedFromThePublicKey(t *testing.T) {
+
+	setUpKeyRing(t)
+	t.Setenv("JWT_SIGNING_KEY_ID", "")
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	first := keys.signing.id
+
+	if err := LoadKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != 16 || keys.signing.id != first {
+		t.Errorf("got the kids %q and %q , want the same 16 hex digits", first, keys.signing.id)
+	}
+}
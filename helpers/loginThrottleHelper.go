@@ -0,0 +1,139 @@
+package helpers
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"strings"
+	"time"
+)
+
+// failed logins are counted per email and per client ip. after a few failures every further attempt has to wait twice as long as the one before ,
+// after too many failures the email or the ip is locked for a while. the check happens before the password is hashed , so a locked caller costs no bcrypt time.
+// many staff can share the ip of a restaurant , so an ip is held back much later than an email
+
+const (
+	emailBackoffAfter    = 3
+	ipBackoffAfter       = 20
+	loginBackoffBase     = time.Second
+	loginBackoffMax      = 5 * time.Minute
+	emailLockoutAfter    = 10
+	ipLockoutAfter       = 50
+	loginLockoutDuration = 15 * time.Minute
+	// failures older than this are forgotten
+	loginFailureWindow = 15 * time.Minute
+)
+
+func emailAttemptKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginRetryAfter returns how long the caller has to wait before the next login attempt , zero means the attempt may go ahead
+func LoginRetryAfter(ctx context.Context, email string, ip string) (time.Duration, error) {
+
+	return retryAfter(ctx, emailAttemptKey(email), ipAttemptKey(ip))
+}
+
+// retryAfter returns the longest wait of the keys
+func retryAfter(ctx context.Context, keys ...string) (time.Duration, error) {
+
+	var wait time.Duration
+
+	for _, key := range keys {
+		attempt, err := loginAttempts.Get(ctx, key)
+
+		if err == repository.ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		if remaining := time.Until(attempt.Locked_until); remaining > wait {
+			wait = remaining
+		}
+	}
+
+	return wait, nil
+}
+
+// RecordLoginFailure counts a failed attempt for the email and the ip , reaching the limit locks them and is written to the audit log
+func RecordLoginFailure(ctx context.Context, email string, ip string, userId string) error {
+
+	if err := recordFailure(ctx, emailAttemptKey(email), emailBackoffAfter, emailLockoutAfter, ip, userId); err != nil {
+		return err
+	}
+
+	return recordFailure(ctx, ipAttemptKey(ip), ipBackoffAfter, ipLockoutAfter, ip, userId)
+}
+
+func recordFailure(ctx context.Context, key string, backoffAfter int, lockoutAfter int, ip string, userId string) error {
+
+	now := time.Now()
+
+	attempt, err := loginAttempts.RecordFailure(ctx, key, now, loginFailureWindow)
+
+	if err != nil {
+		return err
+	}
+
+	if attempt.Failures < backoffAfter {
+		return nil
+	}
+
+	lockedUntil := now.Add(loginBackoff(attempt.Failures - backoffAfter))
+	locked := attempt.Failures >= lockoutAfter
+
+	if locked {
+		lockedUntil = now.Add(loginLockoutDuration)
+	}
+
+	if err := loginAttempts.Lock(ctx, key, lockedUntil); err != nil {
+		return err
+	}
+
+	// only the failure that reaches the limit is recorded , not every attempt made while locked
+	if attempt.Failures == lockoutAfter {
+		audit.Record(ctx, models.AuditEvent{
+			Action:      "login.locked",
+			Entity_type: "user",
+			Entity_id:   userId,
+			Details: map[string]interface{}{
+				"key":          key,
+				"ip":           ip,
+				"failures":     attempt.Failures,
+				"locked_until": lockedUntil,
+			},
+		})
+	}
+
+	return nil
+}
+
+// loginBackoff doubles the wait with every failure past the backoff threshold , up to loginBackoffMax
+func loginBackoff(failuresPast int) time.Duration {
+
+	wait := loginBackoffBase
+
+	for i := 0; i < failuresPast; i++ {
+		wait *= 2
+
+		if wait >= loginBackoffMax {
+			return loginBackoffMax
+		}
+	}
+
+	return wait
+}
+
+// ResetLoginFailures is called after a successful login and when an admin unlocks a user , the ip counter is left alone so one valid account can't clear it
+func ResetLoginFailures(ctx context.Context, email string) error {
+
+	return loginAttempts.Delete(ctx, emailAttemptKey(email))
+}
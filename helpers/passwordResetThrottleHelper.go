@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// every password reset request sends a mail , so unlike the logins every request is counted and not only the failures.
+// an email gets one mail a minute and a few an hour , an ip is held back after many requests over any emails.
+// the requests are counted whether the email has an account or not , a 429 tells nothing about who works here
+
+const (
+	resetEmailCooldown = time.Minute
+	resetEmailLimit    = 5
+	resetIpLimit       = 20
+	// requests older than this are forgotten , reaching a limit holds the email or the ip back for as long
+	resetRequestWindow = time.Hour
+)
+
+func resetEmailKey(email string) string {
+	return "reset-email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func resetIpKey(ip string) string {
+	return "reset-ip:" + ip
+}
+
+// PasswordResetRetryAfter returns how long the caller has to wait before the next password reset request , zero means the request may go ahead
+func PasswordResetRetryAfter(ctx context.Context, email string, ip string) (time.Duration, error) {
+
+	return retryAfter(ctx, resetEmailKey(email), resetIpKey(ip))
+}
+
+// RecordPasswordResetRequest counts a password reset request for the email and the ip
+func RecordPasswordResetRequest(ctx context.Context, email string, ip string) error {
+
+	now := time.Now()
+
+	attempt, err := loginAttempts.RecordFailure(ctx, resetEmailKey(email), now, resetRequestWindow)
+
+	if err != nil {
+		return err
+	}
+
+	lockedUntil := now.Add(resetEmailCooldown)
+
+	if attempt.Failures >= resetEmailLimit {
+		lockedUntil = now.Add(resetRequestWindow)
+	}
+
+	if err := loginAttempts.Lock(ctx, resetEmailKey(email), lockedUntil); err != nil {
+		return err
+	}
+
+	attempt, err = loginAttempts.RecordFailure(ctx, resetIpKey(ip), now, resetRequestWindow)
+
+	if err != nil {
+		return err
+	}
+
+	if attempt.Failures < resetIpLimit {
+		return nil
+	}
+
+	return loginAttempts.Lock(ctx, resetIpKey(ip), now.Add(resetRequestWindow))
+}
@@ -0,0 +1,23 @@
+package helpers
+
+import (
+	"go-restaurent-management-system/repository"
+)
+
+var (
+	users         repository.UserRepository
+	apiKeys       repository.ApiKeyRepository
+	loginAttempts repository.LoginAttemptRepository
+
+	// Revocations is set up by Init() with the revocation repository of the store
+	Revocations repository.RevocationRepository
+)
+
+// Init hands the helpers the repositories they use , main() calls it once after the store is built
+func Init(store *repository.Store) {
+
+	users = store.Users
+	apiKeys = store.ApiKeys
+	loginAttempts = store.LoginAttempts
+	Revocations = store.Revocations
+}
@@ -0,0 +1,39 @@
+package helpers
+
+import (
+	"context"
+	"time"
+)
+
+// CheckRevocation returns a message when the token was revoked on its own or together with all the other tokens of its user
+func CheckRevocation(claims *SignedDetails) (msg string) {
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if claims.Id != "" {
+		revoked, err := Revocations.IsTokenRevoked(ctx, claims.Id)
+
+		if err != nil {
+			return "error occured while checking the token"
+		}
+
+		if revoked {
+			return "the token has been revoked"
+		}
+	}
+
+	if claims.Uid != "" {
+		generation, err := Revocations.TokenGeneration(ctx, claims.Uid)
+
+		if err != nil {
+			return "error occured while checking the token"
+		}
+
+		if claims.Generation < generation {
+			return "the token has been revoked"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateSecret returns a random hex string made of size random bytes , it is used for one time tokens that are sent to users
+func GenerateSecret(size int) (string, error) {
+
+	bytes := make([]byte, size)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashSecret is used to store one time tokens , unlike passwords they are long and random so a fast sha256 is enough
+func HashSecret(secret string) string {
+
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
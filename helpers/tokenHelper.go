@@ -3,16 +3,13 @@ package helpers
 import (
 	"context"
 	"fmt"
-	"go-restaurent-management-system/database"
 	"log"
-	"os"
 	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
+	"go-restaurent-management-system/repository"
+
+	jwt "github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type SignedDetails struct {
@@ -20,91 +17,142 @@ type SignedDetails struct {
 	First_name string
 	Last_name  string
 	Uid        string
+	Role       string
+	Branch_id  string
+	Token_type string
+	Generation int
 	jwt.StandardClaims
 }
 
+// the access token is sent with every request , the refresh token is only accepted by the /users/refresh endpoint to get a new pair
+// the challenge token is handed out by Login() when the password was right but the second factor is still missing
+const (
+	ACCESS_TOKEN    = "access"
+	REFRESH_TOKEN   = "refresh"
+	CHALLENGE_TOKEN = "challenge"
+)
+
 //JWTs have three main parts: Header, Payload, and Signature.
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
+func GenerateAllTokens(email string, firstName string, lastName string, uid string, role string, branchId string) (signedToken string, signedRefreshToken string, err error) {
+	// tokens are signed with the current generation of the user , revoking all sessions raises it and invalidates them
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
 
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
+	generation, err := Revocations.TokenGeneration(ctx, uid)
+
+	if err != nil {
+		return "", "", err
+	}
 
-func GenerateAllTokens(email string, firstName string, lastName string, uid string) (signedToken string, signedRefreshToken string, err error) {
 	claims := &SignedDetails{
 		Email:      email,
 		First_name: firstName,
 		Last_name:  lastName,
 		Uid:        uid,
+		Role:       role,
+		Branch_id:  branchId,
+		Token_type: ACCESS_TOKEN,
+		Generation: generation,
 		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
 		},
 	}
 
+	// the refresh token carries the user id so that /users/refresh can find the stored token , the Id (jti) makes every rotated token unique even when two are signed within the same second
 	refreshClaims := &SignedDetails{
+		Uid:        uid,
+		Token_type: REFRESH_TOKEN,
+		Generation: generation,
 		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
 		},
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
-	//jwt.SigningMethodHS256: This indicates that the JWT will be signed using the HMAC-SHA256 algorithm. HMAC (Hash-based Message Authentication Code) is a method for creating a cryptographic hash of the token's content and signing it with a secret key to ensure its integrity and authenticity.
-	//Signing: When a JWT is created and signed with this method, a secret key (or shared secret) is used to create a digital signature of the token's content. This signature is added to the JWT, ensuring that the token has not been tampered with.
-	//Verification: When someone receives a JWT and wants to verify its authenticity, they must also have access to the same secret key. They can use this key to verify the signature and confirm that the token has not been modified since it was signed.
-	//SigningMethodHS256 : is a term related to JWT (JSON Web Tokens) and is typically used in Go libraries like "github.com/dgrijalva/jwt-go" for signing and verifying JWTs using the HMAC-SHA256 algorithm. HMAC-SHA256 is a widely used cryptographic algorithm for creating and verifying digital signatures.
-	//SignedString([]byte(SECRET_KEY)): This is a method call that signs a JWT with the secret key. Here's how it works:
-
-	//The SignedString method takes the secret key as a byte slice.
+	token, err := signToken(claims)
+	if err != nil {
+		return "", "", err
+	}
 
-	//It uses a specific signing algorithm (e.g., HMAC-SHA256) and the provided secret key to create a digital signature of the JWT's content.
+	refreshToken, err := signToken(refreshClaims)
+	if err != nil {
+		return "", "", err
+	}
 
-	//The digital signature is added to the JWT, ensuring that the token's content has not been tampered with.
+	return token, refreshToken, nil
+}
 
-	//The resulting JWT, which now includes the digital signature, can be shared with other parties.
-	if err != nil {
-		log.Panic(err)
+// GenerateChallengeToken is only accepted by /users/login/2fa , it is short lived so a stolen one is of little use
+func GenerateChallengeToken(uid string) (signedToken string, err error) {
+	claims := &SignedDetails{
+		Uid:        uid,
+		Token_type: CHALLENGE_TOKEN,
+		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
+			ExpiresAt: time.Now().Local().Add(time.Minute * time.Duration(5)).Unix(),
+		},
 	}
 
-	return token, refreshToken, err
+	return signToken(claims)
 }
 
 func UpdateAllTokens(signedToken string, signedRefreshToken string, userId string) {
 
 	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
 
-	var updateObj primitive.D
+	err := users.SetTokens(ctx, userId, signedToken, signedRefreshToken)
 
-	updateObj = append(updateObj, bson.E{Key: "token", Value: signedToken})
-	updateObj = append(updateObj, bson.E{Key: "refreshToken", Value: signedRefreshToken})
+	if err != nil {
+		log.Panic(err)
+	}
 
-	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return
+}
 
-	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: Updated_at})
+// CheckUserActive returns a message when the user of a token was deactivated or removed , it is checked on every request so it takes effect immediately
+func CheckUserActive(userId string) (msg string) {
 
-	upsert := true
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
 
-	filter := bson.M{"user_id": userId}
+	found, err := users.Get(ctx, "", userId)
 
-	opts := options.UpdateOptions{
-		Upsert: &upsert,
+	if err == repository.ErrNotFound {
+		return "the user of this token no longer exists"
 	}
 
-	_, err := userCollection.UpdateOne(
-		ctx,
-		filter,
-		bson.D{
-			{Key: "$set", Value: updateObj},
-		},
-		&opts,
-	)
+	if err != nil {
+		return "error occured while checking the user"
+	}
+
+	if found.Deactivated {
+		return "the account has been deactivated"
+	}
 
+	return ""
+}
+
+// ClearTokens removes the stored token pair of a user , the refresh token that was handed out can't be exchanged anymore afterwards
+func ClearTokens(userId string) error {
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
 
-	if err != nil {
-		log.Panic(err)
-	}
+	return users.SetTokens(ctx, userId, "", "")
+}
 
-	return
+// RotateTokens replaces the stored token pair only if the stored refresh token is still the one the client presented.
+// the repository checks and writes in one step , so a refresh token can be exchanged exactly once even when two requests race with it
+func RotateTokens(signedToken string, signedRefreshToken string, userId string, usedRefreshToken string) (rotated bool, err error) {
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	return users.RotateTokens(ctx, userId, usedRefreshToken, signedToken, signedRefreshToken)
 }
 
 func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
@@ -112,19 +160,21 @@ func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&SignedDetails{},
-		func(token *jwt.Token) (interface{}, error) { //n Go, returning an interface{} type from a function is a way to return a value of unspecified type. It's a very flexible feature but should be used with caution because it can make your code less type-safe and harder to understand. It's often used in certain situations, such as when working with data of different types dynamically or when you want to create generic functions. Here are some common scenarios where you might return an interface{} type from a function:
-			return []byte(SECRET_KEY), nil // secret key is used by the server to access the claims of the json token .
-		},
+		verificationKey, // picks the public key named by the kid header , so tokens signed with a key that is being rotated out are still accepted
 	)
 
 	// if the token is invalid
 
+	if err != nil {
+		msg = err.Error()
+		return
+	}
+
 	claims, ok := token.Claims.(*SignedDetails) // Type Assertion : In Go, a type assertion is a mechanism that allows you to convert an interface value to a concrete type. Type assertions are used when you have an interface value that could potentially hold a value of a specific type, and you want to access the value as that specific type. If the conversion is not possible, a type assertion may return an error or panic.
 	// the above line checking that whether claims is of type signeddetailes
 
-	if !ok {
+	if !ok || !token.Valid {
 		msg = fmt.Sprintf("the token is invalid")
-		msg = err.Error()
 		return
 	}
 
@@ -133,7 +183,6 @@ func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
 	if claims.ExpiresAt < time.Now().Local().Unix() {
 
 		msg = fmt.Sprintf("token is expired")
-		msg = err.Error()
 		return
 
 	}
@@ -0,0 +1,81 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+// the SHA1 secret of the test vectors in appendix B of RFC 6238 , "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// the RFC lists 8 digit codes , a 6 digit code is the last 6 of them
+var rfcVectors = []struct {
+	at   int64
+	step int64
+	code string
+}{
+	{59, 1, "287082"},
+	{1111111109, 37037036, "081804"},
+	{1111111111, 37037037, "050471"},
+	{1234567890, 41152263, "005924"},
+	{2000000000, 66666666, "279037"},
+	{20000000000, 666666666, "353130"},
+}
+
+func TestValidateTOTPAcceptsTheRFCVectors(t *testing.T) {
+
+	for _, vector := range rfcVectors {
+		step, ok := ValidateTOTP(rfcSecret, vector.code, time.Unix(vector.at, 0))
+
+		if !ok || step != vector.step {
+			t.Errorf("T=%d: got step %d and %v , want step %d", vector.at, step, ok, vector.step)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+
+	vector := rfcVectors[1]
+
+	// the code of the previous and the next step is accepted , and the step it belongs to is returned
+	for _, at := range []int64{vector.at - totpPeriod, vector.at + totpPeriod} {
+		if step, ok := ValidateTOTP(rfcSecret, vector.code, time.Unix(at, 0)); !ok || step != vector.step {
+			t.Errorf("T=%d: got step %d and %v , want step %d", at, step, ok, vector.step)
+		}
+	}
+
+	for _, at := range []int64{vector.at - 2*totpPeriod, vector.at + 2*totpPeriod} {
+		if _, ok := ValidateTOTP(rfcSecret, vector.code, time.Unix(at, 0)); ok {
+			t.Errorf("T=%d: a code two steps away was accepted", at)
+		}
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+
+	at := time.Unix(rfcVectors[1].at, 0)
+
+	cases := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "081805"},
+		{"all 8 digits", rfcSecret, "07081804"},
+		{"too short", rfcSecret, "81804"},
+		{"empty", rfcSecret, ""},
+		{"other secret", "JBSWY3DPEHPK3PXP", "081804"},
+		{"broken secret", "not base32!", "081804"},
+	}
+
+	for _, c := range cases {
+		if _, ok := ValidateTOTP(c.secret, c.code, at); ok {
+			t.Errorf("%s: the code was accepted", c.name)
+		}
+	}
+
+	// spaces around the code and a lower case secret are fine
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", " 081804 ", at); !ok {
+		t.Errorf("a lower case secret and spaces around the code were refused")
+	}
+}
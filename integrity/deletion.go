@@ -0,0 +1,246 @@
+package integrity
+
+import (
+	"context"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/repository"
+	"time"
+)
+
+// Target is one document a delete reaches
+type Target struct {
+	Entity string
+	Id     string
+}
+
+// Deletion is what a delete will do before it is done , the documents the CASCADE rules take along with it
+type Deletion struct {
+	Branch_id string
+	Cascade   []Target
+}
+
+// Change is one document deleted by a cascade , the handler writes it to the audit log
+type Change struct {
+	Entity_type string
+	Entity_id   string
+	Before      map[string]interface{}
+	After       map[string]interface{}
+}
+
+// Delete deletes a document together with everything the CASCADE rules take along , in one transaction of the store.
+// deleteRoot stores the deleted document itself , it is called after the cascade and may be called again when the store retries the transaction.
+// when anything fails nothing is deleted , a RESTRICT rule fails it with a *RestrictError and the error of deleteRoot is returned as it is.
+// the changes are only returned once the transaction is stored , so the handler never writes an audit record of a delete that was taken back
+func Delete(ctx context.Context, entity string, branchId string, id string, at *time.Time, by *string, deleteRoot func(ctx context.Context) error) ([]Change, error) {
+
+	var changes []Change
+
+	err := store.Transaction(ctx, func(ctx context.Context) error {
+
+		deletion, err := PlanDelete(ctx, entity, branchId, id)
+
+		if err != nil {
+			return err
+		}
+
+		changes, err = deletion.Run(ctx, at, by)
+
+		if err != nil {
+			return err
+		}
+
+		return deleteRoot(ctx)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// PlanDelete follows the references to the document before it is deleted.
+// a RESTRICT relation with documents in it stops the delete with a *RestrictError , also when it is reached through a cascade.
+// nothing is written yet , Delete runs the plan inside a transaction
+func PlanDelete(ctx context.Context, entity string, branchId string, id string) (*Deletion, error) {
+
+	deletion := &Deletion{Branch_id: branchId}
+	visited := map[Target]bool{{Entity: entity, Id: id}: true}
+
+	if err := deletion.walk(ctx, entity, id, visited); err != nil {
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+func (d *Deletion) walk(ctx context.Context, entity string, id string, visited map[Target]bool) error {
+
+	for _, relation := range RELATIONS {
+
+		if relation.Parent != entity || rules[relation.Name] == KEEP {
+			continue
+		}
+
+		childIds, err := children(ctx, relation, d.Branch_id, id)
+
+		if err != nil {
+			return err
+		}
+
+		if len(childIds) == 0 {
+			continue
+		}
+
+		if rules[relation.Name] == RESTRICT {
+			return &RestrictError{Relation: relation.Name, Entity: entity, Child: relation.Child, Count: len(childIds)}
+		}
+
+		for _, childId := range childIds {
+
+			target := Target{Entity: relation.Child, Id: childId}
+
+			if visited[target] {
+				continue
+			}
+
+			visited[target] = true
+			d.Cascade = append(d.Cascade, target)
+
+			if err := d.walk(ctx, relation.Child, childId, visited); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// children are the ids of the documents that are not deleted and reference the parent through the relation
+func children(ctx context.Context, relation Relation, branchId string, parentId string) ([]string, error) {
+
+	ids := []string{}
+
+	switch relation.Name {
+
+	case "menu.foods":
+		foods, err := store.Foods.ListByMenu(ctx, branchId, parentId)
+		for _, food := range foods {
+			ids = append(ids, food.Food_id)
+		}
+		return ids, err
+
+	case "table.orders":
+		orders, err := store.Orders.ListByTable(ctx, branchId, parentId)
+		for _, order := range orders {
+			ids = append(ids, order.Order_id)
+		}
+		return ids, err
+
+	case "order.order_items":
+		orderItems, err := store.OrderItems.ListByOrder(ctx, branchId, parentId)
+		for _, orderItem := range orderItems {
+			ids = append(ids, orderItem.Order_item_id)
+		}
+		return ids, err
+
+	case "order.invoices":
+		invoices, err := store.Invoices.ListByOrder(ctx, branchId, parentId)
+		for _, invoice := range invoices {
+			ids = append(ids, invoice.Invoice_id)
+		}
+		return ids, err
+
+	case "food.order_items":
+		orderItems, err := store.OrderItems.ListByFood(ctx, branchId, parentId)
+		for _, orderItem := range orderItems {
+			ids = append(ids, orderItem.Order_item_id)
+		}
+		return ids, err
+	}
+
+	return ids, nil
+}
+
+// Run soft deletes the cascaded documents with the same deleted_at and deleted_by as the document they were reached from.
+// a document somebody else deleted in the meantime is skipped , the changes made before an error are returned with it.
+// Run writes the documents one by one , only the transaction of Delete makes them all or nothing
+func (d *Deletion) Run(ctx context.Context, at *time.Time, by *string) ([]Change, error) {
+
+	changes := []Change{}
+
+	for _, target := range d.Cascade {
+
+		change, err := softDelete(ctx, d.Branch_id, target, at, by)
+
+		if err == repository.ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			return changes, err
+		}
+
+		changes = append(changes, *change)
+	}
+
+	return changes, nil
+}
+
+func softDelete(ctx context.Context, branchId string, target Target, at *time.Time, by *string) (*Change, error) {
+
+	change := &Change{Entity_type: target.Entity, Entity_id: target.Id}
+
+	switch target.Entity {
+
+	case FOOD:
+		food, err := store.Foods.Get(ctx, branchId, target.Id, false)
+		if err != nil {
+			return nil, err
+		}
+		change.Before = audit.Snapshot(food)
+		food.Deleted_at, food.Deleted_by, food.Updated_at = at, by, *at
+		if err := store.Foods.Update(ctx, food); err != nil {
+			return nil, err
+		}
+		change.After = audit.Snapshot(food)
+
+	case ORDER:
+		order, err := store.Orders.Get(ctx, branchId, target.Id, false)
+		if err != nil {
+			return nil, err
+		}
+		change.Before = audit.Snapshot(order)
+		order.Deleted_at, order.Deleted_by, order.Updated_at = at, by, *at
+		if err := store.Orders.Update(ctx, order); err != nil {
+			return nil, err
+		}
+		change.After = audit.Snapshot(order)
+
+	case ORDER_ITEM:
+		orderItem, err := store.OrderItems.Get(ctx, branchId, target.Id, false)
+		if err != nil {
+			return nil, err
+		}
+		change.Before = audit.Snapshot(orderItem)
+		orderItem.Deleted_at, orderItem.Deleted_by, orderItem.Updated_at = at, by, *at
+		if err := store.OrderItems.Update(ctx, orderItem); err != nil {
+			return nil, err
+		}
+		change.After = audit.Snapshot(orderItem)
+
+	case INVOICE:
+		invoice, err := store.Invoices.Get(ctx, branchId, target.Id, false)
+		if err != nil {
+			return nil, err
+		}
+		change.Before = audit.Snapshot(invoice)
+		invoice.Deleted_at, invoice.Deleted_by, invoice.Updated_at = at, by, *at
+		if err := store.Invoices.Update(ctx, invoice); err != nil {
+			return nil, err
+		}
+		change.After = audit.Snapshot(invoice)
+	}
+
+	return change, nil
+}
@@ -0,0 +1,135 @@
+package integrity
+
+import (
+	"fmt"
+	"go-restaurent-management-system/repository"
+	"sort"
+	"strings"
+)
+
+// the entities that reference each other , the names are the entity types of the audit log
+const (
+	MENU       = "menu"
+	FOOD       = "food"
+	TABLE      = "table"
+	ORDER      = "order"
+	ORDER_ITEM = "order_item"
+	INVOICE    = "invoice"
+)
+
+// Rule says what happens to the documents that reference a document when it is deleted
+type Rule string
+
+const (
+	// RESTRICT refuses to delete a document while anything that is not deleted references it
+	RESTRICT Rule = "restrict"
+	// CASCADE deletes the referencing documents together with it
+	CASCADE Rule = "cascade"
+	// KEEP leaves the referencing documents alone , they go on pointing at the deleted document
+	KEEP Rule = "keep"
+)
+
+// Relation is one kind of reference , Name is how the configuration refers to it
+type Relation struct {
+	Name    string
+	Parent  string
+	Child   string
+	Default Rule
+}
+
+// RELATIONS are all the references that are looked at when a document is deleted.
+// foods and tables keep their orders by default , the history of what was served stays as it is
+var RELATIONS = []Relation{
+	{Name: "menu.foods", Parent: MENU, Child: FOOD, Default: RESTRICT},
+	{Name: "table.orders", Parent: TABLE, Child: ORDER, Default: KEEP},
+	{Name: "order.order_items", Parent: ORDER, Child: ORDER_ITEM, Default: CASCADE},
+	{Name: "order.invoices", Parent: ORDER, Child: INVOICE, Default: RESTRICT},
+	{Name: "food.order_items", Parent: FOOD, Child: ORDER_ITEM, Default: KEEP},
+}
+
+var (
+	store *repository.Store
+	rules map[string]Rule
+)
+
+// Init hands the integrity checks the repositories and the rules of the configuration , main() calls it once after the store is built.
+// onDelete maps relation names to rules , the relations that are not in it keep their default rule
+func Init(s *repository.Store, onDelete map[string]string) error {
+
+	configured := map[string]Rule{}
+
+	for _, relation := range RELATIONS {
+		configured[relation.Name] = relation.Default
+	}
+
+	for name, value := range onDelete {
+		if _, ok := configured[name]; !ok {
+			return fmt.Errorf("integrity: there is no relation %q , it has to be one of %s", name, relationNames())
+		}
+
+		rule := Rule(strings.ToLower(value))
+
+		if rule != RESTRICT && rule != CASCADE && rule != KEEP {
+			return fmt.Errorf("integrity: the rule of %s has to be restrict , cascade or keep", name)
+		}
+
+		configured[name] = rule
+	}
+
+	store, rules = s, configured
+
+	return nil
+}
+
+func relationNames() string {
+
+	names := []string{}
+
+	for _, relation := range RELATIONS {
+		names = append(names, relation.Name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, " , ")
+}
+
+// ReferenceError is a document that points at another document which doesn't exist , is deleted or belongs to another branch
+type ReferenceError struct {
+	Field  string
+	Entity string
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s: the %s was not found", e.Field, strings.ReplaceAll(e.Entity, "_", " "))
+}
+
+// RestrictError is a delete refused by a RESTRICT rule
+type RestrictError struct {
+	Relation string
+	Entity   string
+	Child    string
+	Count    int
+}
+
+func (e *RestrictError) Error() string {
+
+	// the relation names the children in plural , menu.foods
+	children := e.Relation[strings.Index(e.Relation, ".")+1:]
+
+	return fmt.Sprintf("the %s can't be deleted while %d %s still reference it", strings.ReplaceAll(e.Entity, "_", " "), e.Count, strings.ReplaceAll(children, "_", " "))
+}
+
+// ItemError is the error of one of the items of a new order , Index is its position in the request
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("order item %d: %s", e.Index, e.Err.Error())
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,92 @@
+package integrity
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+// the Check functions look up what a document points at before it is created or changed.
+// a reference has to be a document of the same branch that is not deleted , a *ReferenceError says which one isn't
+
+// CheckFood checks the menu of the food
+func CheckFood(ctx context.Context, food *models.Food) error {
+
+	if food.Menu_id == nil {
+		return &ReferenceError{Field: "menu_id", Entity: MENU}
+	}
+
+	_, err := store.Menus.Get(ctx, food.Branch_id, *food.Menu_id, false)
+
+	return reference(err, "menu_id", MENU)
+}
+
+// CheckOrder checks the table of the order , an order doesn't need to have one
+func CheckOrder(ctx context.Context, order *models.Order) error {
+
+	if order.Table_id == nil {
+		return nil
+	}
+
+	_, err := store.Tables.Get(ctx, order.Branch_id, *order.Table_id, false)
+
+	return reference(err, "table_id", TABLE)
+}
+
+// CheckOrderItem checks the food and the order of the item
+func CheckOrderItem(ctx context.Context, orderItem *models.OrderItem) error {
+
+	if err := checkFoodOf(ctx, orderItem); err != nil {
+		return err
+	}
+
+	_, err := store.Orders.Get(ctx, orderItem.Branch_id, orderItem.Order_id, false)
+
+	return reference(err, "order_id", ORDER)
+}
+
+// CheckNewOrder is for an order that is created together with its items , the items can't point at an order that doesn't exist yet.
+// the error of an item is an *ItemError with the position of the item
+func CheckNewOrder(ctx context.Context, order *models.Order, orderItems []models.OrderItem) error {
+
+	if err := CheckOrder(ctx, order); err != nil {
+		return err
+	}
+
+	for i := range orderItems {
+		if err := checkFoodOf(ctx, &orderItems[i]); err != nil {
+			return &ItemError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// CheckInvoice checks the order of the invoice
+func CheckInvoice(ctx context.Context, invoice *models.Invoice) error {
+
+	_, err := store.Orders.Get(ctx, invoice.Branch_id, invoice.Order_id, false)
+
+	return reference(err, "order_id", ORDER)
+}
+
+func checkFoodOf(ctx context.Context, orderItem *models.OrderItem) error {
+
+	if orderItem.Food_id == nil {
+		return &ReferenceError{Field: "food_id", Entity: FOOD}
+	}
+
+	_, err := store.Foods.Get(ctx, orderItem.Branch_id, *orderItem.Food_id, false)
+
+	return reference(err, "food_id", FOOD)
+}
+
+// reference turns the ErrNotFound of a lookup into a *ReferenceError , other errors are passed on
+func reference(err error, field string, entity string) error {
+
+	if err == repository.ErrNotFound {
+		return &ReferenceError{Field: field, Entity: entity}
+	}
+
+	return err
+}
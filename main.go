@@ -1,37 +1,143 @@
 package main
 
 import (
-	"go-restaurent-management-system/database"
+	"context"
+	"errors"
+	"go-restaurent-management-system/audit"
+	"go-restaurent-management-system/config"
+	helper "go-restaurent-management-system/helpers"
+	"go-restaurent-management-system/integrity"
 	middleware "go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/repository"
 	"go-restaurent-management-system/routes"
+	"go-restaurent-management-system/storage"
+	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
+// requests still running when the server is asked to stop get this long to finish
+const SHUTDOWN_TIMEOUT = 10 * time.Second
 
 func main() {
 
-	port := os.Getenv("PORT")
+	// the database and the port come from config.json and the environment , see config.Load()
 
-	if port == "" {
-		port = "8000"
+	cfg, err := config.Load()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the backend comes from the config and the handlers only see its repositories , memrepo.NewStore() can be put in here to run without a database
+
+	store, closeStore, err := storage.Open(cfg)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	helper.Init(store)
+	audit.Init(store.Audit)
+
+	// a misspelled relation or rule in the config stops the server , deletes shouldn't quietly fall back to the defaults
+
+	if err := integrity.Init(store, cfg.Integrity.On_delete); err != nil {
+		log.Fatal(err)
+	}
+
+	// tokens can't be signed or verified without a key , so a missing key stops the server here instead of at the first login
+
+	if err := helper.LoadKeys(); err != nil {
+		log.Fatal(err)
+	}
+
+	router, err := newRouter(store)
+
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: router,
+	}
+
+	// on SIGINT or SIGTERM the server stops taking new requests , lets the running ones finish and only then closes the database connections
+
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-stop.Done()
+
+	log.Println("shutting down")
+
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancelShutdown()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("server shutdown:", err)
+	}
+
+	if err := closeStore(ctx); err != nil {
+		log.Println("closing the database:", err)
+	}
+
+}
+
+// newRouter registers every route on a new router , the handlers use the repositories of store
+func newRouter(store *repository.Store) (*gin.Engine, error) {
+
 	router := gin.New()
+
+	// failed logins are counted per client ip , so X-Forwarded-For is only believed when it comes from one of our own proxies
+
+	var trustedProxies []string
+
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+
 	router.Use(gin.Logger()) //gin.Logger(): This is a predefined middleware provided by the gin framework. It's a logging middleware that automatically logs information about incoming requests and outgoing responses. When this middleware is used, it will log details such as the HTTP method, URL, status code, and request processing time for each request.
-	router.Use(middleware.Authentication())
 
-	routes.UserRoutes(router)
-	routes.FoodRoutes(router)
-	routes.MenuRoutes(router)
-	routes.TableRoutes(router)
-	routes.OrderRoutes(router)
-	routes.OrderItemRoutes(router)
-	routes.InvoiceRoutes(router)
+	// routes that can be used without a token
+	public := router.Group("/")
+
+	routes.HealthRoutes(public, store)
+	routes.WellKnownRoutes(public)
+	routes.AuthRoutes(public, store)
+	routes.PublicMenuRoutes(public, store)
+
+	// every other route needs a valid token
+	protected := router.Group("/")
+	protected.Use(middleware.Authentication())
 
-	router.Run(":" + port)
+	routes.UserRoutes(protected, store)
+	routes.BranchRoutes(protected, store)
+	routes.FoodRoutes(protected, store)
+	routes.MenuRoutes(protected, store)
+	routes.TableRoutes(protected, store)
+	routes.OrderRoutes(protected, store)
+	routes.OrderItemRoutes(protected, store)
+	routes.InvoiceRoutes(protected, store)
+	routes.ApiKeyRoutes(protected, store)
+	routes.AuditRoutes(protected)
 
+	return router, nil
 }
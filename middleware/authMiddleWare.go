@@ -3,35 +3,117 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	helper "go-restaurent-management-system/helpers"
 
 	"github.com/gin-gonic/gin"
 )
 
+// callers are either staff members with a JWT in the token header or machines with an API key in the Authorization header
+const (
+	AUTH_TYPE_USER    = "user"
+	AUTH_TYPE_API_KEY = "api_key"
+)
+
 func Authentication() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if authorization := c.Request.Header.Get("Authorization"); strings.HasPrefix(authorization, "ApiKey ") {
+			if !authenticateApiKey(c, strings.TrimSpace(strings.TrimPrefix(authorization, "ApiKey "))) {
+				return
+			}
+
+			c.Next()
+			return
+		}
+
 		clientToken := c.Request.Header.Get("token")
 		if clientToken == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error ": fmt.Sprintf("No authorisation Header Provided")})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("No authorisation Header Provided")})
 			c.Abort()
 			return
 		}
 
-		claims, err := helper.ValidateToken(clientToken)
+		if !authenticate(c, clientToken) {
+			return
+		}
+
+		c.Next() // In the context of web frameworks like Gin or Echo in Go, c.Next() is used to instruct the framework to continue processing the current HTTP request by calling the next middleware function or the next route handler in the chain. It allows you to delegate control to the next piece of middleware or the next handler in line.
 
-		if err != "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-			c.Abort() //In Go, c.Abort() is commonly associated with web application frameworks like Gin and Echo, and it's used to prematurely terminate the processing of a request and immediately return a response to the client without allowing further middleware functions or request handlers to execute.
+	}
+}
+
+// OptionalAuthentication is used on public routes which behave differently for logged in users , a request without a token goes through anonymously but an invalid token is still rejected
+func OptionalAuthentication() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientToken := c.Request.Header.Get("token")
+
+		if clientToken != "" && !authenticate(c, clientToken) {
 			return
 		}
 
-		c.Set("email", claims.Email)
-		c.Set("first_name", claims.First_name)
-		c.Set("last_name", claims.Last_name)
-		c.Set("uid", claims.Uid)
+		c.Next()
 
-		c.Next() // In the context of web frameworks like Gin or Echo in Go, c.Next() is used to instruct the framework to continue processing the current HTTP request by calling the next middleware function or the next route handler in the chain. It allows you to delegate control to the next piece of middleware or the next handler in line.
+	}
+}
+
+// authenticate validates the token and stores its claims in the context , the request is aborted when the token can't be used
+func authenticate(c *gin.Context, clientToken string) bool {
+
+	claims, err := helper.ValidateToken(clientToken)
+
+	if err != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
+		c.Abort() //In Go, c.Abort() is commonly associated with web application frameworks like Gin and Echo, and it's used to prematurely terminate the processing of a request and immediately return a response to the client without allowing further middleware functions or request handlers to execute.
+		return false
+	}
+
+	if claims.Token_type != helper.ACCESS_TOKEN {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "only an access token can be used here"})
+		c.Abort()
+		return false
+	}
+
+	if msg := helper.CheckRevocation(claims); msg != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+		c.Abort()
+		return false
+	}
 
+	if msg := helper.CheckUserActive(claims.Uid); msg != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+		c.Abort()
+		return false
 	}
+
+	c.Set("email", claims.Email)
+	c.Set("first_name", claims.First_name)
+	c.Set("last_name", claims.Last_name)
+	c.Set("uid", claims.Uid)
+	c.Set("role", claims.Role)
+	c.Set("branch_id", claims.Branch_id)
+	c.Set("token_id", claims.Id)
+	c.Set("token_expires_at", claims.ExpiresAt)
+	c.Set("auth_type", AUTH_TYPE_USER)
+
+	return true
+}
+
+// authenticateApiKey stores the key , its scopes and its branch in the context , the key id is used as uid so that the caller can still be identified
+func authenticateApiKey(c *gin.Context, key string) bool {
+
+	apiKey, msg := helper.ValidateApiKey(key)
+
+	if msg != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+		c.Abort()
+		return false
+	}
+
+	c.Set("uid", apiKey.Api_key_id)
+	c.Set("scopes", apiKey.Scopes)
+	c.Set("branch_id", apiKey.Branch_id)
+	c.Set("auth_type", AUTH_TYPE_API_KEY)
+
+	return true
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"go-restaurent-management-system/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authorize only lets staff members through , and only those whose role stored in the context by Authentication() is one of the allowed roles.
+// without any role every logged in user is allowed , API keys are always refused.
+// it has to be registered after Authentication() , routes declare it per route , for example incomingRoutes.POST("/users/2fa/enroll", middleware.Authorize(models.ROLE_ADMIN), controller.EnrollTwoFactor())
+func Authorize(allowedRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") == AUTH_TYPE_USER && hasRole(c, allowedRoles) {
+			c.Next()
+			return
+		}
+
+		forbidden(c)
+	}
+}
+
+// Permit is used on the routes that machines may call as well , API keys need the scope and staff members need one of the roles (any role when none is given)
+func Permit(scope string, allowedRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.GetString("auth_type") {
+		case AUTH_TYPE_USER:
+			if hasRole(c, allowedRoles) {
+				c.Next()
+				return
+			}
+		case AUTH_TYPE_API_KEY:
+			for _, granted := range c.GetStringSlice("scopes") {
+				if granted == scope {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		forbidden(c)
+	}
+}
+
+// HeadOffice only lets admins through who don't belong to a branch , they are the ones managing the branches
+func HeadOffice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsHeadOffice(c) {
+			c.Next()
+			return
+		}
+
+		forbidden(c)
+	}
+}
+
+// IsHeadOffice tells whether the caller is an admin of the head office , only they may read the data of every branch
+func IsHeadOffice(c *gin.Context) bool {
+	return c.GetString("auth_type") == AUTH_TYPE_USER && c.GetString("role") == models.ROLE_ADMIN && c.GetString("branch_id") == ""
+}
+
+func hasRole(c *gin.Context, allowedRoles []string) bool {
+
+	if len(allowedRoles) == 0 {
+		return true
+	}
+
+	role := c.GetString("role")
+
+	for _, allowedRole := range allowedRoles {
+		if role == allowedRole {
+			return true
+		}
+	}
+
+	return false
+}
+
+func forbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "you are not allowed to access this resource"})
+	c.Abort()
+}
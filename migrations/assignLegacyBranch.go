@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LEGACY_BRANCH_ID is the branch the documents from before branches are given , cmd/migrate reads it from the environment.
+// it is only needed when there are such documents , the branch has to be created before
+var LEGACY_BRANCH_ID = ""
+
+// the collections whose documents belong to a branch , written out for the same reason as legacyCollectionNames
+var branchCollections = []string{"foods", "menus", "tables", "orders", "order_items", "invoices"}
+
+// a document written before branches has no branch_id , the API never writes an empty one
+var withoutBranch = bson.M{"$or": []bson.M{{"branch_id": bson.M{"$exists": false}}, {"branch_id": nil}, {"branch_id": ""}}}
+
+// legacyBranchFilter matches the documents of collection that have no branch yet.
+// an admin without a branch is the head office and keeps it , every other user without a branch is from before branches
+func legacyBranchFilter(collection string) bson.M {
+
+	if collection == "users" {
+		return bson.M{"$and": []bson.M{withoutBranch, {"role": bson.M{"$ne": "ADMIN"}}}}
+	}
+
+	return withoutBranch
+}
+
+// assignLegacyBranch gives the foods , menus , tables , orders , order items and invoices written before branches ,
+// and the staff that signed up back then , the branch of LEGACY_BRANCH_ID. without it they are only visible to the head office.
+//
+// Down does nothing , the code from before branches never read branch_id
+var assignLegacyBranch = Migration{
+	Version: 2,
+	Name:    "assign_legacy_branch",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+
+		collections := append(append([]string{}, branchCollections...), "users")
+		legacy := map[string]int64{}
+		total := int64(0)
+
+		for _, collection := range collections {
+			count, err := db.Collection(collection).CountDocuments(ctx, legacyBranchFilter(collection))
+
+			if err != nil {
+				return err
+			}
+
+			legacy[collection] = count
+			total += count
+		}
+
+		if total == 0 {
+			return nil
+		}
+
+		if LEGACY_BRANCH_ID == "" {
+			return fmt.Errorf("%d documents have no branch , set LEGACY_BRANCH_ID to the branch_id of the branch they belong to", total)
+		}
+
+		branches, err := db.Collection("branches").CountDocuments(ctx, bson.M{"branch_id": LEGACY_BRANCH_ID})
+
+		if err != nil {
+			return err
+		}
+
+		if branches == 0 {
+			return fmt.Errorf("the branch %s of LEGACY_BRANCH_ID doesn't exist , create it first", LEGACY_BRANCH_ID)
+		}
+
+		for _, collection := range collections {
+			if legacy[collection] == 0 {
+				continue
+			}
+
+			_, err := db.Collection(collection).UpdateMany(ctx, legacyBranchFilter(collection), bson.D{{Key: "$set", Value: bson.D{{Key: "branch_id", Value: LEGACY_BRANCH_ID}}}})
+
+			if err != nil {
+				return fmt.Errorf("%s: %w", collection, err)
+			}
+		}
+
+		return nil
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+		return nil
+	},
+}
@@ -0,0 +1,256 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// the versions that were applied are kept in this collection , one document per version
+const SCHEMA_MIGRATIONS_COLLECTION = "schema_migrations"
+
+// Migration changes the database from the version before it to Version , Down undoes it again.
+// a migration is not run in a transaction , Up has to be safe to run again when it stopped half way
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// MIGRATIONS lists every migration , a new one is added at the end with the next version
+var MIGRATIONS = []Migration{
+	normaliseLegacyData,
+	assignLegacyBranch,
+}
+
+// AppliedMigration is the record of a migration in SCHEMA_MIGRATIONS_COLLECTION
+type AppliedMigration struct {
+	Version    int       `bson:"version"`
+	Name       string    `bson:"name"`
+	Applied_at time.Time `bson:"applied_at"`
+}
+
+// Status is a migration together with the time it was applied , Applied_at is nil while it is pending
+type Status struct {
+	Version    int
+	Name       string
+	Applied_at *time.Time
+}
+
+func sorted() []Migration {
+
+	migrations := append([]Migration{}, MIGRATIONS...)
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations
+}
+
+func applied(ctx context.Context, db *mongo.Database) (map[int]AppliedMigration, error) {
+
+	cursor, err := db.Collection(SCHEMA_MIGRATIONS_COLLECTION).Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var records []AppliedMigration
+
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]AppliedMigration{}
+
+	for _, record := range records {
+		byVersion[record.Version] = record
+	}
+
+	return byVersion, nil
+}
+
+// Statuses lists every known migration in the order they are applied
+func Statuses(ctx context.Context, db *mongo.Database) ([]Status, error) {
+
+	done, err := applied(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+
+	for _, migration := range sorted() {
+		status := Status{Version: migration.Version, Name: migration.Name}
+
+		if record, ok := done[migration.Version]; ok {
+			appliedAt := record.Applied_at
+			status.Applied_at = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Up applies the pending migrations up to and including target , a target of zero applies all of them.
+// it stops at the first migration that fails , the ones before it stay applied
+func Up(ctx context.Context, db *mongo.Database, target int) ([]Migration, error) {
+
+	done, err := applied(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ran := []Migration{}
+
+	for _, migration := range sorted() {
+		if target > 0 && migration.Version > target {
+			break
+		}
+
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+
+		if err := migration.Up(ctx, db); err != nil {
+			return ran, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		record := AppliedMigration{Version: migration.Version, Name: migration.Name, Applied_at: time.Now().UTC()}
+		upsert := true
+
+		_, err := db.Collection(SCHEMA_MIGRATIONS_COLLECTION).UpdateOne(
+			ctx,
+			bson.M{"version": migration.Version},
+			bson.D{{Key: "$set", Value: record}},
+			&options.UpdateOptions{Upsert: &upsert},
+		)
+
+		if err != nil {
+			return ran, fmt.Errorf("recording migration %d: %w", migration.Version, err)
+		}
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// Down rolls back the last steps applied migrations , newest first
+func Down(ctx context.Context, db *mongo.Database, steps int) ([]Migration, error) {
+
+	done, err := applied(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := sorted()
+	ran := []Migration{}
+
+	for i := len(migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+		migration := migrations[i]
+
+		if _, ok := done[migration.Version]; !ok {
+			continue
+		}
+
+		if err := migration.Down(ctx, db); err != nil {
+			return ran, fmt.Errorf("rolling back migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+
+		if _, err := db.Collection(SCHEMA_MIGRATIONS_COLLECTION).DeleteOne(ctx, bson.M{"version": migration.Version}); err != nil {
+			return ran, fmt.Errorf("removing the record of migration %d: %w", migration.Version, err)
+		}
+
+		ran = append(ran, migration)
+	}
+
+	return ran, nil
+}
+
+// renameCollection renames from to to , nothing happens when from doesn't exist (anymore).
+// an empty to , like the one the server creates with its indexes on startup , is replaced , a to with documents in it stops the migration
+func renameCollection(ctx context.Context, db *mongo.Database, from string, to string) error {
+
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": bson.M{"$in": []string{from, to}}})
+
+	if err != nil {
+		return err
+	}
+
+	fromExists, toExists := false, false
+
+	for _, name := range names {
+		fromExists = fromExists || name == from
+		toExists = toExists || name == to
+	}
+
+	if !fromExists {
+		return nil
+	}
+
+	if toExists {
+		count, err := db.Collection(to).CountDocuments(ctx, bson.M{})
+
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return fmt.Errorf("both %s and %s hold documents , they have to be merged by hand", from, to)
+		}
+	}
+
+	return db.Client().Database("admin").RunCommand(ctx, bson.D{
+		{Key: "renameCollection", Value: db.Name() + "." + from},
+		{Key: "to", Value: db.Name() + "." + to},
+		{Key: "dropTarget", Value: toExists},
+	}).Err()
+}
+
+// moveDocuments copies the documents of from that move accepts into to and deletes them from from afterwards.
+// a document that was copied before is replaced , so a move that stopped half way can run again
+func moveDocuments(ctx context.Context, db *mongo.Database, from string, to string, move func(doc bson.M) bool) error {
+
+	cursor, err := db.Collection(from).Find(ctx, bson.M{})
+
+	if err != nil {
+		return err
+	}
+
+	var docs []bson.M
+
+	if err := cursor.All(ctx, &docs); err != nil {
+		return err
+	}
+
+	upsert := true
+
+	for _, doc := range docs {
+		if !move(doc) {
+			continue
+		}
+
+		if _, err := db.Collection(to).ReplaceOne(ctx, bson.M{"_id": doc["_id"]}, doc, &options.ReplaceOptions{Upsert: &upsert}); err != nil {
+			return fmt.Errorf("moving %v from %s to %s: %w", doc["_id"], from, to, err)
+		}
+
+		if _, err := db.Collection(from).DeleteOne(ctx, bson.M{"_id": doc["_id"]}); err != nil {
+			return fmt.Errorf("moving %v from %s to %s: %w", doc["_id"], from, to, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// the collections were named by whoever added them first , they are all plural snake case now.
+// the names are written out here instead of using the constants of mongorepo , a migration has to keep doing what it did when it was written
+var legacyCollectionNames = []struct {
+	from string
+	to   string
+}{
+	{"menu", "menus"},
+	{"user", "users"},
+	{"orderItem", "order_items"},
+}
+
+// the first foodController opened the user collection for its foods , so the foods and the users were stored side by side in it
+const LEGACY_FOOD_COLLECTION = "user"
+
+// isLegacyFood tells the foods in the legacy user collection apart from the users , only a food has a food_id
+func isLegacyFood(doc bson.M) bool {
+
+	_, ok := doc["food_id"]
+	return ok
+}
+
+// normaliseLegacyData cleans up what the first versions of the handlers left behind :
+//
+//   - the refresh token of a user was written as refreshToken by UpdateAllTokens and as refreshtoken by SignUp (the model had no bson tag) , it is refresh_token now
+//   - UpdateOrder stored the new table of an order in a field called menu instead of table_id
+//   - the foods are moved out of the user collection into foods
+//   - the menu , user and orderItem collections get the plural snake case names the others already have
+//
+// Down renames the collections back and moves the foods into the user collection again ,
+// the old field names were never read by anything and aren't brought back
+var normaliseLegacyData = Migration{
+	Version: 1,
+	Name:    "normalise_legacy_data",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+
+		// the foods have to leave before the users are renamed , the unique email and phone indexes of users can't be built over them
+
+		if err := moveDocuments(ctx, db, LEGACY_FOOD_COLLECTION, "foods", isLegacyFood); err != nil {
+			return err
+		}
+
+		for _, names := range legacyCollectionNames {
+			if err := renameCollection(ctx, db, names.from, names.to); err != nil {
+				return err
+			}
+		}
+
+		users := db.Collection("users")
+
+		// a refresh_token that is already there wins , it is the one the current code writes
+
+		for _, legacyField := range []string{"refreshToken", "refreshtoken"} {
+			_, err := users.UpdateMany(
+				ctx,
+				bson.M{legacyField: bson.M{"$exists": true}, "refresh_token": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "refresh_token", Value: "$" + legacyField}}}}},
+			)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := users.UpdateMany(
+			ctx,
+			bson.M{"$or": []bson.M{{"refreshToken": bson.M{"$exists": true}}, {"refreshtoken": bson.M{"$exists": true}}}},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "refreshToken", Value: ""}, {Key: "refreshtoken", Value: ""}}}},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		orders := db.Collection("orders")
+
+		// menu only becomes the table of the order when the order has none , a table_id set since then is newer
+
+		_, err = orders.UpdateMany(
+			ctx,
+			bson.M{"menu": bson.M{"$type": "string"}, "$or": []bson.M{{"table_id": bson.M{"$exists": false}}, {"table_id": nil}}},
+			mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "table_id", Value: "$menu"}}}}},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = orders.UpdateMany(ctx, bson.M{"menu": bson.M{"$exists": true}}, bson.D{{Key: "$unset", Value: bson.D{{Key: "menu", Value: ""}}}})
+
+		return err
+	},
+	Down: func(ctx context.Context, db *mongo.Database) error {
+
+		for i := len(legacyCollectionNames) - 1; i >= 0; i-- {
+			if err := renameCollection(ctx, db, legacyCollectionNames[i].to, legacyCollectionNames[i].from); err != nil {
+				return err
+			}
+		}
+
+		return moveDocuments(ctx, db, "foods", LEGACY_FOOD_COLLECTION, func(doc bson.M) bool { return true })
+	},
+}
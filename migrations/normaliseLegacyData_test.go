@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// baselineFood and baselineUser are the models of the first version , they had no bson tags
+// so the driver stored every field under its lower cased name
+
+type baselineFood struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Name       *string
+	Price      *float64
+	Food_image *string
+	Created_at time.Time
+	Updated_at time.Time
+	Food_id    string
+	Menu_id    *string
+}
+
+type baselineUser struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	First_name   *string
+	Last_name    *string
+	Password     *string
+	Email        *string
+	Avatar       *string
+	Phone        *string
+	Token        *string
+	RefreshToken *string
+	Created_at   time.Time
+	Updated_at   time.Time
+	User_id      string
+}
+
+func toDocument(t *testing.T, record interface{}) bson.M {
+
+	raw, err := bson.Marshal(record)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc bson.M
+
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// the user collection of the baseline , as CreateFood and SignUp left it
+func baselineUserCollection(t *testing.T) (docs []bson.M, foods int) {
+
+	text := func(s string) *string { return &s }
+	price := 9.5
+	now := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, name := range []string{"Pizza", "Burger", "Salad"} {
+		id := primitive.NewObjectID()
+		docs = append(docs, toDocument(t, baselineFood{ID: id, Name: text(name), Price: &price, Food_image: text("img"), Created_at: now, Updated_at: now, Food_id: id.Hex(), Menu_id: text("menu")}))
+
+		// the users are interleaved with the foods , like signups and new foods came in
+		userId := primitive.NewObjectID()
+		docs = append(docs, toDocument(t, baselineUser{ID: userId, First_name: text("Ann"), Last_name: text("Lee"), Password: text("hash"), Email: text(name + "@x.io"), Phone: text(fmt.Sprint(i)), RefreshToken: text("refresh"), Created_at: now, Updated_at: now, User_id: userId.Hex()}))
+	}
+
+	return docs, 3
+}
+
+func TestIsLegacyFoodSplitsTheBaselineUserCollection(t *testing.T) {
+
+	docs, wantFoods := baselineUserCollection(t)
+
+	foods, users := 0, 0
+	emails := map[interface{}]bool{}
+
+	for _, doc := range docs {
+		if isLegacyFood(doc) {
+			foods++
+
+			if _, ok := doc["user_id"]; ok {
+				t.Errorf("a user was taken for a food: %v", doc)
+			}
+
+			continue
+		}
+
+		users++
+
+		// what stays behind becomes users , the unique email index needs an email on every document
+		email, ok := doc["email"]
+
+		if !ok || email == nil {
+			t.Errorf("a document without an email stays in users: %v", doc)
+		}
+
+		if emails[email] {
+			t.Errorf("the email %v is there twice", email)
+		}
+
+		emails[email] = true
+	}
+
+	if foods != wantFoods || users != len(docs)-wantFoods {
+		t.Errorf("got %d foods and %d users , want %d and %d", foods, users, wantFoods, len(docs)-wantFoods)
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scopes an API key can be granted , every route that machines may call declares the scope it needs
+var API_KEY_SCOPES = []string{
+	"foods:read", "foods:write",
+	"menus:read", "menus:write",
+	"tables:read", "tables:write",
+	"orders:read", "orders:write",
+	"order_items:read", "order_items:write",
+	"invoices:read", "invoices:write",
+}
+
+// ApiKey is the credential of a machine such as a kitchen display , only the sha256 hash of the key is stored
+type ApiKey struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Scopes       []string           `json:"scopes" validate:"required,min=1"`
+	Prefix       string             `json:"prefix"`
+	Key_hash     string             `json:"-"`
+	Created_by   string             `json:"created_by"`
+	Last_used_at *time.Time         `json:"last_used_at"`
+	Revoked_at   *time.Time         `json:"revoked_at"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+	Api_key_id   string             `json:"api_key_id"`
+	Branch_id    string             `json:"branch_id"`
+}
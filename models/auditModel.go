@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEvent is one entry of the append-only audit log , entries are only ever inserted.
+// Before and After are snapshots of the document around a change , Before is empty for a create
+type AuditEvent struct {
+	ID          primitive.ObjectID     `bson:"_id"`
+	Audit_id    string                 `json:"audit_id"`
+	Actor_id    string                 `json:"actor_id"`
+	Actor_type  string                 `json:"actor_type"`
+	Action      string                 `json:"action"`
+	Entity_type string                 `json:"entity_type"`
+	Entity_id   string                 `json:"entity_id"`
+	Before      map[string]interface{} `json:"before,omitempty"`
+	After       map[string]interface{} `json:"after,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+	Branch_id   string                 `json:"branch_id,omitempty"`
+	Created_at  time.Time              `json:"created_at"`
+}
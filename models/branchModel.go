@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Branch is one location of the restaurant , foods , menus , tables , orders , order items and invoices all belong to exactly one branch
+type Branch struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Name       *string            `json:"name" validate:"required,min=2,max=100"`
+	Address    *string            `json:"address"`
+	Phone      *string            `json:"phone"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	Version    int                `json:"version"`
+	Branch_id  string             `json:"branch_id"`
+}
@@ -6,6 +6,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// the sizes an order item is ordered in , see OrderItem.Quantity
+const (
+	SIZE_SMALL  = "S"
+	SIZE_MEDIUM = "M"
+	SIZE_LARGE  = "L"
+)
+
 type Food struct {
 	ID         primitive.ObjectID `bson:"_id"`
 	Name       *string            `json:"name" validate:"required"`
@@ -13,6 +20,47 @@ type Food struct {
 	Food_image *string            `json:"food_image" validate:"required"`
 	Created_at time.Time          `json:"created_at"`
 	Updated_at time.Time          `json:"updated_at"`
+	Version    int                `json:"version"`
+	Deleted_at *time.Time         `json:"deleted_at"`
+	Deleted_by *string            `json:"deleted_by"`
 	Food_id    string             `json:"food_id"`
 	Menu_id    *string            `json:"menu_id" validate:"required"`
+	Branch_id  string             `json:"branch_id"`
+	// Sizes changes the price or the availability of single sizes , a size that isn't listed is sold for Price
+	Sizes map[string]SizePrice `json:"sizes"`
+	// Modifier_groups are the choices a guest makes when ordering the food , see modifierModel.go
+	Modifier_groups []ModifierGroup `json:"modifier_groups"`
+}
+
+// SizePrice is one size of a food , a missing price is the Price of the food and a missing availability means it can be ordered
+type SizePrice struct {
+	Price     *float64 `json:"price"`
+	Available *bool    `json:"available"`
+}
+
+// SIZES are all sizes , in the order they are listed
+var SIZES = []string{SIZE_SMALL, SIZE_MEDIUM, SIZE_LARGE}
+
+// PriceOf is the unit price of the food in a size , false when the food can't be ordered in that size
+func (food *Food) PriceOf(size string) (float64, bool) {
+
+	if food.Price == nil {
+		return 0, false
+	}
+
+	sizePrice, ok := food.Sizes[size]
+
+	if !ok {
+		return *food.Price, true
+	}
+
+	if sizePrice.Available != nil && !*sizePrice.Available {
+		return 0, false
+	}
+
+	if sizePrice.Price != nil {
+		return *sizePrice.Price, true
+	}
+
+	return *food.Price, true
 }
@@ -15,4 +15,8 @@ type Invoice struct {
 	Payment_due_date time.Time          `json:"payment_due_date"`
 	Created_at       time.Time          `json:"created_at"`
 	Updated_at       time.Time          `json:"updated_at"`
+	Version          int                `json:"version"`
+	Deleted_at       *time.Time         `json:"deleted_at"`
+	Deleted_by       *string            `json:"deleted_by"`
+	Branch_id        string             `json:"branch_id"`
 }
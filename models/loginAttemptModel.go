@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt counts the failed logins of one email or one client ip , the key is "email:<email>" or "ip:<ip>".
+// the password reset requests are counted the same way under "reset-email:<email>" and "reset-ip:<ip>"
+type LoginAttempt struct {
+	Key             string    `json:"key"`
+	Failures        int       `json:"failures"`
+	Last_failure_at time.Time `json:"last_failure_at"`
+	Locked_until    time.Time `json:"locked_until"`
+}
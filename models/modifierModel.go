@@ -0,0 +1,193 @@
+package models
+
+import (
+	"fmt"
+)
+
+// options can open groups of their own , like the sauce of the fries that were picked as the side. the nesting stops at this depth
+const MAX_MODIFIER_DEPTH = 3
+
+// ModifierGroup is one choice a guest makes for a food , like the side , the extras or what to leave out.
+// a required group needs at least one selection , Max_selections of 0 allows every option of the group
+type ModifierGroup struct {
+	Group_id       string           `json:"group_id"`
+	Name           string           `json:"name"`
+	Required       bool             `json:"required"`
+	Min_selections int              `json:"min_selections"`
+	Max_selections int              `json:"max_selections"`
+	Options        []ModifierOption `json:"options"`
+}
+
+// ModifierOption is one option of a group , Price_delta is added to the unit price and is negative for something that is left out
+type ModifierOption struct {
+	Option_id   string  `json:"option_id"`
+	Name        string  `json:"name"`
+	Price_delta float64 `json:"price_delta"`
+	// a missing availability means the option can be picked
+	Available *bool `json:"available"`
+	// Groups are asked for when this option is picked
+	Groups []ModifierGroup `json:"groups"`
+}
+
+// SelectedModifier is an option picked for an order item , the client sends the ids and the name and the price delta are copied from the food ,
+// so the item keeps what it was sold with when the food changes later. Modifiers are the picks of the groups of the option
+type SelectedModifier struct {
+	Group_id    string             `json:"group_id"`
+	Option_id   string             `json:"option_id"`
+	Name        string             `json:"name"`
+	Price_delta float64            `json:"price_delta"`
+	Modifiers   []SelectedModifier `json:"modifiers"`
+}
+
+// minimum is the number of selections the group needs , a required group needs at least one
+func (group *ModifierGroup) minimum() int {
+
+	if group.Required && group.Min_selections < 1 {
+		return 1
+	}
+
+	return group.Min_selections
+}
+
+// ValidateModifierGroups checks the modifier groups of a food before they are stored
+func ValidateModifierGroups(groups []ModifierGroup) error {
+	return validateModifierGroups(groups, 1)
+}
+
+func validateModifierGroups(groups []ModifierGroup, depth int) error {
+
+	// the options of the deepest groups have no groups of their own , only groups below them are too deep
+	if len(groups) > 0 && depth > MAX_MODIFIER_DEPTH {
+		return fmt.Errorf("modifier groups can only be nested %d deep", MAX_MODIFIER_DEPTH)
+	}
+
+	groupIds := map[string]bool{}
+
+	for _, group := range groups {
+
+		if group.Group_id == "" || group.Name == "" {
+			return fmt.Errorf("every modifier group needs a group_id and a name")
+		}
+
+		if groupIds[group.Group_id] {
+			return fmt.Errorf("the modifier group %q is there twice", group.Group_id)
+		}
+
+		groupIds[group.Group_id] = true
+
+		if len(group.Options) == 0 {
+			return fmt.Errorf("%s: a modifier group needs at least one option", group.Group_id)
+		}
+
+		if group.Min_selections < 0 || group.Max_selections < 0 {
+			return fmt.Errorf("%s: min_selections and max_selections can't be negative", group.Group_id)
+		}
+
+		if group.minimum() > len(group.Options) || (group.Max_selections > 0 && group.minimum() > group.Max_selections) {
+			return fmt.Errorf("%s: min_selections can't be more than max_selections or the number of options", group.Group_id)
+		}
+
+		optionIds := map[string]bool{}
+
+		for _, option := range group.Options {
+
+			if option.Option_id == "" || option.Name == "" {
+				return fmt.Errorf("%s: every option needs an option_id and a name", group.Group_id)
+			}
+
+			if optionIds[option.Option_id] {
+				return fmt.Errorf("%s: the option %q is there twice", group.Group_id, option.Option_id)
+			}
+
+			optionIds[option.Option_id] = true
+
+			if err := validateModifierGroups(option.Groups, depth+1); err != nil {
+				return fmt.Errorf("%s.%s: %w", group.Group_id, option.Option_id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveModifiers checks the selections of an order item against the modifier groups of its food.
+// it returns the selections with the names and price deltas of the food and the sum of all price deltas , nested ones included
+func ResolveModifiers(groups []ModifierGroup, selected []SelectedModifier) ([]SelectedModifier, float64, error) {
+
+	resolved := []SelectedModifier{}
+	total := 0.0
+	counts := map[string]int{}
+	picked := map[string]bool{}
+
+	for _, selection := range selected {
+
+		group := findGroup(groups, selection.Group_id)
+
+		if group == nil {
+			return nil, 0, fmt.Errorf("the food has no modifier group %q", selection.Group_id)
+		}
+
+		option := findOption(group, selection.Option_id)
+
+		if option == nil {
+			return nil, 0, fmt.Errorf("%s: there is no option %q", group.Name, selection.Option_id)
+		}
+
+		if option.Available != nil && !*option.Available {
+			return nil, 0, fmt.Errorf("%s: %s is not available", group.Name, option.Name)
+		}
+
+		if picked[group.Group_id+"/"+option.Option_id] {
+			return nil, 0, fmt.Errorf("%s: %s is picked twice", group.Name, option.Name)
+		}
+
+		picked[group.Group_id+"/"+option.Option_id] = true
+		counts[group.Group_id]++
+
+		nested, nestedTotal, err := ResolveModifiers(option.Groups, selection.Modifiers)
+
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", option.Name, err)
+		}
+
+		resolved = append(resolved, SelectedModifier{Group_id: group.Group_id, Option_id: option.Option_id, Name: option.Name, Price_delta: option.Price_delta, Modifiers: nested})
+		total += option.Price_delta + nestedTotal
+	}
+
+	for i := range groups {
+
+		group := &groups[i]
+
+		if counts[group.Group_id] < group.minimum() {
+			return nil, 0, fmt.Errorf("%s: pick at least %d", group.Name, group.minimum())
+		}
+
+		if group.Max_selections > 0 && counts[group.Group_id] > group.Max_selections {
+			return nil, 0, fmt.Errorf("%s: pick at most %d", group.Name, group.Max_selections)
+		}
+	}
+
+	return resolved, total, nil
+}
+
+func findGroup(groups []ModifierGroup, groupId string) *ModifierGroup {
+
+	for i := range groups {
+		if groups[i].Group_id == groupId {
+			return &groups[i]
+		}
+	}
+
+	return nil
+}
+
+func findOption(group *ModifierGroup, optionId string) *ModifierOption {
+
+	for i := range group.Options {
+		if group.Options[i].Option_id == optionId {
+			return &group.Options[i]
+		}
+	}
+
+	return nil
+}
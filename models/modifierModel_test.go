@@ -0,0 +1,149 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// burgerGroups are the choices of a burger , the side is required and the fries ask for a sauce
+func burgerGroups() []ModifierGroup {
+
+	unavailable := false
+
+	return []ModifierGroup{
+		{Group_id: "side", Name: "Side", Required: true, Max_selections: 1, Options: []ModifierOption{
+			{Option_id: "fries", Name: "Fries", Groups: []ModifierGroup{
+				{Group_id: "sauce", Name: "Sauce", Required: true, Max_selections: 1, Options: []ModifierOption{
+					{Option_id: "ketchup", Name: "Ketchup"},
+					{Option_id: "aioli", Name: "Aioli", Price_delta: 0.5},
+				}},
+			}},
+			{Option_id: "salad", Name: "Salad", Price_delta: 1},
+		}},
+		{Group_id: "extras", Name: "Extras", Max_selections: 2, Options: []ModifierOption{
+			{Option_id: "cheese", Name: "Cheese", Price_delta: 1},
+			{Option_id: "bacon", Name: "Bacon", Price_delta: 2},
+			{Option_id: "egg", Name: "Egg", Price_delta: 1.5, Available: &unavailable},
+		}},
+		{Group_id: "without", Name: "Without", Options: []ModifierOption{
+			{Option_id: "onion", Name: "Onion", Price_delta: -0.25},
+		}},
+	}
+}
+
+// nestedGroups are depth groups , every option of a group opens the next one
+func nestedGroups(depth int) []ModifierGroup {
+
+	if depth == 0 {
+		return nil
+	}
+
+	return []ModifierGroup{{Group_id: "level", Name: "Level", Options: []ModifierOption{{Option_id: "next", Name: "Next", Groups: nestedGroups(depth - 1)}}}}
+}
+
+func TestValidateModifierGroups(t *testing.T) {
+
+	if err := ValidateModifierGroups(burgerGroups()); err != nil {
+		t.Fatalf("the groups of the burger were refused: %v", err)
+	}
+
+	if err := ValidateModifierGroups(nestedGroups(MAX_MODIFIER_DEPTH)); err != nil {
+		t.Errorf("groups nested %d deep were refused: %v", MAX_MODIFIER_DEPTH, err)
+	}
+
+	if err := ValidateModifierGroups(nestedGroups(MAX_MODIFIER_DEPTH + 1)); err == nil {
+		t.Errorf("groups nested %d deep were accepted", MAX_MODIFIER_DEPTH+1)
+	}
+
+	option := ModifierOption{Option_id: "cheese", Name: "Cheese"}
+
+	cases := []struct {
+		name  string
+		group ModifierGroup
+		err   string
+	}{
+		{"group without an id", ModifierGroup{Name: "Extras", Options: []ModifierOption{option}}, "needs a group_id and a name"},
+		{"group without options", ModifierGroup{Group_id: "extras", Name: "Extras"}, "at least one option"},
+		{"option without a name", ModifierGroup{Group_id: "extras", Name: "Extras", Options: []ModifierOption{{Option_id: "cheese"}}}, "needs an option_id and a name"},
+		{"option twice", ModifierGroup{Group_id: "extras", Name: "Extras", Options: []ModifierOption{option, option}}, "is there twice"},
+		{"negative maximum", ModifierGroup{Group_id: "extras", Name: "Extras", Max_selections: -1, Options: []ModifierOption{option}}, "can't be negative"},
+		{"minimum above the maximum", ModifierGroup{Group_id: "extras", Name: "Extras", Min_selections: 2, Max_selections: 1, Options: []ModifierOption{option, {Option_id: "bacon", Name: "Bacon"}}}, "can't be more than"},
+		{"minimum above the options", ModifierGroup{Group_id: "extras", Name: "Extras", Min_selections: 2, Options: []ModifierOption{option}}, "can't be more than"},
+	}
+
+	for _, c := range cases {
+		if err := ValidateModifierGroups([]ModifierGroup{c.group}); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: got the error %v , want %q", c.name, err, c.err)
+		}
+	}
+
+	group := ModifierGroup{Group_id: "extras", Name: "Extras", Options: []ModifierOption{option}}
+
+	if err := ValidateModifierGroups([]ModifierGroup{group, group}); err == nil || !strings.Contains(err.Error(), "is there twice") {
+		t.Errorf("a group that is there twice got the error %v", err)
+	}
+}
+
+func TestResolveModifiers(t *testing.T) {
+
+	resolved, total, err := ResolveModifiers(burgerGroups(), []SelectedModifier{
+		{Group_id: "side", Option_id: "fries", Name: "Free fries", Price_delta: -5, Modifiers: []SelectedModifier{{Group_id: "sauce", Option_id: "aioli"}}},
+		{Group_id: "extras", Option_id: "cheese"},
+		{Group_id: "extras", Option_id: "bacon"},
+		{Group_id: "without", Option_id: "onion"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the names and price deltas are the ones of the food , not of the client
+	if total != 0.5+1+2-0.25 {
+		t.Errorf("got the price deltas %v , want 3.25", total)
+	}
+
+	if len(resolved) != 4 || resolved[0].Name != "Fries" || resolved[0].Price_delta != 0 || len(resolved[0].Modifiers) != 1 || resolved[0].Modifiers[0].Name != "Aioli" {
+		t.Errorf("the selections were resolved to %+v", resolved)
+	}
+
+	side := SelectedModifier{Group_id: "side", Option_id: "salad"}
+	fries := SelectedModifier{Group_id: "side", Option_id: "fries", Modifiers: []SelectedModifier{{Group_id: "sauce", Option_id: "ketchup"}}}
+
+	cases := []struct {
+		name     string
+		selected []SelectedModifier
+		err      string
+	}{
+		{"required group left out", nil, "Side: pick at least 1"},
+		{"two of a group with a maximum of one", []SelectedModifier{side, fries}, "Side: pick at most 1"},
+		{"nested required group left out", []SelectedModifier{{Group_id: "side", Option_id: "fries"}}, "Fries: Sauce: pick at least 1"},
+		{"option that isn't available", []SelectedModifier{side, {Group_id: "extras", Option_id: "egg"}}, "Egg is not available"},
+		{"option picked twice", []SelectedModifier{side, {Group_id: "extras", Option_id: "cheese"}, {Group_id: "extras", Option_id: "cheese"}}, "Cheese is picked twice"},
+		{"group of another food", []SelectedModifier{side, {Group_id: "dressing", Option_id: "vinaigrette"}}, "no modifier group"},
+		{"option of another group", []SelectedModifier{{Group_id: "side", Option_id: "cheese"}}, "no option"},
+		{"nested option of the parent group", []SelectedModifier{{Group_id: "side", Option_id: "fries", Modifiers: []SelectedModifier{{Group_id: "side", Option_id: "salad"}}}}, "no modifier group"},
+	}
+
+	for _, c := range cases {
+		if _, _, err := ResolveModifiers(burgerGroups(), c.selected); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: got the error %v , want %q", c.name, err, c.err)
+		}
+	}
+
+	// the maximum counts the picks of one group , a minimum above zero is enforced without required
+	groups := []ModifierGroup{{Group_id: "toppings", Name: "Toppings", Min_selections: 2, Max_selections: 2, Options: []ModifierOption{
+		{Option_id: "olives", Name: "Olives"}, {Option_id: "capers", Name: "Capers"}, {Option_id: "basil", Name: "Basil"},
+	}}}
+
+	if _, _, err := ResolveModifiers(groups, []SelectedModifier{{Group_id: "toppings", Option_id: "olives"}}); err == nil || !strings.Contains(err.Error(), "pick at least 2") {
+		t.Errorf("one topping of at least two got the error %v", err)
+	}
+
+	if _, _, err := ResolveModifiers(groups, []SelectedModifier{{Group_id: "toppings", Option_id: "olives"}, {Group_id: "toppings", Option_id: "capers"}, {Group_id: "toppings", Option_id: "basil"}}); err == nil || !strings.Contains(err.Error(), "pick at most 2") {
+		t.Errorf("three toppings of at most two got the error %v", err)
+	}
+
+	if _, _, err := ResolveModifiers(groups, []SelectedModifier{{Group_id: "toppings", Option_id: "olives"}, {Group_id: "toppings", Option_id: "basil"}}); err != nil {
+		t.Errorf("two toppings were refused: %v", err)
+	}
+}
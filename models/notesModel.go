@@ -6,11 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Note is not stored by any backend , there is no route that reads or writes notes.
+// it gets a repository , a mongodb collection and a sql table once something uses it
 type Note struct {
 	ID         primitive.ObjectID `bson:"_id"`
 	Text       string             `json:"text"`
 	Title      string             `json:"title"`
 	Created_at time.Time          `json:"created_at"`
 	Updated_at time.Time          `json:"updated_at"`
+	Version    int                `json:"version"`
 	Note_id    string             `json:"note_id"`
 }
@@ -11,8 +11,14 @@ type OrderItem struct {
 	Quantity      *string            `json:"quantity" validate:"required,eq=S|eq=M|eq=L"` //s- small , m- medium . L -large
 	Created_at    time.Time          `json:"created_at" `
 	Updated_at    time.Time          `json:"updated_at" `
+	Version       int                `json:"version"`
+	Deleted_at    *time.Time         `json:"deleted_at"`
+	Deleted_by    *string            `json:"deleted_by"`
 	Food_id       *string            `json:"food_id" validate:"required"`
 	Order_item_id string             `json:"order_item_id"`
 	Order_id      string             `json:"order_id"`
-	Unit_price    *float64           `json:"unit_price" validate:"required"`
+	Unit_price    *float64           `json:"unit_price"` // the price of the food in the size of Quantity with the price deltas of the Modifiers , it is set when the item is created and not by the client
+	Branch_id     string             `json:"branch_id"`
+	// Modifiers are the options picked from the modifier groups of the food , their price deltas are part of Unit_price
+	Modifiers []SelectedModifier `json:"modifiers"`
 }
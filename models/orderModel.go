@@ -11,6 +11,10 @@ type Order struct {
 	Order_date time.Time          `json:"order_date" validate:"required"`
 	Created_at time.Time          `json:"created_at" `
 	Updated_at time.Time          `json:"updated_at" `
+	Version    int                `json:"version"`
+	Deleted_at *time.Time         `json:"deleted_at"`
+	Deleted_by *string            `json:"deleted_by"`
 	Order_id   string             `json:"order_id"`
 	Table_id   *string            `json:"table_id"`
+	Branch_id  string             `json:"branch_id"`
 }
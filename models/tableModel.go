@@ -12,5 +12,9 @@ type Table struct {
 	Table_number     *int               `json:"table_number" validate:"required"`
 	Created_at       time.Time          `json:"created_at" `
 	Updated_at       time.Time          `json:"updated_at" `
+	Version          int                `json:"version"`
+	Deleted_at       *time.Time         `json:"deleted_at"`
+	Deleted_by       *string            `json:"deleted_by"`
 	Table_id         string             `json:"table_id"`
+	Branch_id        string             `json:"branch_id"`
 }
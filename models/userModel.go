@@ -6,6 +6,15 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// roles a member of staff can hold , every route in the routes package declares which of these are allowed to call it
+const (
+	ROLE_ADMIN   = "ADMIN"
+	ROLE_MANAGER = "MANAGER"
+	ROLE_WAITER  = "WAITER"
+	ROLE_CHEF    = "CHEF"
+	ROLE_CASHIER = "CASHIER"
+)
+
 type User struct {
 	ID           primitive.ObjectID `bson:"_id"`
 	First_name   *string            `json:"first_name" validate:"required,min=2,max=100"`
@@ -14,9 +23,21 @@ type User struct {
 	Email        *string            `json:"email" validate:"email,required"`
 	Avatar       *string            `json:"avatar"`
 	Phone        *string            `json:"phone" validate:"required"`
+	Role         *string            `json:"role" validate:"required,eq=ADMIN|eq=MANAGER|eq=WAITER|eq=CHEF|eq=CASHIER"`
 	Token        *string            `json:"token"`
-	RefreshToken *string            `json:"refresh_token"`
-	Created_at   time.Time          `json:"created_at"`
-	Updated_at   time.Time          `json:"updated_at"`
-	User_id      string             `json:"user_id"`
+	RefreshToken *string            `json:"refresh_token" bson:"refresh_token"`
+	// the branch the member of staff works at , an admin without a branch belongs to the head office and can read the data of every branch
+	Branch_id *string `json:"branch_id"`
+	// deactivated users can't log in and their tokens stop working immediately , the document is kept for the history
+	Deactivated    bool       `json:"deactivated"`
+	Deactivated_at *time.Time `json:"deactivated_at"`
+	// two factor authentication , the secret and the hashed recovery codes never leave the server
+	Two_factor_enabled bool      `json:"two_factor_enabled"`
+	Totp_secret        *string   `json:"-"`
+	Totp_last_step     int64     `json:"-"`
+	Recovery_codes     []string  `json:"-"`
+	Created_at         time.Time `json:"created_at"`
+	Updated_at         time.Time `json:"updated_at"`
+	Version            int       `json:"version"`
+	User_id            string    `json:"user_id"`
 }
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"context"
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier sends messages to users , the SMTP notifier is used in production and the outbox notifier writes the messages into files for local testing
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New picks the notifier from the MAIL_DRIVER environment variable , smtp or outbox (the default)
+func New() Notifier {
+
+	from := os.Getenv("MAIL_FROM")
+
+	if from == "" {
+		from = "no-reply@restaurent.local"
+	}
+
+	if os.Getenv("MAIL_DRIVER") == "smtp" {
+		return &SMTPNotifier{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	dir := os.Getenv("MAIL_OUTBOX_DIR")
+
+	if dir == "" {
+		dir = "outbox"
+	}
+
+	return &OutboxNotifier{Dir: dir, From: from}
+}
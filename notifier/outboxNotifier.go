@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OutboxNotifier writes every message into its own .eml file instead of sending it , developers read the reset links from there
+type OutboxNotifier struct {
+	Dir  string
+	From string
+}
+
+func (n *OutboxNotifier) Send(ctx context.Context, message Message) error {
+
+	if err := os.MkdirAll(n.Dir, 0o755); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+
+	return os.WriteFile(filepath.Join(n.Dir, name), formatMessage(n.From, message), 0o600)
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPNotifier struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, message Message) error {
+
+	if n.Host == "" {
+		return fmt.Errorf("SMTP_HOST is not configured")
+	}
+
+	port := n.Port
+
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+
+	// servers used for local relays often don't need authentication
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	return smtp.SendMail(n.Host+":"+port, auth, n.From, []string{message.To}, formatMessage(n.From, message))
+}
+
+// formatMessage builds a plain text mail with the headers every server expects
+func formatMessage(from string, message Message) []byte {
+
+	var builder strings.Builder
+
+	builder.WriteString("From: " + from + "\r\n")
+	builder.WriteString("To: " + message.To + "\r\n")
+	builder.WriteString("Subject: " + message.Subject + "\r\n")
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(message.Body)
+
+	return []byte(builder.String())
+}
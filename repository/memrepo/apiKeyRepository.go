@@ -0,0 +1,55 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"time"
+)
+
+type apiKeyRepository struct {
+	rows *table[models.ApiKey]
+}
+
+func (r apiKeyRepository) List(ctx context.Context, branchId string) ([]models.ApiKey, error) {
+	return r.rows.list(branchId, nil), nil
+}
+
+func (r apiKeyRepository) Get(ctx context.Context, branchId string, apiKeyId string) (*models.ApiKey, error) {
+	return r.rows.get(branchId, apiKeyId)
+}
+
+func (r apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.ApiKey, error) {
+	return r.rows.find(func(apiKey models.ApiKey) bool {
+		return apiKey.Key_hash == keyHash
+	})
+}
+
+func (r apiKeyRepository) Create(ctx context.Context, apiKey *models.ApiKey) error {
+	return r.rows.insert(ctx, apiKey.Api_key_id, *apiKey)
+}
+
+func (r apiKeyRepository) Revoke(ctx context.Context, branchId string, apiKeyId string, at time.Time) (bool, error) {
+
+	revoked, err := r.rows.modify(ctx, apiKeyId, func(apiKey *models.ApiKey) bool {
+		if !r.rows.inBranch(*apiKey, branchId) || apiKey.Revoked_at != nil {
+			return false
+		}
+
+		apiKey.Revoked_at = &at
+		apiKey.Updated_at = at
+
+		return true
+	})
+
+	return revoked, ignoreNotFound(err)
+}
+
+func (r apiKeyRepository) Touch(ctx context.Context, apiKeyId string, at time.Time) error {
+
+	_, err := r.rows.modify(ctx, apiKeyId, func(apiKey *models.ApiKey) bool {
+		apiKey.Last_used_at = &at
+		return true
+	})
+
+	return ignoreNotFound(err)
+}
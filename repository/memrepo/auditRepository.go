@@ -0,0 +1,38 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type auditRepository struct {
+	rows *table[models.AuditEvent]
+}
+
+func (r auditRepository) Insert(ctx context.Context, event *models.AuditEvent) error {
+	return r.rows.insert(ctx, event.Audit_id, *event)
+}
+
+func (r auditRepository) Find(ctx context.Context, filter repository.AuditFilter, skip int64, limit int64) ([]models.AuditEvent, int64, error) {
+
+	matching := r.rows.list(filter.Branch_id, func(event models.AuditEvent) bool {
+		return (filter.Entity_type == "" || event.Entity_type == filter.Entity_type) &&
+			(filter.Entity_id == "" || event.Entity_id == filter.Entity_id) &&
+			(filter.Actor_id == "" || event.Actor_id == filter.Actor_id) &&
+			(filter.Action == "" || event.Action == filter.Action) &&
+			(filter.From == nil || !event.Created_at.Before(*filter.From)) &&
+			(filter.To == nil || !event.Created_at.After(*filter.To))
+	})
+
+	total := int64(len(matching))
+
+	// the events are kept in the order they were inserted , newest first is that order reversed
+	events := make([]models.AuditEvent, 0, len(matching))
+
+	for i := len(matching) - 1 - int(skip); i >= 0 && (limit <= 0 || int64(len(events)) < limit); i-- {
+		events = append(events, matching[i])
+	}
+
+	return events, total, nil
+}
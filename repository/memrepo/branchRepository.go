@@ -0,0 +1,26 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+type branchRepository struct {
+	rows *table[models.Branch]
+}
+
+func (r branchRepository) List(ctx context.Context) ([]models.Branch, error) {
+	return r.rows.list("", nil), nil
+}
+
+func (r branchRepository) Get(ctx context.Context, branchId string) (*models.Branch, error) {
+	return r.rows.get("", branchId)
+}
+
+func (r branchRepository) Create(ctx context.Context, branch *models.Branch) error {
+	return r.rows.insert(ctx, branch.Branch_id, *branch)
+}
+
+func (r branchRepository) Update(ctx context.Context, branch *models.Branch) error {
+	return r.rows.replace(ctx, branch.Branch_id, branch, &branch.Version, func(stored models.Branch) int { return stored.Version })
+}
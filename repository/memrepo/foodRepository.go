@@ -0,0 +1,45 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type foodRepository struct {
+	rows *table[models.Food]
+}
+
+func (r foodRepository) List(ctx context.Context, branchId string, skip int64, limit int64, includeDeleted bool) ([]models.Food, int64, error) {
+
+	foods, total := r.rows.page(branchId, func(food models.Food) bool {
+		return includeDeleted || food.Deleted_at == nil
+	}, skip, limit)
+
+	return foods, total, nil
+}
+
+func (r foodRepository) Get(ctx context.Context, branchId string, foodId string, includeDeleted bool) (*models.Food, error) {
+
+	food, err := r.rows.get(branchId, foodId)
+
+	if err == nil && !includeDeleted && food.Deleted_at != nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return food, err
+}
+
+func (r foodRepository) ListByMenu(ctx context.Context, branchId string, menuId string) ([]models.Food, error) {
+	return r.rows.list(branchId, func(food models.Food) bool {
+		return food.Menu_id != nil && *food.Menu_id == menuId && food.Deleted_at == nil
+	}), nil
+}
+
+func (r foodRepository) Create(ctx context.Context, food *models.Food) error {
+	return r.rows.insert(ctx, food.Food_id, *food)
+}
+
+func (r foodRepository) Update(ctx context.Context, food *models.Food) error {
+	return r.rows.replace(ctx, food.Food_id, food, &food.Version, func(stored models.Food) int { return stored.Version })
+}
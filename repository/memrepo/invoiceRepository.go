@@ -0,0 +1,42 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type invoiceRepository struct {
+	rows *table[models.Invoice]
+}
+
+func (r invoiceRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Invoice, error) {
+	return r.rows.list(branchId, func(invoice models.Invoice) bool {
+		return includeDeleted || invoice.Deleted_at == nil
+	}), nil
+}
+
+func (r invoiceRepository) Get(ctx context.Context, branchId string, invoiceId string, includeDeleted bool) (*models.Invoice, error) {
+
+	invoice, err := r.rows.get(branchId, invoiceId)
+
+	if err == nil && !includeDeleted && invoice.Deleted_at != nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return invoice, err
+}
+
+func (r invoiceRepository) ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.Invoice, error) {
+	return r.rows.list(branchId, func(invoice models.Invoice) bool {
+		return invoice.Order_id == orderId && invoice.Deleted_at == nil
+	}), nil
+}
+
+func (r invoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	return r.rows.insert(ctx, invoice.Invoice_id, *invoice)
+}
+
+func (r invoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	return r.rows.replace(ctx, invoice.Invoice_id, invoice, &invoice.Version, func(stored models.Invoice) int { return stored.Version })
+}
@@ -0,0 +1,59 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"time"
+)
+
+type loginAttemptRepository struct {
+	rows *table[models.LoginAttempt]
+}
+
+func (r loginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	return r.rows.get("", key)
+}
+
+func (r loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+
+	var attempt models.LoginAttempt
+
+	r.rows.mu.Lock()
+	defer r.rows.mu.Unlock()
+
+	if stored, ok := r.rows.rows[key]; ok {
+		attempt = stored
+	} else {
+		attempt = models.LoginAttempt{Key: key}
+		r.rows.ids = append(r.rows.ids, key)
+	}
+
+	// a failure long after the previous one starts counting from zero again
+	if attempt.Last_failure_at.Before(at.Add(-window)) {
+		attempt.Failures = 0
+	}
+
+	attempt.Failures++
+	attempt.Last_failure_at = at
+
+	r.rows.rows[key] = attempt
+
+	return &attempt, nil
+}
+
+func (r loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+
+	_, err := r.rows.modify(ctx, key, func(attempt *models.LoginAttempt) bool {
+		attempt.Locked_until = until
+		return true
+	})
+
+	return ignoreNotFound(err)
+}
+
+func (r loginAttemptRepository) Delete(ctx context.Context, key string) error {
+
+	r.rows.delete(ctx, key)
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type menuRepository struct {
+	rows *table[models.Menu]
+}
+
+func (r menuRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Menu, error) {
+	return r.rows.list(branchId, func(menu models.Menu) bool {
+		return includeDeleted || menu.Deleted_at == nil
+	}), nil
+}
+
+func (r menuRepository) Get(ctx context.Context, branchId string, menuId string, includeDeleted bool) (*models.Menu, error) {
+
+	menu, err := r.rows.get(branchId, menuId)
+
+	if err == nil && !includeDeleted && menu.Deleted_at != nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return menu, err
+}
+
+func (r menuRepository) Create(ctx context.Context, menu *models.Menu) error {
+	return r.rows.insert(ctx, menu.Menu_id, *menu)
+}
+
+func (r menuRepository) Update(ctx context.Context, menu *models.Menu) error {
+	return r.rows.replace(ctx, menu.Menu_id, menu, &menu.Version, func(stored models.Menu) int { return stored.Version })
+}
@@ -0,0 +1,113 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+// orderItemRepository reads the foods , orders and tables of the same store for ItemsByOrder
+type orderItemRepository struct {
+	rows   *table[models.OrderItem]
+	foods  *table[models.Food]
+	orders *table[models.Order]
+	tables *table[models.Table]
+}
+
+func (r orderItemRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.OrderItem, error) {
+	return r.rows.list(branchId, func(orderItem models.OrderItem) bool {
+		return includeDeleted || orderItem.Deleted_at == nil
+	}), nil
+}
+
+func (r orderItemRepository) Get(ctx context.Context, branchId string, orderItemId string, includeDeleted bool) (*models.OrderItem, error) {
+
+	orderItem, err := r.rows.get(branchId, orderItemId)
+
+	if err == nil && !includeDeleted && orderItem.Deleted_at != nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return orderItem, err
+}
+
+func (r orderItemRepository) ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.OrderItem, error) {
+	return r.rows.list(branchId, func(orderItem models.OrderItem) bool {
+		return orderItem.Order_id == orderId && orderItem.Deleted_at == nil
+	}), nil
+}
+
+func (r orderItemRepository) ListByFood(ctx context.Context, branchId string, foodId string) ([]models.OrderItem, error) {
+	return r.rows.list(branchId, func(orderItem models.OrderItem) bool {
+		return orderItem.Food_id != nil && *orderItem.Food_id == foodId && orderItem.Deleted_at == nil
+	}), nil
+}
+
+func (r orderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+
+	for _, orderItem := range orderItems {
+		if err := r.rows.insert(ctx, orderItem.Order_item_id, orderItem); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r orderItemRepository) Update(ctx context.Context, orderItem *models.OrderItem) error {
+	return r.rows.replace(ctx, orderItem.Order_item_id, orderItem, &orderItem.Version, func(stored models.OrderItem) int { return stored.Version })
+}
+
+// ItemsByOrder joins like the lookups of the mongodb repository , the foods , the order and the table are looked up across branches
+func (r orderItemRepository) ItemsByOrder(ctx context.Context, branchId string, orderId string) (*repository.OrderSummary, error) {
+
+	orderItems := r.rows.list(branchId, func(orderItem models.OrderItem) bool {
+		return orderItem.Order_id == orderId && orderItem.Deleted_at == nil
+	})
+
+	var table *models.Table
+
+	if order, err := r.orders.get("", orderId); err == nil && order.Table_id != nil {
+		table, _ = r.tables.get("", *order.Table_id)
+	}
+
+	items := make([]repository.OrderSummaryItem, 0, len(orderItems))
+
+	for _, orderItem := range orderItems {
+		item := repository.OrderSummaryItem{
+			Order_item_id: orderItem.Order_item_id,
+			Order_id:      orderItem.Order_id,
+			Food_id:       orderItem.Food_id,
+			Quantity:      orderItem.Quantity,
+			Modifiers:     orderItem.Modifiers,
+		}
+
+		if orderItem.Food_id != nil {
+			if food, err := r.foods.get("", *orderItem.Food_id); err == nil {
+				item.Food_name = food.Name
+				item.Food_image = food.Food_image
+				item.Price = food.Price
+			}
+		}
+
+		// the unit price of the item is the price of its size , the price of the food is only there for items without one
+
+		if orderItem.Unit_price != nil {
+			item.Price = orderItem.Unit_price
+		}
+
+		if item.Price != nil {
+			item.Amount = *item.Price
+		}
+
+		if table != nil {
+			tableId := table.Table_id
+			item.Table_id = &tableId
+			item.Table_number = table.Table_number
+		}
+
+		items = append(items, item)
+	}
+
+	return repository.Summarize(orderId, items)
+}
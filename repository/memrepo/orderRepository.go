@@ -0,0 +1,76 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+// orderRepository shares the table of the order items with orderItemRepository , CreateWithItems writes to both
+type orderRepository struct {
+	rows  *table[models.Order]
+	items *table[models.OrderItem]
+}
+
+func (r orderRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Order, error) {
+	return r.rows.list(branchId, func(order models.Order) bool {
+		return includeDeleted || order.Deleted_at == nil
+	}), nil
+}
+
+func (r orderRepository) Get(ctx context.Context, branchId string, orderId string, includeDeleted bool) (*models.Order, error) {
+
+	order, err := r.rows.get(branchId, orderId)
+
+	if err == nil && !includeDeleted && order.Deleted_at != nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return order, err
+}
+
+func (r orderRepository) ListByTable(ctx context.Context, branchId string, tableId string) ([]models.Order, error) {
+	return r.rows.list(branchId, func(order models.Order) bool {
+		return order.Table_id != nil && *order.Table_id == tableId && order.Deleted_at == nil
+	}), nil
+}
+
+func (r orderRepository) Create(ctx context.Context, order *models.Order) error {
+	return r.rows.insert(ctx, order.Order_id, *order)
+}
+
+// CreateWithItems holds the locks of both tables , every row is checked before the first one is stored
+func (r orderRepository) CreateWithItems(ctx context.Context, order *models.Order, orderItems []models.OrderItem) error {
+
+	r.rows.mu.Lock()
+	defer r.rows.mu.Unlock()
+
+	r.items.mu.Lock()
+	defer r.items.mu.Unlock()
+
+	if !r.rows.free(order.Order_id, *order) {
+		return repository.ErrDuplicate
+	}
+
+	seen := map[string]bool{}
+
+	for _, orderItem := range orderItems {
+		if seen[orderItem.Order_item_id] || !r.items.free(orderItem.Order_item_id, orderItem) {
+			return repository.ErrDuplicate
+		}
+
+		seen[orderItem.Order_item_id] = true
+	}
+
+	r.rows.put(ctx, order.Order_id, *order)
+
+	for _, orderItem := range orderItems {
+		r.items.put(ctx, orderItem.Order_item_id, orderItem)
+	}
+
+	return nil
+}
+
+func (r orderRepository) Update(ctx context.Context, order *models.Order) error {
+	return r.rows.replace(ctx, order.Order_id, order, &order.Version, func(stored models.Order) int { return stored.Version })
+}
@@ -0,0 +1,63 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"sync"
+	"time"
+)
+
+// passwordResetRepository looks tokens up by their hash , so it keeps a slice instead of a table keyed by id
+type passwordResetRepository struct {
+	mu     *sync.Mutex
+	resets *[]models.PasswordReset
+}
+
+func (r passwordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	*r.resets = append(*r.resets, *reset)
+
+	return nil
+}
+
+func (r passwordResetRepository) InvalidateAll(ctx context.Context, userId string, at time.Time) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range *r.resets {
+		reset := &(*r.resets)[i]
+
+		if reset.User_id == userId && reset.Used_at == nil {
+			used := at
+			reset.Used_at = &used
+		}
+	}
+
+	return nil
+}
+
+func (r passwordResetRepository) Redeem(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range *r.resets {
+		reset := &(*r.resets)[i]
+
+		if reset.Token_hash == tokenHash && reset.Used_at == nil && reset.Expires_at.After(at) {
+			used := at
+			reset.Used_at = &used
+
+			redeemed := *reset
+
+			return &redeemed, nil
+		}
+	}
+
+	return nil, repository.ErrNotFound
+}
@@ -0,0 +1,51 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type revocationRepository struct {
+	mu               *sync.RWMutex
+	revokedTokens    map[string]time.Time
+	tokenGenerations map[string]int
+}
+
+func (r revocationRepository) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revokedTokens[tokenId] = expiresAt
+
+	return nil
+}
+
+func (r revocationRepository) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, revoked := r.revokedTokens[tokenId]
+
+	return revoked, nil
+}
+
+func (r revocationRepository) TokenGeneration(ctx context.Context, userId string) (int, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.tokenGenerations[userId], nil
+}
+
+func (r revocationRepository) RevokeAllTokens(ctx context.Context, userId string) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokenGenerations[userId]++
+
+	return nil
+}
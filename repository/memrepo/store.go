@@ -0,0 +1,80 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"sync"
+	"time"
+)
+
+// NewStore builds repositories that keep everything in memory , nothing survives a restart.
+// every repository is safe to use from many requests at once , which makes the store good for development and for trying out the handlers without a database
+func NewStore() *repository.Store {
+
+	foods := newTable(func(food models.Food) string { return food.Branch_id })
+	tables := newTable(func(table models.Table) string { return table.Branch_id }).unique(func(table models.Table) []string {
+		if table.Table_number == nil {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s/%d", table.Branch_id, *table.Table_number)}
+	})
+	orders := newTable(func(order models.Order) string { return order.Branch_id })
+	orderItems := newTable(func(orderItem models.OrderItem) string { return orderItem.Branch_id })
+
+	return &repository.Store{
+		Branches: branchRepository{newTable[models.Branch](nil)},
+		Foods:    foodRepository{foods},
+		Menus:    menuRepository{newTable(func(menu models.Menu) string { return menu.Branch_id })},
+		Tables:   tableRepository{tables},
+		Orders:   orderRepository{rows: orders, items: orderItems},
+		OrderItems: orderItemRepository{
+			rows:   orderItems,
+			foods:  foods,
+			orders: orders,
+			tables: tables,
+		},
+		Invoices: invoiceRepository{newTable(func(invoice models.Invoice) string { return invoice.Branch_id })},
+		Users: userRepository{newTable(func(user models.User) string {
+			if user.Branch_id == nil {
+				return ""
+			}
+			return *user.Branch_id
+		}).unique(func(user models.User) []string {
+			keys := []string{}
+			if user.Email != nil {
+				keys = append(keys, "email:"+*user.Email)
+			}
+			if user.Phone != nil {
+				keys = append(keys, "phone:"+*user.Phone)
+			}
+			return keys
+		})},
+		ApiKeys: apiKeyRepository{newTable(func(apiKey models.ApiKey) string { return apiKey.Branch_id }).unique(func(apiKey models.ApiKey) []string {
+			return []string{apiKey.Key_hash}
+		})},
+		PasswordResets: passwordResetRepository{mu: &sync.Mutex{}, resets: &[]models.PasswordReset{}},
+		LoginAttempts:  loginAttemptRepository{newTable[models.LoginAttempt](nil)},
+		Revocations: revocationRepository{
+			mu:               &sync.RWMutex{},
+			revokedTokens:    map[string]time.Time{},
+			tokenGenerations: map[string]int{},
+		},
+		Audit:       auditRepository{newTable(func(event models.AuditEvent) string { return event.Branch_id })},
+		Transaction: transaction(&sync.Mutex{}),
+		Ping: func(ctx context.Context) error {
+			return nil
+		},
+	}
+}
+
+// ignoreNotFound is for the conditional updates , a missing row is the same as a condition that didn't hold
+func ignoreNotFound(err error) error {
+
+	if err == repository.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,324 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/repository"
+	"sync"
+)
+
+// table keeps the rows of one entity in memory , in the order they were created. rows are stored and handed out as copies ,
+// a caller changing a returned entity doesn't change the table until it calls Update
+type table[T any] struct {
+	mu       sync.RWMutex
+	ids      []string
+	rows     map[string]T
+	branchOf func(row T) string
+	// uniqueKeys lists the values of a row no other row may have , like the unique indexes of mongorepo. empty keys are not checked
+	uniqueKeys func(row T) []string
+}
+
+func newTable[T any](branchOf func(row T) string) *table[T] {
+	return &table[T]{rows: map[string]T{}, branchOf: branchOf}
+}
+
+// unique sets the keys that have to be unique across the table
+func (t *table[T]) unique(uniqueKeys func(row T) []string) *table[T] {
+	t.uniqueKeys = uniqueKeys
+	return t
+}
+
+// conflicts is true when another row already has one of the unique keys of row , the table has to be locked
+func (t *table[T]) conflicts(id string, row T) bool {
+
+	if t.uniqueKeys == nil {
+		return false
+	}
+
+	taken := map[string]bool{}
+
+	for storedId, stored := range t.rows {
+		if storedId == id {
+			continue
+		}
+
+		for _, key := range t.uniqueKeys(stored) {
+			taken[key] = true
+		}
+	}
+
+	for _, key := range t.uniqueKeys(row) {
+		if key != "" && taken[key] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inBranch is true for every row when the branch id is empty , like database.InBranch
+func (t *table[T]) inBranch(row T, branchId string) bool {
+	return branchId == "" || t.branchOf == nil || t.branchOf(row) == branchId
+}
+
+func (t *table[T]) list(branchId string, match func(row T) bool) []T {
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	rows := []T{}
+
+	for _, id := range t.ids {
+		row := t.rows[id]
+
+		if t.inBranch(row, branchId) && (match == nil || match(row)) {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// page works like list , a limit of zero returns every row after skip
+func (t *table[T]) page(branchId string, match func(row T) bool, skip int64, limit int64) ([]T, int64) {
+
+	rows := t.list(branchId, match)
+	total := int64(len(rows))
+
+	if skip > total {
+		skip = total
+	}
+
+	rows = rows[skip:]
+
+	if limit > 0 && limit < int64(len(rows)) {
+		rows = rows[:limit]
+	}
+
+	return rows, total
+}
+
+func (t *table[T]) get(branchId string, id string) (*T, error) {
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	row, ok := t.rows[id]
+
+	if !ok || !t.inBranch(row, branchId) {
+		return nil, repository.ErrNotFound
+	}
+
+	return &row, nil
+}
+
+// find returns the first row matching , in the order of creation
+func (t *table[T]) find(match func(row T) bool) (*T, error) {
+
+	rows := t.list("", match)
+
+	if len(rows) == 0 {
+		return nil, repository.ErrNotFound
+	}
+
+	return &rows[0], nil
+}
+
+func (t *table[T]) insert(ctx context.Context, id string, row T) error {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.free(id, row) {
+		return repository.ErrDuplicate
+	}
+
+	t.put(ctx, id, row)
+
+	return nil
+}
+
+// free is true when row can be stored under id , the table has to be locked
+func (t *table[T]) free(id string, row T) bool {
+
+	if _, ok := t.rows[id]; ok {
+		return false
+	}
+
+	return !t.conflicts(id, row)
+}
+
+// put stores a new row without checking it , the table has to be locked and free has to be true
+func (t *table[T]) put(ctx context.Context, id string, row T) {
+
+	t.ids = append(t.ids, id)
+	t.rows[id] = row
+
+	t.undo(ctx, func() {
+		t.remove(id)
+	})
+}
+
+// replace works like the one of mongorepo , it only stores row while storedVersion of the stored row is still *version
+func (t *table[T]) replace(ctx context.Context, id string, row *T, version *int, storedVersion func(stored T) int) error {
+
+	expected := *version
+	*version = expected + 1
+
+	changed := false
+
+	_, err := t.modify(ctx, id, func(stored *T) bool {
+		if storedVersion(*stored) != expected {
+			return false
+		}
+
+		*stored = *row
+		changed = true
+
+		return true
+	})
+
+	if err == nil && !changed {
+		err = repository.ErrVersionConflict
+	}
+
+	if err != nil {
+		*version = expected
+	}
+
+	return err
+}
+
+// modify hands a copy of the row to change while the table is locked , the copy is only stored when change returns true.
+// it is how the conditional updates of the mongodb repositories are done in memory
+func (t *table[T]) modify(ctx context.Context, id string, change func(row *T) bool) (bool, error) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.rows[id]
+
+	if !ok {
+		return false, repository.ErrNotFound
+	}
+
+	row := previous
+
+	if !change(&row) {
+		return false, nil
+	}
+
+	if t.conflicts(id, row) {
+		return false, repository.ErrDuplicate
+	}
+
+	t.rows[id] = row
+
+	t.undo(ctx, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if _, ok := t.rows[id]; ok {
+			t.rows[id] = previous
+		}
+	})
+
+	return true, nil
+}
+
+func (t *table[T]) delete(ctx context.Context, id string) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, ok := t.rows[id]
+
+	if !ok {
+		return
+	}
+
+	position := t.removeLocked(id)
+
+	t.undo(ctx, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		if _, ok := t.rows[id]; ok {
+			return
+		}
+
+		t.ids = append(t.ids[:position], append([]string{id}, t.ids[position:]...)...)
+		t.rows[id] = previous
+	})
+}
+
+func (t *table[T]) remove(id string) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(id)
+}
+
+// removeLocked takes the row out of the table and returns where it was in the order of creation , the table has to be locked
+func (t *table[T]) removeLocked(id string) int {
+
+	delete(t.rows, id)
+
+	for i, stored := range t.ids {
+		if stored == id {
+			t.ids = append(t.ids[:i], t.ids[i+1:]...)
+			return i
+		}
+	}
+
+	return len(t.ids)
+}
+
+// undoKey marks the context of a transaction , its value is the undoLog the tables write down how to take back each of their changes in
+type undoKey struct{}
+
+type undoLog struct {
+	mu    sync.Mutex
+	steps []func()
+}
+
+// undo writes step down when ctx belongs to a transaction , step locks the table itself because it only runs after the change returned
+func (t *table[T]) undo(ctx context.Context, step func()) {
+
+	log, ok := ctx.Value(undoKey{}).(*undoLog)
+
+	if !ok {
+		return
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	log.steps = append(log.steps, step)
+}
+
+// transaction runs fn with an undoLog in its context and takes back what fn changed when it fails , the newest change first.
+// transactions run one after another but they are not isolated , requests outside of them see and may change the rows in between
+func transaction(mu *sync.Mutex) func(ctx context.Context, fn func(ctx context.Context) error) error {
+	return func(ctx context.Context, fn func(ctx context.Context) error) error {
+
+		if _, ok := ctx.Value(undoKey{}).(*undoLog); ok {
+			return fn(ctx)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		log := &undoLog{}
+
+		err := fn(context.WithValue(ctx, undoKey{}, log))
+
+		if err != nil {
+			for i := len(log.steps) - 1; i >= 0; i-- {
+				log.steps[i]()
+			}
+		}
+
+		return err
+	}
+}
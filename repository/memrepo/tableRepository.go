@@ -0,0 +1,36 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type tableRepository struct {
+	rows *table[models.Table]
+}
+
+func (r tableRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Table, error) {
+	return r.rows.list(branchId, func(table models.Table) bool {
+		return includeDeleted || table.Deleted_at == nil
+	}), nil
+}
+
+func (r tableRepository) Get(ctx context.Context, branchId string, tableId string, includeDeleted bool) (*models.Table, error) {
+
+	table, err := r.rows.get(branchId, tableId)
+
+	if err == nil && !includeDeleted && table.Deleted_at != nil {
+		return nil, repository.ErrNotFound
+	}
+
+	return table, err
+}
+
+func (r tableRepository) Create(ctx context.Context, table *models.Table) error {
+	return r.rows.insert(ctx, table.Table_id, *table)
+}
+
+func (r tableRepository) Update(ctx context.Context, table *models.Table) error {
+	return r.rows.replace(ctx, table.Table_id, table, &table.Version, func(stored models.Table) int { return stored.Version })
+}
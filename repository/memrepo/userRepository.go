@@ -0,0 +1,145 @@
+package memrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+)
+
+type userRepository struct {
+	rows *table[models.User]
+}
+
+func (r userRepository) List(ctx context.Context, branchId string, skip int64, limit int64) ([]models.User, int64, error) {
+
+	users, total := r.rows.page(branchId, nil, skip, limit)
+
+	return users, total, nil
+}
+
+func (r userRepository) Get(ctx context.Context, branchId string, userId string) (*models.User, error) {
+	return r.rows.get(branchId, userId)
+}
+
+func (r userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.rows.find(func(user models.User) bool {
+		return user.Email != nil && *user.Email == email
+	})
+}
+
+func (r userRepository) EmailTaken(ctx context.Context, email string, exceptUserId string) (bool, error) {
+
+	taken := r.rows.list("", func(user models.User) bool {
+		return user.User_id != exceptUserId && user.Email != nil && *user.Email == email
+	})
+
+	return len(taken) > 0, nil
+}
+
+func (r userRepository) PhoneTaken(ctx context.Context, phone string, exceptUserId string) (bool, error) {
+
+	taken := r.rows.list("", func(user models.User) bool {
+		return user.User_id != exceptUserId && user.Phone != nil && *user.Phone == phone
+	})
+
+	return len(taken) > 0, nil
+}
+
+func (r userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.rows.insert(ctx, user.User_id, *user)
+}
+
+// Update keeps the stored token pair , like the $set of the mongodb repository
+func (r userRepository) Update(ctx context.Context, user *models.User) error {
+
+	expected := user.Version
+	user.Version = expected + 1
+
+	changed := false
+
+	_, err := r.rows.modify(ctx, user.User_id, func(stored *models.User) bool {
+		if stored.Version != expected {
+			return false
+		}
+
+		token, refreshToken := stored.Token, stored.RefreshToken
+
+		*stored = *user
+		stored.Token, stored.RefreshToken = token, refreshToken
+		changed = true
+
+		return true
+	})
+
+	if err == nil && !changed {
+		err = repository.ErrVersionConflict
+	}
+
+	if err != nil {
+		user.Version = expected
+	}
+
+	return err
+}
+
+func (r userRepository) SetTokens(ctx context.Context, userId string, token string, refreshToken string) error {
+
+	_, err := r.rows.modify(ctx, userId, func(user *models.User) bool {
+		user.Token = &token
+		user.RefreshToken = &refreshToken
+		user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		return true
+	})
+
+	return err
+}
+
+func (r userRepository) RotateTokens(ctx context.Context, userId string, usedRefreshToken string, token string, refreshToken string) (bool, error) {
+
+	rotated, err := r.rows.modify(ctx, userId, func(user *models.User) bool {
+		if user.RefreshToken == nil || *user.RefreshToken != usedRefreshToken {
+			return false
+		}
+
+		user.Token = &token
+		user.RefreshToken = &refreshToken
+		user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		return true
+	})
+
+	return rotated, ignoreNotFound(err)
+}
+
+func (r userRepository) UseTotpStep(ctx context.Context, userId string, step int64) (bool, error) {
+
+	used, err := r.rows.modify(ctx, userId, func(user *models.User) bool {
+		if user.Totp_last_step >= step {
+			return false
+		}
+
+		user.Totp_last_step = step
+
+		return true
+	})
+
+	return used, ignoreNotFound(err)
+}
+
+func (r userRepository) UseRecoveryCode(ctx context.Context, userId string, hashedCode string) (bool, error) {
+
+	used, err := r.rows.modify(ctx, userId, func(user *models.User) bool {
+		for i, code := range user.Recovery_codes {
+			if code == hashedCode {
+				user.Recovery_codes = append(append([]string{}, user.Recovery_codes[:i]...), user.Recovery_codes[i+1:]...)
+				return true
+			}
+		}
+
+		return false
+	})
+
+	return used, ignoreNotFound(err)
+}
@@ -0,0 +1,55 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type apiKeyRepository struct {
+	collection[models.ApiKey]
+}
+
+func (r apiKeyRepository) List(ctx context.Context, branchId string) ([]models.ApiKey, error) {
+	return r.find(ctx, branchId, bson.M{})
+}
+
+func (r apiKeyRepository) Get(ctx context.Context, branchId string, apiKeyId string) (*models.ApiKey, error) {
+	return r.get(ctx, branchId, apiKeyId)
+}
+
+func (r apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.ApiKey, error) {
+	return r.findOne(ctx, "", bson.M{"key_hash": keyHash})
+}
+
+func (r apiKeyRepository) Create(ctx context.Context, apiKey *models.ApiKey) error {
+	return r.insert(ctx, apiKey)
+}
+
+func (r apiKeyRepository) Revoke(ctx context.Context, branchId string, apiKeyId string, at time.Time) (bool, error) {
+
+	result, err := database.InBranch(r.coll, branchId).UpdateOne(
+		ctx,
+		bson.M{"api_key_id": apiKeyId, "revoked_at": nil},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "revoked_at", Value: at},
+			{Key: "updated_at", Value: at},
+		}}},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (r apiKeyRepository) Touch(ctx context.Context, apiKeyId string, at time.Time) error {
+
+	_, err := r.coll.UpdateOne(ctx, bson.M{"api_key_id": apiKeyId}, bson.D{{Key: "$set", Value: bson.D{{Key: "last_used_at", Value: at}}}})
+
+	return err
+}
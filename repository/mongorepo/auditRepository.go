@@ -0,0 +1,84 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditRepository struct {
+	coll *mongo.Collection
+}
+
+func (r auditRepository) Insert(ctx context.Context, event *models.AuditEvent) error {
+
+	_, err := r.coll.InsertOne(ctx, event)
+
+	return err
+}
+
+// Find returns a page of the events matching the filter , newest first , together with the number of all matching events
+func (r auditRepository) Find(ctx context.Context, filter repository.AuditFilter, skip int64, limit int64) ([]models.AuditEvent, int64, error) {
+
+	query := bson.M{}
+
+	if filter.Branch_id != "" {
+		query["branch_id"] = filter.Branch_id
+	}
+
+	if filter.Entity_type != "" {
+		query["entity_type"] = filter.Entity_type
+	}
+
+	if filter.Entity_id != "" {
+		query["entity_id"] = filter.Entity_id
+	}
+
+	if filter.Actor_id != "" {
+		query["actor_id"] = filter.Actor_id
+	}
+
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+
+	if filter.From != nil || filter.To != nil {
+		createdAt := bson.M{}
+
+		if filter.From != nil {
+			createdAt["$gte"] = *filter.From
+		}
+
+		if filter.To != nil {
+			createdAt["$lte"] = *filter.To
+		}
+
+		query["created_at"] = createdAt
+	}
+
+	total, err := r.coll.CountDocuments(ctx, query)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(skip).SetLimit(limit)
+
+	result, err := r.coll.Find(ctx, query, opts)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []models.AuditEvent{}
+
+	if err := result.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
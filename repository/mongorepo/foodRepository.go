@@ -0,0 +1,32 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type foodRepository struct {
+	collection[models.Food]
+}
+
+func (r foodRepository) List(ctx context.Context, branchId string, skip int64, limit int64, includeDeleted bool) ([]models.Food, int64, error) {
+	return r.page(ctx, branchId, visible(bson.M{}, includeDeleted), skip, limit)
+}
+
+func (r foodRepository) Get(ctx context.Context, branchId string, foodId string, includeDeleted bool) (*models.Food, error) {
+	return r.findOne(ctx, branchId, visible(bson.M{"food_id": foodId}, includeDeleted))
+}
+
+func (r foodRepository) ListByMenu(ctx context.Context, branchId string, menuId string) ([]models.Food, error) {
+	return r.find(ctx, branchId, visible(bson.M{"menu_id": menuId}, false))
+}
+
+func (r foodRepository) Create(ctx context.Context, food *models.Food) error {
+	return r.insert(ctx, food)
+}
+
+func (r foodRepository) Update(ctx context.Context, food *models.Food) error {
+	return r.replace(ctx, food.Food_id, food, &food.Version)
+}
@@ -0,0 +1,123 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/repository"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionIndexes are the indexes one collection needs
+type CollectionIndexes struct {
+	Collection string
+	Indexes    []mongo.IndexModel
+}
+
+// INDEXES declares every index of the database , EnsureIndexes creates the missing ones at startup.
+// the unique indexes are what really keeps emails , phones and table numbers from being taken twice , the checks in the handlers only give the nicer message
+var INDEXES = []CollectionIndexes{
+	{BRANCH_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("branch_id_unique", bson.D{{Key: "branch_id", Value: 1}}),
+	}},
+	{FOOD_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("food_id_unique", bson.D{{Key: "food_id", Value: 1}}),
+		index("branch_id_menu_id", bson.D{{Key: "branch_id", Value: 1}, {Key: "menu_id", Value: 1}}),
+	}},
+	{MENU_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("menu_id_unique", bson.D{{Key: "menu_id", Value: 1}}),
+		index("branch_id", bson.D{{Key: "branch_id", Value: 1}}),
+	}},
+	{TABLE_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("table_id_unique", bson.D{{Key: "table_id", Value: 1}}),
+		// a table number can be used once in every branch , deleted tables keep theirs so that they can be restored
+		uniqueIndex("branch_id_table_number_unique", bson.D{{Key: "branch_id", Value: 1}, {Key: "table_number", Value: 1}}),
+	}},
+	{ORDER_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("order_id_unique", bson.D{{Key: "order_id", Value: 1}}),
+		index("branch_id_table_id", bson.D{{Key: "branch_id", Value: 1}, {Key: "table_id", Value: 1}}),
+	}},
+	{ORDER_ITEM_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("order_item_id_unique", bson.D{{Key: "order_item_id", Value: 1}}),
+		// ItemsByOrder matches the items of an order inside a branch
+		index("order_id_branch_id", bson.D{{Key: "order_id", Value: 1}, {Key: "branch_id", Value: 1}}),
+		index("branch_id_food_id", bson.D{{Key: "branch_id", Value: 1}, {Key: "food_id", Value: 1}}),
+	}},
+	{INVOICE_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("invoice_id_unique", bson.D{{Key: "invoice_id", Value: 1}}),
+		index("branch_id_order_id", bson.D{{Key: "branch_id", Value: 1}, {Key: "order_id", Value: 1}}),
+	}},
+	{USER_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("user_id_unique", bson.D{{Key: "user_id", Value: 1}}),
+		uniqueIndex("email_unique", bson.D{{Key: "email", Value: 1}}),
+		uniqueIndex("phone_unique", bson.D{{Key: "phone", Value: 1}}),
+		index("branch_id", bson.D{{Key: "branch_id", Value: 1}}),
+	}},
+	{API_KEY_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("api_key_id_unique", bson.D{{Key: "api_key_id", Value: 1}}),
+		uniqueIndex("key_hash_unique", bson.D{{Key: "key_hash", Value: 1}}),
+	}},
+	{PASSWORD_RESET_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("token_hash_unique", bson.D{{Key: "token_hash", Value: 1}}),
+		index("user_id", bson.D{{Key: "user_id", Value: 1}}),
+	}},
+	{LOGIN_ATTEMPT_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("key_unique", bson.D{{Key: "key", Value: 1}}),
+	}},
+	{REVOKED_TOKEN_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("token_id_unique", bson.D{{Key: "token_id", Value: 1}}),
+		// a revoked token is dropped once it would have expired anyway
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+	}},
+	{TOKEN_GENERATION_COLLECTION, []mongo.IndexModel{
+		uniqueIndex("user_id_unique", bson.D{{Key: "user_id", Value: 1}}),
+	}},
+	{AUDIT_COLLECTION, []mongo.IndexModel{
+		index("created_at", bson.D{{Key: "created_at", Value: -1}}),
+		index("entity_type_entity_id_created_at", bson.D{{Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "created_at", Value: -1}}),
+		index("branch_id_created_at", bson.D{{Key: "branch_id", Value: 1}, {Key: "created_at", Value: -1}}),
+	}},
+}
+
+func index(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+}
+
+func uniqueIndex(name string, keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name).SetUnique(true)}
+}
+
+// EnsureIndexes creates the indexes of INDEXES , the ones that already exist are left alone.
+// a unique index can't be built while the collection holds duplicates , the error names the collection so the data can be cleaned up first
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+
+	for _, collection := range INDEXES {
+
+		names, err := database.OpenCollection(db, collection.Collection).Indexes().CreateMany(ctx, collection.Indexes)
+
+		if err != nil {
+			return fmt.Errorf("creating the indexes of %s: %w", collection.Collection, err)
+		}
+
+		log.Printf("indexes of %s: %v", collection.Collection, names)
+	}
+
+	return nil
+}
+
+// duplicate turns the error of a write refused by a unique index into repository.ErrDuplicate
+func duplicate(err error) error {
+
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ErrDuplicate
+	}
+
+	return err
+}
@@ -0,0 +1,32 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type invoiceRepository struct {
+	collection[models.Invoice]
+}
+
+func (r invoiceRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Invoice, error) {
+	return r.find(ctx, branchId, visible(bson.M{}, includeDeleted))
+}
+
+func (r invoiceRepository) Get(ctx context.Context, branchId string, invoiceId string, includeDeleted bool) (*models.Invoice, error) {
+	return r.findOne(ctx, branchId, visible(bson.M{"invoice_id": invoiceId}, includeDeleted))
+}
+
+func (r invoiceRepository) ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.Invoice, error) {
+	return r.find(ctx, branchId, visible(bson.M{"order_id": orderId}, false))
+}
+
+func (r invoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	return r.insert(ctx, invoice)
+}
+
+func (r invoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	return r.replace(ctx, invoice.Invoice_id, invoice, &invoice.Version)
+}
@@ -0,0 +1,82 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginAttemptRepository struct {
+	coll *mongo.Collection
+}
+
+func (r loginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+
+	var attempt models.LoginAttempt
+
+	err := r.coll.FindOne(ctx, bson.M{"key": key}).Decode(&attempt)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+
+	// a failure long after the previous one starts counting from zero again
+	_, err := r.coll.UpdateOne(
+		ctx,
+		bson.M{"key": key, "last_failure_at": bson.M{"$lt": at.Add(-window)}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "failures", Value: 0}}}},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	upsert := true
+	after := options.After
+
+	var attempt models.LoginAttempt
+
+	err = r.coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"key": key},
+		bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "failures", Value: 1}}},
+			{Key: "$set", Value: bson.D{{Key: "last_failure_at", Value: at}}},
+		},
+		&options.FindOneAndUpdateOptions{Upsert: &upsert, ReturnDocument: &after},
+	).Decode(&attempt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+
+	_, err := r.coll.UpdateOne(ctx, bson.M{"key": key}, bson.D{{Key: "$set", Value: bson.D{{Key: "locked_until", Value: until}}}})
+
+	return err
+}
+
+func (r loginAttemptRepository) Delete(ctx context.Context, key string) error {
+
+	_, err := r.coll.DeleteOne(ctx, bson.M{"key": key})
+
+	return err
+}
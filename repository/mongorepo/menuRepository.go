@@ -0,0 +1,28 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type menuRepository struct {
+	collection[models.Menu]
+}
+
+func (r menuRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Menu, error) {
+	return r.find(ctx, branchId, visible(bson.M{}, includeDeleted))
+}
+
+func (r menuRepository) Get(ctx context.Context, branchId string, menuId string, includeDeleted bool) (*models.Menu, error) {
+	return r.findOne(ctx, branchId, visible(bson.M{"menu_id": menuId}, includeDeleted))
+}
+
+func (r menuRepository) Create(ctx context.Context, menu *models.Menu) error {
+	return r.insert(ctx, menu)
+}
+
+func (r menuRepository) Update(ctx context.Context, menu *models.Menu) error {
+	return r.replace(ctx, menu.Menu_id, menu, &menu.Version)
+}
@@ -0,0 +1,106 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type orderItemRepository struct {
+	collection[models.OrderItem]
+}
+
+func (r orderItemRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.OrderItem, error) {
+	return r.find(ctx, branchId, visible(bson.M{}, includeDeleted))
+}
+
+func (r orderItemRepository) Get(ctx context.Context, branchId string, orderItemId string, includeDeleted bool) (*models.OrderItem, error) {
+	return r.findOne(ctx, branchId, visible(bson.M{"order_item_id": orderItemId}, includeDeleted))
+}
+
+func (r orderItemRepository) ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.OrderItem, error) {
+	return r.find(ctx, branchId, visible(bson.M{"order_id": orderId}, false))
+}
+
+func (r orderItemRepository) ListByFood(ctx context.Context, branchId string, foodId string) ([]models.OrderItem, error) {
+	return r.find(ctx, branchId, visible(bson.M{"food_id": foodId}, false))
+}
+
+func (r orderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+
+	documents := make([]interface{}, 0, len(orderItems))
+
+	for _, orderItem := range orderItems {
+		documents = append(documents, orderItem)
+	}
+
+	_, err := r.coll.InsertMany(ctx, documents)
+
+	return duplicate(err)
+}
+
+func (r orderItemRepository) Update(ctx context.Context, orderItem *models.OrderItem) error {
+	return r.replace(ctx, orderItem.Order_item_id, orderItem, &orderItem.Version)
+}
+
+// ItemsByOrder looks up the food of every item and the table of the order , the totals are added up by repository.Summarize
+func (r orderItemRepository) ItemsByOrder(ctx context.Context, branchId string, orderId string) (*repository.OrderSummary, error) {
+
+	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "order_id", Value: orderId}, {Key: "deleted_at", Value: nil}}}} // matchStage : obtains the documents which are matched with the given query , deleted items are left out
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}
+	// lookup stage : it is used to run the query to obtain the fiels of other document using the foreign key field of current document .
+	foodLookUpStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: FOOD_COLLECTION}, {Key: "localField", Value: "food_id"}, {Key: "foreignField", Value: "food_id"}, {Key: "as", Value: "food"}}}}
+	// afte lookup stage , the result comes as array . unwind stage turns it into a single document , items without a food are kept
+	foodUnwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	orderLookUpStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: ORDER_COLLECTION}, {Key: "localField", Value: "order_id"}, {Key: "foreignField", Value: "order_id"}, {Key: "as", Value: "order"}}}}
+	orderUnwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$order"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	tableLookUpStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: TABLE_COLLECTION}, {Key: "localField", Value: "order.table_id"}, {Key: "foreignField", Value: "table_id"}, {Key: "as", Value: "table"}}}}
+	tableUnwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$table"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	// projectStage gives every item the shape of repository.OrderSummaryItem
+	projectStage := bson.D{
+		{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "order_item_id", Value: 1},
+			{Key: "order_id", Value: 1},
+			{Key: "food_id", Value: 1},
+			{Key: "quantity", Value: 1},
+			{Key: "modifiers", Value: 1},
+			{Key: "food_name", Value: "$food.name"},
+			{Key: "food_image", Value: "$food.food_image"},
+			// the unit price of the item is the price of its size , the price of the food is only there for items without one
+			{Key: "price", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$unit_price", "$food.price"}}}},
+			{Key: "amount", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$unit_price", bson.D{{Key: "$ifNull", Value: bson.A{"$food.price", 0.0}}}}}}},
+			{Key: "table_id", Value: "$table.table_id"},
+			{Key: "table_number", Value: "$table.table_number"},
+		}}}
+
+	cursor, err := database.InBranch(r.coll, branchId).Aggregate(ctx, mongo.Pipeline{
+		matchStage,
+		sortStage,
+		foodLookUpStage,
+		foodUnwindStage,
+		orderLookUpStage,
+		orderUnwindStage,
+		tableLookUpStage,
+		tableUnwindStage,
+		projectStage})
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := []repository.OrderSummaryItem{}
+
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return repository.Summarize(orderId, items)
+}
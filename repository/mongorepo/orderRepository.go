@@ -0,0 +1,71 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// orderRepository writes to the order items as well , an order and its items are created in one transaction
+type orderRepository struct {
+	collection[models.Order]
+	items *mongo.Collection
+}
+
+func (r orderRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Order, error) {
+	return r.find(ctx, branchId, visible(bson.M{}, includeDeleted))
+}
+
+func (r orderRepository) Get(ctx context.Context, branchId string, orderId string, includeDeleted bool) (*models.Order, error) {
+	return r.findOne(ctx, branchId, visible(bson.M{"order_id": orderId}, includeDeleted))
+}
+
+func (r orderRepository) ListByTable(ctx context.Context, branchId string, tableId string) ([]models.Order, error) {
+	return r.find(ctx, branchId, visible(bson.M{"table_id": tableId}, false))
+}
+
+func (r orderRepository) Create(ctx context.Context, order *models.Order) error {
+	return r.insert(ctx, order)
+}
+
+// CreateWithItems runs both inserts in a multi-document transaction , mongodb only has those on a replica set or a sharded cluster.
+// a single node replica set (mongod --replSet rs0 , then rs.initiate()) is enough for development
+func (r orderRepository) CreateWithItems(ctx context.Context, order *models.Order, orderItems []models.OrderItem) error {
+
+	session, err := r.coll.Database().Client().StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+
+		if _, err := r.coll.InsertOne(sessionCtx, order); err != nil {
+			return nil, err
+		}
+
+		if len(orderItems) == 0 {
+			return nil, nil
+		}
+
+		documents := make([]interface{}, 0, len(orderItems))
+
+		for _, orderItem := range orderItems {
+			documents = append(documents, orderItem)
+		}
+
+		_, err := r.items.InsertMany(sessionCtx, documents)
+
+		return nil, err
+	})
+
+	return duplicate(err)
+}
+
+func (r orderRepository) Update(ctx context.Context, order *models.Order) error {
+	return r.replace(ctx, order.Order_id, order, &order.Version)
+}
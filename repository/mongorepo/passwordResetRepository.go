@@ -0,0 +1,61 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type passwordResetRepository struct {
+	coll *mongo.Collection
+}
+
+func (r passwordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+
+	_, err := r.coll.InsertOne(ctx, reset)
+
+	return err
+}
+
+func (r passwordResetRepository) InvalidateAll(ctx context.Context, userId string, at time.Time) error {
+
+	_, err := r.coll.UpdateMany(
+		ctx,
+		bson.M{"user_id": userId, "used_at": nil},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: at}}}},
+	)
+
+	return err
+}
+
+// Redeem marks the token as used in the same call that finds it , so it can't be redeemed twice
+func (r passwordResetRepository) Redeem(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error) {
+
+	var reset models.PasswordReset
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": at},
+	}
+
+	err := r.coll.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: at}}}},
+	).Decode(&reset)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &reset, nil
+}
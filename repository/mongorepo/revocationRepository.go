@@ -0,0 +1,81 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type revocationRepository struct {
+	revokedTokens    *mongo.Collection
+	tokenGenerations *mongo.Collection
+}
+
+func (r revocationRepository) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+
+	upsert := true
+
+	// expires_at is kept so that the entry can be dropped once the token would have expired anyway
+	_, err := r.revokedTokens.UpdateOne(
+		ctx,
+		bson.M{"token_id": tokenId},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "token_id", Value: tokenId},
+				{Key: "expires_at", Value: expiresAt},
+			}},
+		},
+		&options.UpdateOptions{Upsert: &upsert},
+	)
+
+	return err
+}
+
+func (r revocationRepository) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+
+	count, err := r.revokedTokens.CountDocuments(ctx, bson.M{"token_id": tokenId})
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r revocationRepository) TokenGeneration(ctx context.Context, userId string) (int, error) {
+
+	var found struct {
+		Generation int `bson:"generation"`
+	}
+
+	err := r.tokenGenerations.FindOne(ctx, bson.M{"user_id": userId}).Decode(&found)
+
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return found.Generation, nil
+}
+
+func (r revocationRepository) RevokeAllTokens(ctx context.Context, userId string) error {
+
+	upsert := true
+
+	_, err := r.tokenGenerations.UpdateOne(
+		ctx,
+		bson.M{"user_id": userId},
+		bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "generation", Value: 1}}},
+		},
+		&options.UpdateOptions{Upsert: &upsert},
+	)
+
+	return err
+}
@@ -0,0 +1,219 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// names of the collections , the lookups of ItemsByOrder refer to them as well.
+// databases created before the names were made consistent are renamed by migration 1 , see cmd/migrate
+const (
+	BRANCH_COLLECTION           = "branches"
+	FOOD_COLLECTION             = "foods"
+	MENU_COLLECTION             = "menus"
+	TABLE_COLLECTION            = "tables"
+	ORDER_COLLECTION            = "orders"
+	ORDER_ITEM_COLLECTION       = "order_items"
+	INVOICE_COLLECTION          = "invoices"
+	USER_COLLECTION             = "users"
+	API_KEY_COLLECTION          = "api_keys"
+	PASSWORD_RESET_COLLECTION   = "password_resets"
+	LOGIN_ATTEMPT_COLLECTION    = "login_attempts"
+	REVOKED_TOKEN_COLLECTION    = "revoked_tokens"
+	TOKEN_GENERATION_COLLECTION = "token_generations"
+	AUDIT_COLLECTION            = "audit_log"
+)
+
+// NewStore builds the repositories on top of the database main() connected to
+func NewStore(db *mongo.Database) *repository.Store {
+	return &repository.Store{
+		Branches: branchRepository{newCollection[models.Branch](db, BRANCH_COLLECTION, "branch_id")},
+		Foods:    foodRepository{newCollection[models.Food](db, FOOD_COLLECTION, "food_id")},
+		Menus:    menuRepository{newCollection[models.Menu](db, MENU_COLLECTION, "menu_id")},
+		Tables:   tableRepository{newCollection[models.Table](db, TABLE_COLLECTION, "table_id")},
+		Orders: orderRepository{
+			collection: newCollection[models.Order](db, ORDER_COLLECTION, "order_id"),
+			items:      database.OpenCollection(db, ORDER_ITEM_COLLECTION),
+		},
+		OrderItems:     orderItemRepository{newCollection[models.OrderItem](db, ORDER_ITEM_COLLECTION, "order_item_id")},
+		Invoices:       invoiceRepository{newCollection[models.Invoice](db, INVOICE_COLLECTION, "invoice_id")},
+		Users:          userRepository{newCollection[models.User](db, USER_COLLECTION, "user_id")},
+		ApiKeys:        apiKeyRepository{newCollection[models.ApiKey](db, API_KEY_COLLECTION, "api_key_id")},
+		PasswordResets: passwordResetRepository{database.OpenCollection(db, PASSWORD_RESET_COLLECTION)},
+		LoginAttempts:  loginAttemptRepository{database.OpenCollection(db, LOGIN_ATTEMPT_COLLECTION)},
+		Revocations: revocationRepository{
+			revokedTokens:    database.OpenCollection(db, REVOKED_TOKEN_COLLECTION),
+			tokenGenerations: database.OpenCollection(db, TOKEN_GENERATION_COLLECTION),
+		},
+		Audit: auditRepository{database.OpenCollection(db, AUDIT_COLLECTION)},
+		Transaction: func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return transaction(ctx, db, fn)
+		},
+		Ping: func(ctx context.Context) error {
+			return db.Client().Ping(ctx, nil)
+		},
+	}
+}
+
+// transaction runs fn in a multi-document transaction , the driver sends every operation made with the session context as part of it.
+// like CreateWithItems it needs a replica set , inside a transaction fn simply joins it
+func transaction(ctx context.Context, db *mongo.Database, fn func(ctx context.Context) error) error {
+
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := db.Client().StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+
+	return err
+}
+
+// collection is what the entity repositories share , T is the model stored in the collection and idField its business id
+type collection[T any] struct {
+	coll    *mongo.Collection
+	idField string
+}
+
+func newCollection[T any](db *mongo.Database, name string, idField string) collection[T] {
+	return collection[T]{coll: database.OpenCollection(db, name), idField: idField}
+}
+
+// find returns the documents in the order they were created
+func (c collection[T]) find(ctx context.Context, branchId string, filter bson.M, opts ...*options.FindOptions) ([]T, error) {
+
+	opts = append([]*options.FindOptions{options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})}, opts...)
+
+	cursor, err := database.InBranch(c.coll, branchId).Find(ctx, filter, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := []T{}
+
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (c collection[T]) page(ctx context.Context, branchId string, filter bson.M, skip int64, limit int64) ([]T, int64, error) {
+
+	total, err := database.InBranch(c.coll, branchId).CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(skip)
+
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	items, err := c.find(ctx, branchId, filter, opts)
+
+	return items, total, err
+}
+
+func (c collection[T]) findOne(ctx context.Context, branchId string, filter bson.M) (*T, error) {
+
+	var item T
+
+	err := database.InBranch(c.coll, branchId).FindOne(ctx, filter).Decode(&item)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (c collection[T]) get(ctx context.Context, branchId string, id string) (*T, error) {
+	return c.findOne(ctx, branchId, bson.M{c.idField: id})
+}
+
+// visible leaves the soft deleted documents out of filter unless includeDeleted is set
+func visible(filter bson.M, includeDeleted bool) bson.M {
+
+	if !includeDeleted {
+		filter["deleted_at"] = nil
+	}
+
+	return filter
+}
+
+func (c collection[T]) insert(ctx context.Context, item *T) error {
+
+	_, err := c.coll.InsertOne(ctx, item)
+
+	return duplicate(err)
+}
+
+// replace only writes the item while the stored version is still *version , the version is raised by one with the write.
+// when nothing was written *version is put back
+func (c collection[T]) replace(ctx context.Context, id string, item *T, version *int) error {
+
+	expected := *version
+	*version = expected + 1
+
+	result, err := c.coll.ReplaceOne(ctx, bson.M{c.idField: id, "version": versionFilter(expected)}, item)
+
+	if err == nil && result.MatchedCount == 0 {
+		err = c.missingOrChanged(ctx, id)
+	}
+
+	if err != nil {
+		*version = expected
+		return duplicate(err)
+	}
+
+	return nil
+}
+
+// missingOrChanged tells apart the two reasons why a versioned write didn't match
+func (c collection[T]) missingOrChanged(ctx context.Context, id string) error {
+
+	count, err := c.coll.CountDocuments(ctx, bson.M{c.idField: id})
+
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+
+	return repository.ErrVersionConflict
+}
+
+// versionFilter matches the version , documents written before there were versions have none and count as version 0
+func versionFilter(version int) interface{} {
+
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+
+	return version
+}
@@ -0,0 +1,28 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type tableRepository struct {
+	collection[models.Table]
+}
+
+func (r tableRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Table, error) {
+	return r.find(ctx, branchId, visible(bson.M{}, includeDeleted))
+}
+
+func (r tableRepository) Get(ctx context.Context, branchId string, tableId string, includeDeleted bool) (*models.Table, error) {
+	return r.findOne(ctx, branchId, visible(bson.M{"table_id": tableId}, includeDeleted))
+}
+
+func (r tableRepository) Create(ctx context.Context, table *models.Table) error {
+	return r.insert(ctx, table)
+}
+
+func (r tableRepository) Update(ctx context.Context, table *models.Table) error {
+	return r.replace(ctx, table.Table_id, table, &table.Version)
+}
@@ -0,0 +1,176 @@
+package mongorepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type userRepository struct {
+	collection[models.User]
+}
+
+func (r userRepository) List(ctx context.Context, branchId string, skip int64, limit int64) ([]models.User, int64, error) {
+	return r.page(ctx, branchId, bson.M{}, skip, limit)
+}
+
+func (r userRepository) Get(ctx context.Context, branchId string, userId string) (*models.User, error) {
+	return r.get(ctx, branchId, userId)
+}
+
+func (r userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, "", bson.M{"email": email})
+}
+
+func (r userRepository) EmailTaken(ctx context.Context, email string, exceptUserId string) (bool, error) {
+	return r.taken(ctx, "email", email, exceptUserId)
+}
+
+func (r userRepository) PhoneTaken(ctx context.Context, phone string, exceptUserId string) (bool, error) {
+	return r.taken(ctx, "phone", phone, exceptUserId)
+}
+
+func (r userRepository) taken(ctx context.Context, field string, value string, exceptUserId string) (bool, error) {
+
+	count, err := r.coll.CountDocuments(ctx, bson.M{field: value, "user_id": bson.M{"$ne": exceptUserId}})
+
+	return count > 0, err
+}
+
+func (r userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.insert(ctx, user)
+}
+
+// Update sets every field of the user but the token pair , a login that happens at the same time keeps its tokens
+func (r userRepository) Update(ctx context.Context, user *models.User) error {
+
+	expected := user.Version
+	user.Version = expected + 1
+
+	err := r.update(ctx, user, expected)
+
+	if err != nil {
+		user.Version = expected
+	}
+
+	return err
+}
+
+func (r userRepository) update(ctx context.Context, user *models.User, expected int) error {
+
+	data, err := bson.Marshal(user)
+
+	if err != nil {
+		return err
+	}
+
+	var document bson.M
+
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	delete(document, "_id")
+	delete(document, "token")
+	delete(document, "refresh_token")
+
+	result, err := r.coll.UpdateOne(ctx, bson.M{"user_id": user.User_id, "version": versionFilter(expected)}, bson.D{{Key: "$set", Value: document}})
+
+	if err != nil {
+		return duplicate(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return r.missingOrChanged(ctx, user.User_id)
+	}
+
+	return nil
+}
+
+func (r userRepository) SetTokens(ctx context.Context, userId string, token string, refreshToken string) error {
+
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	result, err := r.coll.UpdateOne(
+		ctx,
+		bson.M{"user_id": userId},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "token", Value: token},
+				{Key: "refresh_token", Value: refreshToken},
+				{Key: "updated_at", Value: Updated_at},
+			}},
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// RotateTokens checks and writes in a single UpdateOne , so a refresh token can be exchanged exactly once even when two requests race with it
+func (r userRepository) RotateTokens(ctx context.Context, userId string, usedRefreshToken string, token string, refreshToken string) (bool, error) {
+
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	result, err := r.coll.UpdateOne(
+		ctx,
+		bson.M{"user_id": userId, "refresh_token": usedRefreshToken},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "token", Value: token},
+				{Key: "refresh_token", Value: refreshToken},
+				{Key: "updated_at", Value: Updated_at},
+			}},
+		},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (r userRepository) UseTotpStep(ctx context.Context, userId string, step int64) (bool, error) {
+
+	filter := bson.M{
+		"user_id": userId,
+		"$or": []bson.M{
+			{"totp_last_step": bson.M{"$lt": step}},
+			{"totp_last_step": bson.M{"$exists": false}},
+		},
+	}
+
+	result, err := r.coll.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: bson.D{{Key: "totp_last_step", Value: step}}}})
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (r userRepository) UseRecoveryCode(ctx context.Context, userId string, hashedCode string) (bool, error) {
+
+	result, err := r.coll.UpdateOne(
+		ctx,
+		bson.M{"user_id": userId, "recovery_codes": hashedCode},
+		bson.D{{Key: "$pull", Value: bson.D{{Key: "recovery_codes", Value: hashedCode}}}},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount == 1, nil
+}
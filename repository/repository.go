@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-restaurent-management-system/models"
+	"time"
+)
+
+// the implementations return these instead of driver specific errors , the handlers turn them into status codes
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is a write that would take a value another entity already has , like an email or the number of a table
+	ErrDuplicate = errors.New("duplicate")
+	// ErrVersionConflict is an Update of an entity that was changed by someone else since it was read
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// the branch id of the List and Get methods narrows them down to one branch , an empty branch id is the head office reading every branch.
+// foods , menus , tables , orders , order items and invoices are only soft deleted , their List and Get leave the deleted ones out unless includeDeleted is set.
+// Update writes the whole entity back , the handlers read it with Get , change it and hand it over again.
+// it only writes while the stored version is still the Version of the entity , then raises the Version by one. otherwise it is ErrVersionConflict
+
+type BranchRepository interface {
+	List(ctx context.Context) ([]models.Branch, error)
+	Get(ctx context.Context, branchId string) (*models.Branch, error)
+	Create(ctx context.Context, branch *models.Branch) error
+	Update(ctx context.Context, branch *models.Branch) error
+}
+
+type FoodRepository interface {
+	// List returns a page of the foods together with the number of all foods , a limit of zero returns all of them
+	List(ctx context.Context, branchId string, skip int64, limit int64, includeDeleted bool) ([]models.Food, int64, error)
+	Get(ctx context.Context, branchId string, foodId string, includeDeleted bool) (*models.Food, error)
+	// ListByMenu returns the foods of a menu that are not deleted
+	ListByMenu(ctx context.Context, branchId string, menuId string) ([]models.Food, error)
+	Create(ctx context.Context, food *models.Food) error
+	Update(ctx context.Context, food *models.Food) error
+}
+
+type MenuRepository interface {
+	List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Menu, error)
+	Get(ctx context.Context, branchId string, menuId string, includeDeleted bool) (*models.Menu, error)
+	Create(ctx context.Context, menu *models.Menu) error
+	Update(ctx context.Context, menu *models.Menu) error
+}
+
+type TableRepository interface {
+	List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Table, error)
+	Get(ctx context.Context, branchId string, tableId string, includeDeleted bool) (*models.Table, error)
+	Create(ctx context.Context, table *models.Table) error
+	Update(ctx context.Context, table *models.Table) error
+}
+
+type OrderRepository interface {
+	List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Order, error)
+	Get(ctx context.Context, branchId string, orderId string, includeDeleted bool) (*models.Order, error)
+	// ListByTable returns the orders served at a table that are not deleted
+	ListByTable(ctx context.Context, branchId string, tableId string) ([]models.Order, error)
+	Create(ctx context.Context, order *models.Order) error
+	// CreateWithItems stores the order and its items together , either all of them are stored or none
+	CreateWithItems(ctx context.Context, order *models.Order, orderItems []models.OrderItem) error
+	Update(ctx context.Context, order *models.Order) error
+}
+
+type OrderItemRepository interface {
+	List(ctx context.Context, branchId string, includeDeleted bool) ([]models.OrderItem, error)
+	Get(ctx context.Context, branchId string, orderItemId string, includeDeleted bool) (*models.OrderItem, error)
+	// ListByOrder and ListByFood return the order items that are not deleted
+	ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.OrderItem, error)
+	ListByFood(ctx context.Context, branchId string, foodId string) ([]models.OrderItem, error)
+	CreateMany(ctx context.Context, orderItems []models.OrderItem) error
+	Update(ctx context.Context, orderItem *models.OrderItem) error
+	// ItemsByOrder joins the items of an order with their foods and the table of the order , ErrNotFound when the order has no items
+	ItemsByOrder(ctx context.Context, branchId string, orderId string) (*OrderSummary, error)
+}
+
+type InvoiceRepository interface {
+	List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Invoice, error)
+	Get(ctx context.Context, branchId string, invoiceId string, includeDeleted bool) (*models.Invoice, error)
+	// ListByOrder returns the invoices of an order that are not deleted
+	ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.Invoice, error)
+	Create(ctx context.Context, invoice *models.Invoice) error
+	Update(ctx context.Context, invoice *models.Invoice) error
+}
+
+type UserRepository interface {
+	List(ctx context.Context, branchId string, skip int64, limit int64) ([]models.User, int64, error)
+	Get(ctx context.Context, branchId string, userId string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// EmailTaken and PhoneTaken look at every user except the one with exceptUserId
+	EmailTaken(ctx context.Context, email string, exceptUserId string) (bool, error)
+	PhoneTaken(ctx context.Context, phone string, exceptUserId string) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	// Update writes every field except the stored token pair , the tokens are only changed by SetTokens and RotateTokens
+	Update(ctx context.Context, user *models.User) error
+	SetTokens(ctx context.Context, userId string, token string, refreshToken string) error
+	// RotateTokens only replaces the token pair while the stored refresh token is still usedRefreshToken
+	RotateTokens(ctx context.Context, userId string, usedRefreshToken string, token string, refreshToken string) (bool, error)
+	// UseTotpStep succeeds only for a step newer than the last one used , so a TOTP code can't be replayed
+	UseTotpStep(ctx context.Context, userId string, step int64) (bool, error)
+	// UseRecoveryCode removes the hashed recovery code , it succeeds only once per code
+	UseRecoveryCode(ctx context.Context, userId string, hashedCode string) (bool, error)
+}
+
+type ApiKeyRepository interface {
+	List(ctx context.Context, branchId string) ([]models.ApiKey, error)
+	Get(ctx context.Context, branchId string, apiKeyId string) (*models.ApiKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.ApiKey, error)
+	Create(ctx context.Context, apiKey *models.ApiKey) error
+	// Revoke only revokes a key that is not revoked yet , false means there was no such key
+	Revoke(ctx context.Context, branchId string, apiKeyId string, at time.Time) (bool, error)
+	Touch(ctx context.Context, apiKeyId string, at time.Time) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *models.PasswordReset) error
+	// InvalidateAll marks every unused reset token of the user as used
+	InvalidateAll(ctx context.Context, userId string, at time.Time) error
+	// Redeem finds an unused and unexpired token by its hash and marks it as used in the same step , ErrNotFound otherwise
+	Redeem(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error)
+}
+
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure counts one more failure , failures older than the window are forgotten first
+	RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+// RevocationRepository remembers the tokens that were invalidated before they expired.
+// a single token is revoked by its id (the jti claim) , all the tokens of a user are revoked by raising the user's token generation ,
+// every token carries the generation it was signed with and is rejected once the stored generation is higher.
+type RevocationRepository interface {
+	RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, tokenId string) (bool, error)
+	TokenGeneration(ctx context.Context, userId string) (int, error)
+	RevokeAllTokens(ctx context.Context, userId string) error
+}
+
+// AuditFilter narrows down the audit log , empty fields are not filtered on
+type AuditFilter struct {
+	Branch_id   string
+	Entity_type string
+	Entity_id   string
+	Actor_id    string
+	Action      string
+	From        *time.Time
+	To          *time.Time
+}
+
+type AuditRepository interface {
+	Insert(ctx context.Context, event *models.AuditEvent) error
+	// Find returns a page of the matching events , newest first , together with the number of all matching events
+	Find(ctx context.Context, filter AuditFilter, skip int64, limit int64) ([]models.AuditEvent, int64, error)
+}
+
+// OrderSummary is an order with its items , the foods they are made of and the table it was served at
+type OrderSummary struct {
+	Order_id     string             `json:"order_id"`
+	Table_id     *string            `json:"table_id"`
+	Table_number *int               `json:"table_number"`
+	Payment_due  float64            `json:"payment_due"`
+	Total_count  int                `json:"total_count"`
+	Order_items  []OrderSummaryItem `json:"order_items"`
+}
+
+type OrderSummaryItem struct {
+	Order_item_id string   `json:"order_item_id"`
+	Order_id      string   `json:"order_id"`
+	Food_id       *string  `json:"food_id"`
+	Food_name     *string  `json:"food_name"`
+	Food_image    *string  `json:"food_image"`
+	Quantity      *string  `json:"quantity"`
+	Price         *float64 `json:"price"`
+	Amount        float64  `json:"amount"`
+	Table_id      *string  `json:"table_id"`
+	Table_number  *int     `json:"table_number"`
+	// Modifiers are the options picked for the item , Price already holds their price deltas
+	Modifiers []models.SelectedModifier `json:"modifiers"`
+}
+
+// Store bundles the repositories of one backend , main() builds it and hands it to the handlers
+type Store struct {
+	Branches       BranchRepository
+	Foods          FoodRepository
+	Menus          MenuRepository
+	Tables         TableRepository
+	Orders         OrderRepository
+	OrderItems     OrderItemRepository
+	Invoices       InvoiceRepository
+	Users          UserRepository
+	ApiKeys        ApiKeyRepository
+	PasswordResets PasswordResetRepository
+	LoginAttempts  LoginAttemptRepository
+	Revocations    RevocationRepository
+	Audit          AuditRepository
+
+	// Transaction runs fn so that either all of its writes are stored or none of them , the repositories have to be called with the ctx fn gets.
+	// fn can run more than once when the backend retries the transaction , so it must not change anything outside the store
+	Transaction func(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// Ping tells the health check whether the backend can be reached
+	Ping func(ctx context.Context) error
+}
+
+// Summarize adds up the items of an order , the backends only have to join the items with their foods and the table.
+// an order without items is ErrNotFound
+func Summarize(orderId string, items []OrderSummaryItem) (*OrderSummary, error) {
+
+	if len(items) == 0 {
+		return nil, ErrNotFound
+	}
+
+	summary := &OrderSummary{
+		Order_id:     orderId,
+		Table_id:     items[0].Table_id,
+		Table_number: items[0].Table_number,
+		Total_count:  len(items),
+		Order_items:  items,
+	}
+
+	for _, item := range items {
+		summary.Payment_due += item.Amount
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,41 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"time"
+)
+
+type apiKeyRepository struct {
+	table[models.ApiKey]
+}
+
+func (r apiKeyRepository) List(ctx context.Context, branchId string) ([]models.ApiKey, error) {
+	return r.find(ctx, inBranch(branchId))
+}
+
+func (r apiKeyRepository) Get(ctx context.Context, branchId string, apiKeyId string) (*models.ApiKey, error) {
+	return r.get(ctx, branchId, apiKeyId)
+}
+
+func (r apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.ApiKey, error) {
+	return r.findOne(ctx, where{}.and("key_hash = ?", keyHash))
+}
+
+func (r apiKeyRepository) Create(ctx context.Context, apiKey *models.ApiKey) error {
+	return r.insert(ctx, apiKey)
+}
+
+func (r apiKeyRepository) Revoke(ctx context.Context, branchId string, apiKeyId string, at time.Time) (bool, error) {
+
+	w := inBranch(branchId).and("api_key_id = ?", apiKeyId).and("revoked_at IS NULL")
+
+	return affected(r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE api_keys SET revoked_at = ?, updated_at = ?"+w.sql()), append([]interface{}{timestamp{&at}, timestamp{&at}}, w.args...)...))
+}
+
+func (r apiKeyRepository) Touch(ctx context.Context, apiKeyId string, at time.Time) error {
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE api_keys SET last_used_at = ? WHERE api_key_id = ?"), timestamp{&at}, apiKeyId)
+
+	return err
+}
@@ -0,0 +1,63 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type auditRepository struct {
+	table[models.AuditEvent]
+}
+
+func (r auditRepository) Insert(ctx context.Context, event *models.AuditEvent) error {
+	return r.insert(ctx, event)
+}
+
+// Find returns a page of the events matching the filter , newest first , together with the number of all matching events
+func (r auditRepository) Find(ctx context.Context, filter repository.AuditFilter, skip int64, limit int64) ([]models.AuditEvent, int64, error) {
+
+	w := where{}
+
+	if filter.Branch_id != "" {
+		w = w.and("branch_id = ?", filter.Branch_id)
+	}
+
+	if filter.Entity_type != "" {
+		w = w.and("entity_type = ?", filter.Entity_type)
+	}
+
+	if filter.Entity_id != "" {
+		w = w.and("entity_id = ?", filter.Entity_id)
+	}
+
+	if filter.Actor_id != "" {
+		w = w.and("actor_id = ?", filter.Actor_id)
+	}
+
+	if filter.Action != "" {
+		w = w.and("action = ?", filter.Action)
+	}
+
+	if filter.From != nil {
+		w = w.and("created_at >= ?", timestamp{filter.From})
+	}
+
+	if filter.To != nil {
+		w = w.and("created_at <= ?", timestamp{filter.To})
+	}
+
+	total, err := r.count(ctx, w)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events, err := r.query(ctx, r.selectFrom()+w.sql()+" ORDER BY created_at DESC, seq DESC"+r.db.limit(skip, limit), w.args...)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
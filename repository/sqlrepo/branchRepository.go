@@ -0,0 +1,26 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+type branchRepository struct {
+	table[models.Branch]
+}
+
+func (r branchRepository) List(ctx context.Context) ([]models.Branch, error) {
+	return r.find(ctx, where{})
+}
+
+func (r branchRepository) Get(ctx context.Context, branchId string) (*models.Branch, error) {
+	return r.get(ctx, "", branchId)
+}
+
+func (r branchRepository) Create(ctx context.Context, branch *models.Branch) error {
+	return r.insert(ctx, branch)
+}
+
+func (r branchRepository) Update(ctx context.Context, branch *models.Branch) error {
+	return r.replace(ctx, branch.Branch_id, branch, &branch.Version)
+}
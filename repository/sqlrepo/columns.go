@@ -0,0 +1,157 @@
+package sqlrepo
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the models are shared with the mongodb backend , these wrappers store the fields sql has no type for.
+// each one points at the field of the model , so the same wrapper is the value of an insert and the destination of a scan
+
+// objectId keeps the ObjectID of a model as its hex string
+type objectId struct {
+	id *primitive.ObjectID
+}
+
+func (o objectId) Value() (driver.Value, error) {
+	return o.id.Hex(), nil
+}
+
+func (o objectId) Scan(src interface{}) error {
+
+	text, err := text(src)
+
+	if err != nil {
+		return err
+	}
+
+	id, err := primitive.ObjectIDFromHex(text)
+
+	if err != nil {
+		return err
+	}
+
+	*o.id = id
+	return nil
+}
+
+// timestamp writes the time in UTC , sqlite compares the times as text and that only works when they are all in the same zone
+type timestamp struct {
+	t *time.Time
+}
+
+func (ts timestamp) Value() (driver.Value, error) {
+	return ts.t.UTC(), nil
+}
+
+func (ts timestamp) Scan(src interface{}) error {
+
+	parsed, err := parseTime(src)
+
+	if err != nil {
+		return err
+	}
+
+	*ts.t = parsed
+	return nil
+}
+
+// nullTimestamp is a timestamp that can be NULL , like deleted_at
+type nullTimestamp struct {
+	t **time.Time
+}
+
+func (ts nullTimestamp) Value() (driver.Value, error) {
+
+	if *ts.t == nil {
+		return nil, nil
+	}
+
+	return (*ts.t).UTC(), nil
+}
+
+func (ts nullTimestamp) Scan(src interface{}) error {
+
+	if src == nil {
+		*ts.t = nil
+		return nil
+	}
+
+	parsed, err := parseTime(src)
+
+	if err != nil {
+		return err
+	}
+
+	*ts.t = &parsed
+	return nil
+}
+
+// jsonColumn stores slices and maps as JSON text , like the scopes of an API key or the snapshots of the audit log
+type jsonColumn struct {
+	v interface{}
+}
+
+func (j jsonColumn) Value() (driver.Value, error) {
+
+	data, err := json.Marshal(j.v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (j jsonColumn) Scan(src interface{}) error {
+
+	if src == nil {
+		return nil
+	}
+
+	data, err := text(src)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(data), j.v)
+}
+
+func text(src interface{}) (string, error) {
+
+	switch value := src.(type) {
+	case string:
+		return value, nil
+	case []byte:
+		return string(value), nil
+	}
+
+	return "", fmt.Errorf("expected text , got %T", src)
+}
+
+// parseTime reads what the drivers return for a timestamp column , sqlite hands back text when the column was written by something else
+func parseTime(src interface{}) (time.Time, error) {
+
+	if t, ok := src.(time.Time); ok {
+		return t.UTC(), nil
+	}
+
+	value, err := text(src)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is not a timestamp", value)
+}
@@ -0,0 +1,226 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-restaurent-management-system/config"
+	"go-restaurent-management-system/repository"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Dialect is the database behind the sql backend , the queries are written once and only the placeholders and a few column types differ
+type Dialect string
+
+const (
+	SQLITE   Dialect = "sqlite"
+	POSTGRES Dialect = "postgres"
+)
+
+// DEFAULT_SQLITE_DSN is the database file of a single store install when no dsn is configured
+const DEFAULT_SQLITE_DSN = "restaurent.db"
+
+// DB is the connection pool together with its dialect
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open connects to the sqlite file or the postgres server of the configuration and pings it , like database.Connect does for mongodb.
+// sqlite gets a single connection , it only has one writer at a time anyway and this way the writes queue up instead of failing with "database is locked"
+func Open(ctx context.Context, backend string, cfg config.Sql) (*DB, error) {
+
+	var db *sql.DB
+	var dialect Dialect
+	var err error
+
+	switch backend {
+
+	case config.BACKEND_SQLITE:
+		dsn := cfg.Dsn
+
+		if dsn == "" {
+			dsn = DEFAULT_SQLITE_DSN
+		}
+
+		dialect = SQLITE
+		db, err = sql.Open("sqlite3", dsn)
+
+		if err == nil {
+			db.SetMaxOpenConns(1)
+		}
+
+	case config.BACKEND_POSTGRES:
+		dialect = POSTGRES
+		db, err = sql.Open("postgres", cfg.Dsn)
+
+		if err == nil && cfg.Max_open_conns > 0 {
+			db.SetMaxOpenConns(cfg.Max_open_conns)
+		}
+
+	default:
+		return nil, fmt.Errorf("%q is not a sql backend", backend)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not reach the %s database: %w", dialect, err)
+	}
+
+	return &DB{DB: db, Dialect: dialect}, nil
+}
+
+// rebind turns the ? placeholders the queries are written with into $1 , $2 ... for postgres
+func (db *DB) rebind(query string) string {
+
+	if db.Dialect != POSTGRES {
+		return query
+	}
+
+	var rebound strings.Builder
+	n := 0
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+			rebound.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		rebound.WriteRune(r)
+	}
+
+	return rebound.String()
+}
+
+// limit is the LIMIT and OFFSET of a page , a limit of zero returns everything after skip
+func (db *DB) limit(skip int64, limit int64) string {
+
+	if limit > 0 {
+		return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, skip)
+	}
+
+	if skip == 0 {
+		return ""
+	}
+
+	// sqlite has no OFFSET without a LIMIT , -1 is no limit
+	if db.Dialect == SQLITE {
+		return fmt.Sprintf(" LIMIT -1 OFFSET %d", skip)
+	}
+
+	return fmt.Sprintf(" OFFSET %d", skip)
+}
+
+// querier is a *sql.DB or a *sql.Tx , the repositories run the same queries inside and outside of transactions
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey is the key of the transaction in the context handed to the function of transaction
+type txKey struct{}
+
+// conn is the transaction ctx belongs to , or the pool outside of one.
+// every query has to go through it , sqlite has a single connection and a query on the pool would wait for the transaction forever
+func (db *DB) conn(ctx context.Context) querier {
+
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return db.DB
+}
+
+// transaction runs fn in a transaction , it is committed when fn returns nil and rolled back otherwise.
+// inside a transaction fn simply joins it
+func (db *DB) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// where collects the conditions of a query , args are the values of their ? placeholders in the same order
+type where struct {
+	conditions []string
+	args       []interface{}
+}
+
+// inBranch starts the conditions of a query with the branch , an empty branch id is the head office reading every branch
+func inBranch(branchId string) where {
+
+	if branchId == "" {
+		return where{}
+	}
+
+	return where{}.and("branch_id = ?", branchId)
+}
+
+// and returns a copy with one more condition , the conditions of the caller are never changed
+func (w where) and(condition string, args ...interface{}) where {
+	return where{
+		conditions: append(append([]string{}, w.conditions...), condition),
+		args:       append(append([]interface{}{}, w.args...), args...),
+	}
+}
+
+// visible leaves the soft deleted rows out unless includeDeleted is set
+func (w where) visible(includeDeleted bool) where {
+
+	if includeDeleted {
+		return w
+	}
+
+	return w.and("deleted_at IS NULL")
+}
+
+func (w where) sql() string {
+
+	if len(w.conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// duplicate turns the error of a write refused by a unique constraint into repository.ErrDuplicate
+func duplicate(err error) error {
+
+	var sqliteErr sqlite3.Error
+
+	if errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		return repository.ErrDuplicate
+	}
+
+	var postgresErr *pq.Error
+
+	if errors.As(err, &postgresErr) && postgresErr.Code == "23505" {
+		return repository.ErrDuplicate
+	}
+
+	return err
+}
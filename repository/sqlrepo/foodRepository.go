@@ -0,0 +1,30 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+type foodRepository struct {
+	table[models.Food]
+}
+
+func (r foodRepository) List(ctx context.Context, branchId string, skip int64, limit int64, includeDeleted bool) ([]models.Food, int64, error) {
+	return r.page(ctx, inBranch(branchId).visible(includeDeleted), skip, limit)
+}
+
+func (r foodRepository) Get(ctx context.Context, branchId string, foodId string, includeDeleted bool) (*models.Food, error) {
+	return r.findOne(ctx, inBranch(branchId).and("food_id = ?", foodId).visible(includeDeleted))
+}
+
+func (r foodRepository) ListByMenu(ctx context.Context, branchId string, menuId string) ([]models.Food, error) {
+	return r.find(ctx, inBranch(branchId).and("menu_id = ?", menuId).visible(false))
+}
+
+func (r foodRepository) Create(ctx context.Context, food *models.Food) error {
+	return r.insert(ctx, food)
+}
+
+func (r foodRepository) Update(ctx context.Context, food *models.Food) error {
+	return r.replace(ctx, food.Food_id, food, &food.Version)
+}
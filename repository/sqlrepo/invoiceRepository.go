@@ -0,0 +1,30 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+type invoiceRepository struct {
+	table[models.Invoice]
+}
+
+func (r invoiceRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Invoice, error) {
+	return r.find(ctx, inBranch(branchId).visible(includeDeleted))
+}
+
+func (r invoiceRepository) Get(ctx context.Context, branchId string, invoiceId string, includeDeleted bool) (*models.Invoice, error) {
+	return r.findOne(ctx, inBranch(branchId).and("invoice_id = ?", invoiceId).visible(includeDeleted))
+}
+
+func (r invoiceRepository) ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.Invoice, error) {
+	return r.find(ctx, inBranch(branchId).and("order_id = ?", orderId).visible(false))
+}
+
+func (r invoiceRepository) Create(ctx context.Context, invoice *models.Invoice) error {
+	return r.insert(ctx, invoice)
+}
+
+func (r invoiceRepository) Update(ctx context.Context, invoice *models.Invoice) error {
+	return r.replace(ctx, invoice.Invoice_id, invoice, &invoice.Version)
+}
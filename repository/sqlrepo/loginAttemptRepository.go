@@ -0,0 +1,69 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+)
+
+type loginAttemptRepository struct {
+	db *DB
+}
+
+func (r loginAttemptRepository) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+
+	var attempt models.LoginAttempt
+
+	err := r.db.conn(ctx).QueryRowContext(ctx, r.db.rebind("SELECT attempt_key, failures, last_failure_at, locked_until FROM login_attempts WHERE attempt_key = ?"), key).
+		Scan(&attempt.Key, &attempt.Failures, timestamp{&attempt.Last_failure_at}, timestamp{&attempt.Locked_until})
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+
+	// a failure long after the previous one starts counting from zero again
+	windowStart := at.Add(-window)
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE login_attempts SET failures = 0 WHERE attempt_key = ? AND last_failure_at < ?"), key, timestamp{&windowStart})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite and postgres both know ON CONFLICT , the first failure inserts the row and the later ones count up
+	var never time.Time
+
+	_, err = r.db.conn(ctx).ExecContext(ctx, r.db.rebind(`INSERT INTO login_attempts (attempt_key, failures, last_failure_at, locked_until) VALUES (?, 1, ?, ?)
+		ON CONFLICT (attempt_key) DO UPDATE SET failures = login_attempts.failures + 1, last_failure_at = excluded.last_failure_at`), key, timestamp{&at}, timestamp{&never})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Get(ctx, key)
+}
+
+func (r loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE login_attempts SET locked_until = ? WHERE attempt_key = ?"), timestamp{&until}, key)
+
+	return err
+}
+
+func (r loginAttemptRepository) Delete(ctx context.Context, key string) error {
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind("DELETE FROM login_attempts WHERE attempt_key = ?"), key)
+
+	return err
+}
@@ -0,0 +1,26 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+type menuRepository struct {
+	table[models.Menu]
+}
+
+func (r menuRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Menu, error) {
+	return r.find(ctx, inBranch(branchId).visible(includeDeleted))
+}
+
+func (r menuRepository) Get(ctx context.Context, branchId string, menuId string, includeDeleted bool) (*models.Menu, error) {
+	return r.findOne(ctx, inBranch(branchId).and("menu_id = ?", menuId).visible(includeDeleted))
+}
+
+func (r menuRepository) Create(ctx context.Context, menu *models.Menu) error {
+	return r.insert(ctx, menu)
+}
+
+func (r menuRepository) Update(ctx context.Context, menu *models.Menu) error {
+	return r.replace(ctx, menu.Menu_id, menu, &menu.Version)
+}
@@ -0,0 +1,98 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+type orderItemRepository struct {
+	table[models.OrderItem]
+}
+
+func (r orderItemRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.OrderItem, error) {
+	return r.find(ctx, inBranch(branchId).visible(includeDeleted))
+}
+
+func (r orderItemRepository) Get(ctx context.Context, branchId string, orderItemId string, includeDeleted bool) (*models.OrderItem, error) {
+	return r.findOne(ctx, inBranch(branchId).and("order_item_id = ?", orderItemId).visible(includeDeleted))
+}
+
+func (r orderItemRepository) ListByOrder(ctx context.Context, branchId string, orderId string) ([]models.OrderItem, error) {
+	return r.find(ctx, inBranch(branchId).and("order_id = ?", orderId).visible(false))
+}
+
+func (r orderItemRepository) ListByFood(ctx context.Context, branchId string, foodId string) ([]models.OrderItem, error) {
+	return r.find(ctx, inBranch(branchId).and("food_id = ?", foodId).visible(false))
+}
+
+// CreateMany inserts all the items or none of them
+func (r orderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+
+	return r.db.transaction(ctx, func(ctx context.Context) error {
+
+		for i := range orderItems {
+			if err := r.insert(ctx, &orderItems[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r orderItemRepository) Update(ctx context.Context, orderItem *models.OrderItem) error {
+	return r.replace(ctx, orderItem.Order_item_id, orderItem, &orderItem.Version)
+}
+
+// ITEMS_BY_ORDER joins the items of an order with their foods , the order and its table , like the $lookup stages of the mongodb backend.
+// the joins are LEFT JOINs , an item whose food is gone is still listed. the price is the unit price of the item , which is the price of its size ,
+// the price of the food is only there for items without one
+const ITEMS_BY_ORDER = `SELECT oi.order_item_id, oi.order_id, oi.food_id, oi.quantity, oi.modifiers,
+		f.name, f.food_image, COALESCE(oi.unit_price, f.price), COALESCE(oi.unit_price, f.price, 0),
+		t.table_id, t.table_number
+	FROM order_items oi
+	LEFT JOIN foods f ON f.food_id = oi.food_id
+	LEFT JOIN orders o ON o.order_id = oi.order_id
+	LEFT JOIN tables t ON t.table_id = o.table_id`
+
+// ItemsByOrder reads the joined items , the totals are added up by repository.Summarize
+func (r orderItemRepository) ItemsByOrder(ctx context.Context, branchId string, orderId string) (*repository.OrderSummary, error) {
+
+	w := where{}.and("oi.order_id = ?", orderId).and("oi.deleted_at IS NULL")
+
+	if branchId != "" {
+		w = w.and("oi.branch_id = ?", branchId)
+	}
+
+	rows, err := r.db.conn(ctx).QueryContext(ctx, r.db.rebind(ITEMS_BY_ORDER+w.sql()+" ORDER BY oi.seq"), w.args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []repository.OrderSummaryItem{}
+
+	for rows.Next() {
+
+		var item repository.OrderSummaryItem
+
+		err := rows.Scan(&item.Order_item_id, &item.Order_id, &item.Food_id, &item.Quantity, jsonColumn{&item.Modifiers},
+			&item.Food_name, &item.Food_image, &item.Price, &item.Amount,
+			&item.Table_id, &item.Table_number)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return repository.Summarize(orderId, items)
+}
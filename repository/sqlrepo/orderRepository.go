@@ -0,0 +1,51 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+// orderRepository writes to the order items as well , an order and its items are created in one transaction
+type orderRepository struct {
+	table[models.Order]
+	items table[models.OrderItem]
+}
+
+func (r orderRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Order, error) {
+	return r.find(ctx, inBranch(branchId).visible(includeDeleted))
+}
+
+func (r orderRepository) Get(ctx context.Context, branchId string, orderId string, includeDeleted bool) (*models.Order, error) {
+	return r.findOne(ctx, inBranch(branchId).and("order_id = ?", orderId).visible(includeDeleted))
+}
+
+func (r orderRepository) ListByTable(ctx context.Context, branchId string, tableId string) ([]models.Order, error) {
+	return r.find(ctx, inBranch(branchId).and("table_id = ?", tableId).visible(false))
+}
+
+func (r orderRepository) Create(ctx context.Context, order *models.Order) error {
+	return r.insert(ctx, order)
+}
+
+// CreateWithItems inserts the order and its items in one transaction , an item that can't be inserted rolls back the order as well
+func (r orderRepository) CreateWithItems(ctx context.Context, order *models.Order, orderItems []models.OrderItem) error {
+
+	return r.db.transaction(ctx, func(ctx context.Context) error {
+
+		if err := r.insert(ctx, order); err != nil {
+			return err
+		}
+
+		for i := range orderItems {
+			if err := r.items.insert(ctx, &orderItems[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r orderRepository) Update(ctx context.Context, order *models.Order) error {
+	return r.replace(ctx, order.Order_id, order, &order.Version)
+}
@@ -0,0 +1,40 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+)
+
+type passwordResetRepository struct {
+	table[models.PasswordReset]
+}
+
+func (r passwordResetRepository) Create(ctx context.Context, reset *models.PasswordReset) error {
+	return r.insert(ctx, reset)
+}
+
+func (r passwordResetRepository) InvalidateAll(ctx context.Context, userId string, at time.Time) error {
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE password_resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL"), timestamp{&at}, userId)
+
+	return err
+}
+
+// Redeem marks the token as used with the same UPDATE that checks it , so it can't be redeemed twice
+func (r passwordResetRepository) Redeem(ctx context.Context, tokenHash string, at time.Time) (*models.PasswordReset, error) {
+
+	redeemed, err := affected(r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE password_resets SET used_at = ? WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?"), timestamp{&at}, tokenHash, timestamp{&at}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !redeemed {
+		return nil, repository.ErrNotFound
+	}
+
+	// the token hash is unique , this is the reset that was just redeemed
+	return r.findOne(ctx, where{}.and("token_hash = ?", tokenHash))
+}
@@ -0,0 +1,51 @@
+package sqlrepo
+
+import (
+	"context"
+	"time"
+)
+
+type revocationRepository struct {
+	db *DB
+}
+
+// RevokeToken also drops the revoked tokens that would have expired by now , mongodb does that with a ttl index
+func (r revocationRepository) RevokeToken(ctx context.Context, tokenId string, expiresAt time.Time) error {
+
+	now := time.Now()
+
+	if _, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind("DELETE FROM revoked_tokens WHERE expires_at < ?"), timestamp{&now}); err != nil {
+		return err
+	}
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind(`INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)
+		ON CONFLICT (token_id) DO UPDATE SET expires_at = excluded.expires_at`), tokenId, timestamp{&expiresAt})
+
+	return err
+}
+
+func (r revocationRepository) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+
+	var count int64
+
+	err := r.db.conn(ctx).QueryRowContext(ctx, r.db.rebind("SELECT COUNT(*) FROM revoked_tokens WHERE token_id = ?"), tokenId).Scan(&count)
+
+	return count > 0, err
+}
+
+func (r revocationRepository) TokenGeneration(ctx context.Context, userId string) (int, error) {
+
+	var generation int
+
+	err := r.db.conn(ctx).QueryRowContext(ctx, r.db.rebind("SELECT generation FROM token_generations WHERE user_id = ?"), userId).Scan(&generation)
+
+	return generation, ignoreNoRows(err)
+}
+
+func (r revocationRepository) RevokeAllTokens(ctx context.Context, userId string) error {
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, r.db.rebind(`INSERT INTO token_generations (user_id, generation) VALUES (?, 1)
+		ON CONFLICT (user_id) DO UPDATE SET generation = token_generations.generation + 1`), userId)
+
+	return err
+}
@@ -0,0 +1,267 @@
+package sqlrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// names of the tables , they are the names of the mongodb collections
+const (
+	BRANCH_TABLE           = "branches"
+	FOOD_TABLE             = "foods"
+	MENU_TABLE             = "menus"
+	TABLE_TABLE            = "tables"
+	ORDER_TABLE            = "orders"
+	ORDER_ITEM_TABLE       = "order_items"
+	INVOICE_TABLE          = "invoices"
+	USER_TABLE             = "users"
+	API_KEY_TABLE          = "api_keys"
+	PASSWORD_RESET_TABLE   = "password_resets"
+	LOGIN_ATTEMPT_TABLE    = "login_attempts"
+	REVOKED_TOKEN_TABLE    = "revoked_tokens"
+	TOKEN_GENERATION_TABLE = "token_generations"
+	AUDIT_TABLE            = "audit_log"
+)
+
+// SCHEMA creates every table and index that is missing , EnsureSchema runs it at startup.
+// {{seq}} and {{time}} are the column types that differ between sqlite and postgres.
+// the unique constraints are the unique indexes of the mongodb backend , the other indexes are the ones its queries need
+var SCHEMA = []string{
+	`CREATE TABLE IF NOT EXISTS branches (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		name TEXT,
+		address TEXT,
+		phone TEXT,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		branch_id TEXT NOT NULL UNIQUE
+	)`,
+	`CREATE TABLE IF NOT EXISTS foods (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		name TEXT,
+		price DOUBLE PRECISION,
+		food_image TEXT,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		deleted_at {{time}},
+		deleted_by TEXT,
+		food_id TEXT NOT NULL UNIQUE,
+		menu_id TEXT,
+		branch_id TEXT NOT NULL,
+		sizes TEXT,
+		modifier_groups TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS foods_branch_id_menu_id ON foods (branch_id, menu_id)`,
+	`CREATE TABLE IF NOT EXISTS menus (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		category TEXT NOT NULL,
+		start_date {{time}},
+		end_date {{time}},
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		deleted_at {{time}},
+		deleted_by TEXT,
+		menu_id TEXT NOT NULL UNIQUE,
+		branch_id TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS menus_branch_id ON menus (branch_id)`,
+	// a table number can be used once in every branch , deleted tables keep theirs so that they can be restored
+	`CREATE TABLE IF NOT EXISTS tables (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		number_of_guests INTEGER,
+		table_number INTEGER,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		deleted_at {{time}},
+		deleted_by TEXT,
+		table_id TEXT NOT NULL UNIQUE,
+		branch_id TEXT NOT NULL,
+		UNIQUE (branch_id, table_number)
+	)`,
+	`CREATE TABLE IF NOT EXISTS orders (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		order_date {{time}} NOT NULL,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		deleted_at {{time}},
+		deleted_by TEXT,
+		order_id TEXT NOT NULL UNIQUE,
+		table_id TEXT,
+		branch_id TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS orders_branch_id_table_id ON orders (branch_id, table_id)`,
+	`CREATE TABLE IF NOT EXISTS order_items (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		quantity TEXT,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		deleted_at {{time}},
+		deleted_by TEXT,
+		food_id TEXT,
+		order_item_id TEXT NOT NULL UNIQUE,
+		order_id TEXT NOT NULL,
+		unit_price DOUBLE PRECISION,
+		branch_id TEXT NOT NULL,
+		modifiers TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS order_items_order_id_branch_id ON order_items (order_id, branch_id)`,
+	`CREATE INDEX IF NOT EXISTS order_items_branch_id_food_id ON order_items (branch_id, food_id)`,
+	`CREATE TABLE IF NOT EXISTS invoices (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		invoice_id TEXT NOT NULL UNIQUE,
+		order_id TEXT NOT NULL,
+		payment_method TEXT,
+		payment_status TEXT,
+		payment_due_date {{time}} NOT NULL,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		deleted_at {{time}},
+		deleted_by TEXT,
+		branch_id TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS invoices_branch_id_order_id ON invoices (branch_id, order_id)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		first_name TEXT,
+		last_name TEXT,
+		password TEXT,
+		email TEXT UNIQUE,
+		avatar TEXT,
+		phone TEXT UNIQUE,
+		role TEXT,
+		token TEXT,
+		refresh_token TEXT,
+		branch_id TEXT,
+		deactivated BOOLEAN NOT NULL DEFAULT FALSE,
+		deactivated_at {{time}},
+		two_factor_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+		totp_secret TEXT,
+		totp_last_step BIGINT NOT NULL DEFAULT 0,
+		recovery_codes TEXT,
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		version INTEGER NOT NULL DEFAULT 0,
+		user_id TEXT NOT NULL UNIQUE
+	)`,
+	`CREATE INDEX IF NOT EXISTS users_branch_id ON users (branch_id)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		name TEXT,
+		scopes TEXT,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		created_by TEXT NOT NULL,
+		last_used_at {{time}},
+		revoked_at {{time}},
+		created_at {{time}} NOT NULL,
+		updated_at {{time}} NOT NULL,
+		api_key_id TEXT NOT NULL UNIQUE,
+		branch_id TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS password_resets (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		user_id TEXT NOT NULL,
+		expires_at {{time}} NOT NULL,
+		used_at {{time}},
+		created_at {{time}} NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS password_resets_user_id ON password_resets (user_id)`,
+	`CREATE TABLE IF NOT EXISTS login_attempts (
+		attempt_key TEXT PRIMARY KEY,
+		failures INTEGER NOT NULL,
+		last_failure_at {{time}} NOT NULL,
+		locked_until {{time}} NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS revoked_tokens (
+		token_id TEXT PRIMARY KEY,
+		expires_at {{time}} NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS revoked_tokens_expires_at ON revoked_tokens (expires_at)`,
+	`CREATE TABLE IF NOT EXISTS token_generations (
+		user_id TEXT PRIMARY KEY,
+		generation INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		seq {{seq}},
+		object_id TEXT NOT NULL,
+		audit_id TEXT NOT NULL,
+		actor_id TEXT NOT NULL,
+		actor_type TEXT NOT NULL,
+		action TEXT NOT NULL,
+		entity_type TEXT NOT NULL,
+		entity_id TEXT NOT NULL,
+		before_snapshot TEXT,
+		after_snapshot TEXT,
+		details TEXT,
+		branch_id TEXT NOT NULL,
+		created_at {{time}} NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_created_at ON audit_log (created_at)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_entity_type_entity_id_created_at ON audit_log (entity_type, entity_id, created_at)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_branch_id_created_at ON audit_log (branch_id, created_at)`,
+}
+
+// ADDED_COLUMNS came after the first version of their table , EnsureSchema adds them to the tables that were created without them
+var ADDED_COLUMNS = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{FOOD_TABLE, "sizes", "TEXT"},
+	{FOOD_TABLE, "modifier_groups", "TEXT"},
+	{ORDER_ITEM_TABLE, "modifiers", "TEXT"},
+}
+
+// EnsureSchema creates the missing tables , indexes and columns , the ones that already exist are left alone.
+// the sql backends are new enough to have no legacy data , cmd/migrate only looks after mongodb
+func EnsureSchema(ctx context.Context, db *DB) error {
+
+	types := strings.NewReplacer("{{seq}}", "INTEGER PRIMARY KEY AUTOINCREMENT", "{{time}}", "TIMESTAMP")
+
+	if db.Dialect == POSTGRES {
+		types = strings.NewReplacer("{{seq}}", "BIGSERIAL PRIMARY KEY", "{{time}}", "TIMESTAMPTZ")
+	}
+
+	for _, statement := range SCHEMA {
+		if _, err := db.ExecContext(ctx, types.Replace(statement)); err != nil {
+			return fmt.Errorf("creating the schema: %w", err)
+		}
+	}
+
+	for _, added := range ADDED_COLUMNS {
+
+		// selecting the column only fails while it is missing
+		rows, err := db.QueryContext(ctx, "SELECT "+added.column+" FROM "+added.table+" LIMIT 0")
+
+		if err == nil {
+			rows.Close()
+			continue
+		}
+
+		if _, err := db.ExecContext(ctx, "ALTER TABLE "+added.table+" ADD COLUMN "+added.column+" "+types.Replace(added.definition)); err != nil {
+			return fmt.Errorf("adding %s.%s: %w", added.table, added.column, err)
+		}
+	}
+
+	return nil
+}
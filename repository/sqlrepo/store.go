@@ -0,0 +1,103 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+// NewStore builds the repositories on top of the sqlite or postgres database main() opened , EnsureSchema has to have run first.
+// there is a table for every model the mongodb backend stores , models.Note is stored by neither
+func NewStore(db *DB) *repository.Store {
+
+	orders := table[models.Order]{db: db, name: ORDER_TABLE, idColumn: "order_id", columns: ORDER_COLUMNS, fields: orderFields}
+	orderItems := table[models.OrderItem]{db: db, name: ORDER_ITEM_TABLE, idColumn: "order_item_id", columns: ORDER_ITEM_COLUMNS, fields: orderItemFields}
+
+	return &repository.Store{
+		Branches:       branchRepository{table[models.Branch]{db: db, name: BRANCH_TABLE, idColumn: "branch_id", columns: BRANCH_COLUMNS, fields: branchFields}},
+		Foods:          foodRepository{table[models.Food]{db: db, name: FOOD_TABLE, idColumn: "food_id", columns: FOOD_COLUMNS, fields: foodFields}},
+		Menus:          menuRepository{table[models.Menu]{db: db, name: MENU_TABLE, idColumn: "menu_id", columns: MENU_COLUMNS, fields: menuFields}},
+		Tables:         tableRepository{table[models.Table]{db: db, name: TABLE_TABLE, idColumn: "table_id", columns: TABLE_COLUMNS, fields: tableFields}},
+		Orders:         orderRepository{table: orders, items: orderItems},
+		OrderItems:     orderItemRepository{orderItems},
+		Invoices:       invoiceRepository{table[models.Invoice]{db: db, name: INVOICE_TABLE, idColumn: "invoice_id", columns: INVOICE_COLUMNS, fields: invoiceFields}},
+		Users:          userRepository{table[models.User]{db: db, name: USER_TABLE, idColumn: "user_id", columns: USER_COLUMNS, fields: userFields}},
+		ApiKeys:        apiKeyRepository{table[models.ApiKey]{db: db, name: API_KEY_TABLE, idColumn: "api_key_id", columns: API_KEY_COLUMNS, fields: apiKeyFields}},
+		PasswordResets: passwordResetRepository{table[models.PasswordReset]{db: db, name: PASSWORD_RESET_TABLE, idColumn: "token_hash", columns: PASSWORD_RESET_COLUMNS, fields: passwordResetFields}},
+		LoginAttempts:  loginAttemptRepository{db},
+		Revocations:    revocationRepository{db},
+		Audit:          auditRepository{table[models.AuditEvent]{db: db, name: AUDIT_TABLE, idColumn: "audit_id", columns: AUDIT_COLUMNS, fields: auditFields}},
+		Transaction:    db.transaction,
+		Ping: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+	}
+}
+
+// the columns of every model and the fields they are read into , both lists have to stay in the same order
+
+var BRANCH_COLUMNS = []string{"object_id", "name", "address", "phone", "created_at", "updated_at", "version", "branch_id"}
+
+func branchFields(b *models.Branch) []interface{} {
+	return []interface{}{objectId{&b.ID}, &b.Name, &b.Address, &b.Phone, timestamp{&b.Created_at}, timestamp{&b.Updated_at}, &b.Version, &b.Branch_id}
+}
+
+var FOOD_COLUMNS = []string{"object_id", "name", "price", "food_image", "created_at", "updated_at", "version", "deleted_at", "deleted_by", "food_id", "menu_id", "branch_id", "sizes", "modifier_groups"}
+
+func foodFields(f *models.Food) []interface{} {
+	return []interface{}{objectId{&f.ID}, &f.Name, &f.Price, &f.Food_image, timestamp{&f.Created_at}, timestamp{&f.Updated_at}, &f.Version, nullTimestamp{&f.Deleted_at}, &f.Deleted_by, &f.Food_id, &f.Menu_id, &f.Branch_id, jsonColumn{&f.Sizes}, jsonColumn{&f.Modifier_groups}}
+}
+
+var MENU_COLUMNS = []string{"object_id", "name", "category", "start_date", "end_date", "created_at", "updated_at", "version", "deleted_at", "deleted_by", "menu_id", "branch_id"}
+
+func menuFields(m *models.Menu) []interface{} {
+	return []interface{}{objectId{&m.ID}, &m.Name, &m.Category, nullTimestamp{&m.Start_Date}, nullTimestamp{&m.End_Date}, timestamp{&m.Created_at}, timestamp{&m.Updated_at}, &m.Version, nullTimestamp{&m.Deleted_at}, &m.Deleted_by, &m.Menu_id, &m.Branch_id}
+}
+
+var TABLE_COLUMNS = []string{"object_id", "number_of_guests", "table_number", "created_at", "updated_at", "version", "deleted_at", "deleted_by", "table_id", "branch_id"}
+
+func tableFields(t *models.Table) []interface{} {
+	return []interface{}{objectId{&t.ID}, &t.Number_of_guests, &t.Table_number, timestamp{&t.Created_at}, timestamp{&t.Updated_at}, &t.Version, nullTimestamp{&t.Deleted_at}, &t.Deleted_by, &t.Table_id, &t.Branch_id}
+}
+
+var ORDER_COLUMNS = []string{"object_id", "order_date", "created_at", "updated_at", "version", "deleted_at", "deleted_by", "order_id", "table_id", "branch_id"}
+
+func orderFields(o *models.Order) []interface{} {
+	return []interface{}{objectId{&o.ID}, timestamp{&o.Order_date}, timestamp{&o.Created_at}, timestamp{&o.Updated_at}, &o.Version, nullTimestamp{&o.Deleted_at}, &o.Deleted_by, &o.Order_id, &o.Table_id, &o.Branch_id}
+}
+
+var ORDER_ITEM_COLUMNS = []string{"object_id", "quantity", "created_at", "updated_at", "version", "deleted_at", "deleted_by", "food_id", "order_item_id", "order_id", "unit_price", "branch_id", "modifiers"}
+
+func orderItemFields(i *models.OrderItem) []interface{} {
+	return []interface{}{objectId{&i.ID}, &i.Quantity, timestamp{&i.Created_at}, timestamp{&i.Updated_at}, &i.Version, nullTimestamp{&i.Deleted_at}, &i.Deleted_by, &i.Food_id, &i.Order_item_id, &i.Order_id, &i.Unit_price, &i.Branch_id, jsonColumn{&i.Modifiers}}
+}
+
+var INVOICE_COLUMNS = []string{"object_id", "invoice_id", "order_id", "payment_method", "payment_status", "payment_due_date", "created_at", "updated_at", "version", "deleted_at", "deleted_by", "branch_id"}
+
+func invoiceFields(i *models.Invoice) []interface{} {
+	return []interface{}{objectId{&i.ID}, &i.Invoice_id, &i.Order_id, &i.Payment_method, &i.Payment_status, timestamp{&i.Payment_due_date}, timestamp{&i.Created_at}, timestamp{&i.Updated_at}, &i.Version, nullTimestamp{&i.Deleted_at}, &i.Deleted_by, &i.Branch_id}
+}
+
+var USER_COLUMNS = []string{"object_id", "first_name", "last_name", "password", "email", "avatar", "phone", "role", "token", "refresh_token", "branch_id", "deactivated", "deactivated_at", "two_factor_enabled", "totp_secret", "totp_last_step", "recovery_codes", "created_at", "updated_at", "version", "user_id"}
+
+func userFields(u *models.User) []interface{} {
+	return []interface{}{objectId{&u.ID}, &u.First_name, &u.Last_name, &u.Password, &u.Email, &u.Avatar, &u.Phone, &u.Role, &u.Token, &u.RefreshToken, &u.Branch_id, &u.Deactivated, nullTimestamp{&u.Deactivated_at}, &u.Two_factor_enabled, &u.Totp_secret, &u.Totp_last_step, jsonColumn{&u.Recovery_codes}, timestamp{&u.Created_at}, timestamp{&u.Updated_at}, &u.Version, &u.User_id}
+}
+
+var API_KEY_COLUMNS = []string{"object_id", "name", "scopes", "prefix", "key_hash", "created_by", "last_used_at", "revoked_at", "created_at", "updated_at", "api_key_id", "branch_id"}
+
+func apiKeyFields(k *models.ApiKey) []interface{} {
+	return []interface{}{objectId{&k.ID}, &k.Name, jsonColumn{&k.Scopes}, &k.Prefix, &k.Key_hash, &k.Created_by, nullTimestamp{&k.Last_used_at}, nullTimestamp{&k.Revoked_at}, timestamp{&k.Created_at}, timestamp{&k.Updated_at}, &k.Api_key_id, &k.Branch_id}
+}
+
+var PASSWORD_RESET_COLUMNS = []string{"object_id", "token_hash", "user_id", "expires_at", "used_at", "created_at"}
+
+func passwordResetFields(p *models.PasswordReset) []interface{} {
+	return []interface{}{objectId{&p.ID}, &p.Token_hash, &p.User_id, timestamp{&p.Expires_at}, nullTimestamp{&p.Used_at}, timestamp{&p.Created_at}}
+}
+
+var AUDIT_COLUMNS = []string{"object_id", "audit_id", "actor_id", "actor_type", "action", "entity_type", "entity_id", "before_snapshot", "after_snapshot", "details", "branch_id", "created_at"}
+
+func auditFields(e *models.AuditEvent) []interface{} {
+	return []interface{}{objectId{&e.ID}, &e.Audit_id, &e.Actor_id, &e.Actor_type, &e.Action, &e.Entity_type, &e.Entity_id, jsonColumn{&e.Before}, jsonColumn{&e.After}, jsonColumn{&e.Details}, &e.Branch_id, timestamp{&e.Created_at}}
+}
@@ -0,0 +1,193 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"go-restaurent-management-system/repository"
+	"strings"
+)
+
+// table is what the entity repositories share , like the collection of the mongodb backend.
+// columns are the columns of T in the order fields returns its fields , seq is left out , it only keeps the rows in the order they were created
+type table[T any] struct {
+	db       *DB
+	name     string
+	idColumn string
+	columns  []string
+	fields   func(item *T) []interface{}
+}
+
+func (t table[T]) selectFrom() string {
+	return "SELECT " + strings.Join(t.columns, ", ") + " FROM " + t.name
+}
+
+// find returns the rows in the order they were created
+func (t table[T]) find(ctx context.Context, w where) ([]T, error) {
+	return t.query(ctx, t.selectFrom()+w.sql()+" ORDER BY seq", w.args...)
+}
+
+func (t table[T]) query(ctx context.Context, query string, args ...interface{}) ([]T, error) {
+
+	rows, err := t.db.conn(ctx).QueryContext(ctx, t.db.rebind(query), args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []T{}
+
+	for rows.Next() {
+
+		var item T
+
+		if err := rows.Scan(t.fields(&item)...); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (t table[T]) page(ctx context.Context, w where, skip int64, limit int64) ([]T, int64, error) {
+
+	total, err := t.count(ctx, w)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items, err := t.query(ctx, t.selectFrom()+w.sql()+" ORDER BY seq"+t.db.limit(skip, limit), w.args...)
+
+	return items, total, err
+}
+
+func (t table[T]) count(ctx context.Context, w where) (int64, error) {
+
+	var total int64
+
+	err := t.db.conn(ctx).QueryRowContext(ctx, t.db.rebind("SELECT COUNT(*) FROM "+t.name+w.sql()), w.args...).Scan(&total)
+
+	return total, err
+}
+
+func (t table[T]) findOne(ctx context.Context, w where) (*T, error) {
+
+	var item T
+
+	err := t.db.conn(ctx).QueryRowContext(ctx, t.db.rebind(t.selectFrom()+w.sql()+" ORDER BY seq LIMIT 1"), w.args...).Scan(t.fields(&item)...)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (t table[T]) get(ctx context.Context, branchId string, id string) (*T, error) {
+	return t.findOne(ctx, inBranch(branchId).and(t.idColumn+" = ?", id))
+}
+
+func (t table[T]) insert(ctx context.Context, item *T) error {
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(t.columns)), ", ")
+
+	_, err := t.db.conn(ctx).ExecContext(ctx, t.db.rebind("INSERT INTO "+t.name+" ("+strings.Join(t.columns, ", ")+") VALUES ("+placeholders+")"), t.fields(item)...)
+
+	return duplicate(err)
+}
+
+// replace only writes the item while the stored version is still *version , the version is raised by one with the write.
+// when nothing was written *version is put back
+func (t table[T]) replace(ctx context.Context, id string, item *T, version *int) error {
+	return t.replaceExcept(ctx, id, item, version)
+}
+
+// replaceExcept is replace without the columns in except , they keep what is stored
+func (t table[T]) replaceExcept(ctx context.Context, id string, item *T, version *int, except ...string) error {
+
+	expected := *version
+	*version = expected + 1
+
+	skipped := map[string]bool{}
+
+	for _, column := range except {
+		skipped[column] = true
+	}
+
+	assignments := []string{}
+	args := []interface{}{}
+
+	for i, field := range t.fields(item) {
+		if !skipped[t.columns[i]] {
+			assignments = append(assignments, t.columns[i]+" = ?")
+			args = append(args, field)
+		}
+	}
+
+	args = append(args, id, expected)
+
+	result, err := t.db.conn(ctx).ExecContext(ctx, t.db.rebind("UPDATE "+t.name+" SET "+strings.Join(assignments, ", ")+" WHERE "+t.idColumn+" = ? AND version = ?"), args...)
+
+	if err == nil {
+		err = t.changedOrMissing(ctx, result, id)
+	}
+
+	if err != nil {
+		*version = expected
+		return duplicate(err)
+	}
+
+	return nil
+}
+
+// changedOrMissing tells apart the two reasons why a versioned write didn't change a row
+func (t table[T]) changedOrMissing(ctx context.Context, result sql.Result, id string) error {
+
+	changed, err := result.RowsAffected()
+
+	if err != nil || changed > 0 {
+		return err
+	}
+
+	count, err := t.count(ctx, where{}.and(t.idColumn+" = ?", id))
+
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+
+	return repository.ErrVersionConflict
+}
+
+// affected is the number of rows a conditional update changed , the repositories return it as the "did it happen" of their interface
+func affected(result sql.Result, err error) (bool, error) {
+
+	if err != nil {
+		return false, err
+	}
+
+	changed, err := result.RowsAffected()
+
+	return changed > 0, err
+}
+
+// ignoreNoRows is for the lookups where a missing row is an answer and not an error
+func ignoreNoRows(err error) error {
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,26 @@
+package sqlrepo
+
+import (
+	"context"
+	"go-restaurent-management-system/models"
+)
+
+type tableRepository struct {
+	table[models.Table]
+}
+
+func (r tableRepository) List(ctx context.Context, branchId string, includeDeleted bool) ([]models.Table, error) {
+	return r.find(ctx, inBranch(branchId).visible(includeDeleted))
+}
+
+func (r tableRepository) Get(ctx context.Context, branchId string, tableId string, includeDeleted bool) (*models.Table, error) {
+	return r.findOne(ctx, inBranch(branchId).and("table_id = ?", tableId).visible(includeDeleted))
+}
+
+func (r tableRepository) Create(ctx context.Context, table *models.Table) error {
+	return r.insert(ctx, table)
+}
+
+func (r tableRepository) Update(ctx context.Context, table *models.Table) error {
+	return r.replace(ctx, table.Table_id, table, &table.Version)
+}
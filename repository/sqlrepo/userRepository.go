@@ -0,0 +1,119 @@
+package sqlrepo
+
+import (
+	"context"
+	"encoding/json"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+	"time"
+)
+
+type userRepository struct {
+	table[models.User]
+}
+
+func (r userRepository) List(ctx context.Context, branchId string, skip int64, limit int64) ([]models.User, int64, error) {
+	return r.page(ctx, inBranch(branchId), skip, limit)
+}
+
+func (r userRepository) Get(ctx context.Context, branchId string, userId string) (*models.User, error) {
+	return r.get(ctx, branchId, userId)
+}
+
+func (r userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, where{}.and("email = ?", email))
+}
+
+func (r userRepository) EmailTaken(ctx context.Context, email string, exceptUserId string) (bool, error) {
+	return r.taken(ctx, "email", email, exceptUserId)
+}
+
+func (r userRepository) PhoneTaken(ctx context.Context, phone string, exceptUserId string) (bool, error) {
+	return r.taken(ctx, "phone", phone, exceptUserId)
+}
+
+func (r userRepository) taken(ctx context.Context, column string, value string, exceptUserId string) (bool, error) {
+
+	count, err := r.count(ctx, where{}.and(column+" = ?", value).and("user_id <> ?", exceptUserId))
+
+	return count > 0, err
+}
+
+func (r userRepository) Create(ctx context.Context, user *models.User) error {
+	return r.insert(ctx, user)
+}
+
+// Update sets every column of the user but the token pair , a login that happens at the same time keeps its tokens
+func (r userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.replaceExcept(ctx, user.User_id, user, &user.Version, "object_id", "token", "refresh_token")
+}
+
+func (r userRepository) SetTokens(ctx context.Context, userId string, token string, refreshToken string) error {
+
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	changed, err := affected(r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE users SET token = ?, refresh_token = ?, updated_at = ? WHERE user_id = ?"), token, refreshToken, timestamp{&Updated_at}, userId))
+
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// RotateTokens checks and writes in a single UPDATE , so a refresh token can be exchanged exactly once even when two requests race with it
+func (r userRepository) RotateTokens(ctx context.Context, userId string, usedRefreshToken string, token string, refreshToken string) (bool, error) {
+
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	return affected(r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE users SET token = ?, refresh_token = ?, updated_at = ? WHERE user_id = ? AND refresh_token = ?"), token, refreshToken, timestamp{&Updated_at}, userId, usedRefreshToken))
+}
+
+func (r userRepository) UseTotpStep(ctx context.Context, userId string, step int64) (bool, error) {
+	return affected(r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE users SET totp_last_step = ? WHERE user_id = ? AND totp_last_step < ?"), step, userId, step))
+}
+
+// UseRecoveryCode writes the codes back only while they are still what was read , a second request with the same code finds it gone
+func (r userRepository) UseRecoveryCode(ctx context.Context, userId string, hashedCode string) (bool, error) {
+
+	for {
+
+		var stored *string
+
+		err := r.db.conn(ctx).QueryRowContext(ctx, r.db.rebind("SELECT recovery_codes FROM users WHERE user_id = ?"), userId).Scan(&stored)
+
+		if err != nil || stored == nil {
+			return false, ignoreNoRows(err)
+		}
+
+		var codes []string
+
+		if err := json.Unmarshal([]byte(*stored), &codes); err != nil {
+			return false, err
+		}
+
+		remaining := []string{}
+
+		for _, code := range codes {
+			if code != hashedCode {
+				remaining = append(remaining, code)
+			}
+		}
+
+		if len(remaining) == len(codes) {
+			return false, nil
+		}
+
+		changed, err := affected(r.db.conn(ctx).ExecContext(ctx, r.db.rebind("UPDATE users SET recovery_codes = ? WHERE user_id = ? AND recovery_codes = ?"), jsonColumn{remaining}, userId, *stored))
+
+		if err != nil || changed {
+			return changed, err
+		}
+
+		// somebody else changed the codes in between , read them again
+	}
+}
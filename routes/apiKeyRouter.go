@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ApiKeyRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
+
+	incomingRoutes.GET("/apikeys", middleware.Authorize(models.ROLE_ADMIN), controller.GetApiKeys(store))
+	incomingRoutes.POST("/apikeys", middleware.Authorize(models.ROLE_ADMIN), controller.CreateApiKey(store))
+	incomingRoutes.DELETE("/apikeys/:api_key_id", middleware.Authorize(models.ROLE_ADMIN), controller.RevokeApiKey(store))
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditRoutes only reads , the audit log can't be changed through the API
+func AuditRoutes(incomingRoutes gin.IRouter) {
+
+	incomingRoutes.GET("/audit", middleware.Authorize(models.ROLE_ADMIN, models.ROLE_MANAGER), controller.GetAuditLog())
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthRoutes are reachable without a token , signup still reads a token when one is sent so that admins can create active staff with any role.
+// a signup without a token creates a deactivated waiter that an admin has to approve
+func AuthRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
+
+	incomingRoutes.POST("/users/signup", middleware.OptionalAuthentication(), controller.SignUp(store))
+	incomingRoutes.POST("/users/login", controller.Login(store))
+	incomingRoutes.POST("/users/login/2fa", controller.LoginSecondFactor(store))
+	incomingRoutes.POST("/users/refresh", controller.RefreshToken(store))
+	incomingRoutes.POST("/users/password/forgot", controller.ForgotPassword(store))
+	incomingRoutes.POST("/users/password/reset", controller.ResetPassword(store))
+}
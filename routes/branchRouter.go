@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BranchRoutes can be read by every member of staff , only the head office opens or edits branches
+func BranchRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
+
+	incomingRoutes.GET("/branches", middleware.Authorize(), controller.GetBranches(store))
+	incomingRoutes.GET("/branches/:branch_id", middleware.Authorize(), controller.GetBranch(store))
+	incomingRoutes.POST("/branches", middleware.HeadOffice(), controller.CreateBranch(store))
+	incomingRoutes.PATCH("/branches/:branch_id", middleware.HeadOffice(), controller.UpdateBranch(store))
+}
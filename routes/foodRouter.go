@@ -2,14 +2,19 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func FoodRoutes(incomingRoutes *gin.Engine) {
+func FoodRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/foods", controller.GetFoods())
-	incomingRoutes.GET("/foods/:food_id", controller.GetFood())
-	incomingRoutes.POST("/foods", controller.CreateFood())
-	incomingRoutes.PATCH("/foods/:food_id", controller.UpdateFood())
+	incomingRoutes.GET("/foods", middleware.Permit("foods:read"), controller.GetFoods(store))
+	incomingRoutes.GET("/foods/:food_id", middleware.Permit("foods:read"), controller.GetFood(store))
+	incomingRoutes.POST("/foods", middleware.Permit("foods:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.CreateFood(store))
+	incomingRoutes.PATCH("/foods/:food_id", middleware.Permit("foods:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.UpdateFood(store))
+	incomingRoutes.DELETE("/foods/:food_id", middleware.Permit("foods:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.DeleteFood(store))
+	incomingRoutes.POST("/foods/:food_id/restore", middleware.Authorize(models.ROLE_ADMIN), controller.RestoreFood(store))
 }
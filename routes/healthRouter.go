@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
+
+	incomingRoutes.GET("/health", controller.HealthCheck(store))
+}
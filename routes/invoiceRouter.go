@@ -2,14 +2,19 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InvoiceRoutes(incomingRoutes *gin.Engine) {
+func InvoiceRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/invoices", controller.GetInvoices())
-	incomingRoutes.GET("/invoices/:invoice_id", controller.GetInvoice())
-	incomingRoutes.POST("/invoices", controller.CreateInvoice())
-	incomingRoutes.PATCH("/invoices/:invoice_id", controller.UpdateInvoice())
+	incomingRoutes.GET("/invoices", middleware.Permit("invoices:read", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_CASHIER), controller.GetInvoices(store))
+	incomingRoutes.GET("/invoices/:invoice_id", middleware.Permit("invoices:read", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_CASHIER, models.ROLE_WAITER), controller.GetInvoice(store))
+	incomingRoutes.POST("/invoices", middleware.Permit("invoices:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_CASHIER, models.ROLE_WAITER), controller.CreateInvoice(store))
+	incomingRoutes.PATCH("/invoices/:invoice_id", middleware.Permit("invoices:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_CASHIER), controller.UpdateInvoice(store))
+	incomingRoutes.DELETE("/invoices/:invoice_id", middleware.Permit("invoices:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_CASHIER), controller.DeleteInvoice(store))
+	incomingRoutes.POST("/invoices/:invoice_id/restore", middleware.Authorize(models.ROLE_ADMIN), controller.RestoreInvoice(store))
 }
@@ -2,14 +2,30 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func MenuRoutes(incomingRoutes *gin.Engine) {
+func MenuRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/menus", controller.GetMenus())
-	incomingRoutes.GET("/menus/:menu_id", controller.GetMenu())
-	incomingRoutes.POST("/menus", controller.CreateMenu())
-	incomingRoutes.PATCH("/menus/:menu_id", controller.UpdateMenu())
+	incomingRoutes.GET("/menus", middleware.Permit("menus:read"), controller.GetMenus(store))
+	incomingRoutes.GET("/menus/:menu_id", middleware.Permit("menus:read"), controller.GetMenu(store))
+	incomingRoutes.POST("/menus", middleware.Permit("menus:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.CreateMenu(store))
+	incomingRoutes.PATCH("/menus/:menu_id", middleware.Permit("menus:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.UpdateMenu(store))
+	incomingRoutes.DELETE("/menus/:menu_id", middleware.Permit("menus:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.DeleteMenu(store))
+	incomingRoutes.POST("/menus/:menu_id/restore", middleware.Authorize(models.ROLE_ADMIN), controller.RestoreMenu(store))
+}
+
+// PublicMenuRoutes is the read-only menu for guests , it is registered on the group without authentication.
+// guests pick the branch from /public/branches and pass it as ?branch_id=
+func PublicMenuRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
+
+	incomingRoutes.GET("/public/branches", controller.GetBranches(store))
+	incomingRoutes.GET("/public/menus", controller.GetMenus(store))
+	incomingRoutes.GET("/public/menus/:menu_id", controller.GetMenu(store))
+	incomingRoutes.GET("/public/foods", controller.GetFoods(store))
+	incomingRoutes.GET("/public/foods/:food_id", controller.GetFood(store))
 }
@@ -2,15 +2,20 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func OrderItemRoutes(incomingRoutes *gin.Engine) {
+func OrderItemRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/orderitems", controller.GetOrderItems())
-	incomingRoutes.GET("/orderitems/:orderitem_id", controller.GetOrderItem())
-	incomingRoutes.POST("/orderitems", controller.CreateOrderItem())
-	incomingRoutes.PATCH("/orderitems/:orderitem_id", controller.UpdateOrderItem())
-	incomingRoutes.GET("/orderItems-order/:order_id", controller.GetOrderItemsByOrder())
+	incomingRoutes.GET("/orderitems", middleware.Permit("order_items:read"), controller.GetOrderItems(store))
+	incomingRoutes.GET("/orderitems/:order_item_id", middleware.Permit("order_items:read"), controller.GetOrderItem(store))
+	incomingRoutes.POST("/orderitems", middleware.Permit("order_items:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER), controller.CreateOrderItem(store))
+	incomingRoutes.PATCH("/orderitems/:order_item_id", middleware.Permit("order_items:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER, models.ROLE_CHEF), controller.UpdateOrderItem(store))
+	incomingRoutes.DELETE("/orderitems/:order_item_id", middleware.Permit("order_items:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER), controller.DeleteOrderItem(store))
+	incomingRoutes.POST("/orderitems/:order_item_id/restore", middleware.Authorize(models.ROLE_ADMIN), controller.RestoreOrderItem(store))
+	incomingRoutes.GET("/orderItems-order/:order_id", middleware.Permit("order_items:read"), controller.GetOrderItemsByOrder(store))
 }
@@ -2,14 +2,19 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func OrderRoutes(incomingRoutes *gin.Engine) {
+func OrderRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/orders", controller.GetOrders())
-	incomingRoutes.GET("/orders/:order_id", controller.GetOrder())
-	incomingRoutes.POST("/orders", controller.CreateOrder())
-	incomingRoutes.PATCH("/orders/:order_id", controller.UpdateOrder())
+	incomingRoutes.GET("/orders", middleware.Permit("orders:read"), controller.GetOrders(store))
+	incomingRoutes.GET("/orders/:order_id", middleware.Permit("orders:read"), controller.GetOrder(store))
+	incomingRoutes.POST("/orders", middleware.Permit("orders:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER), controller.CreateOrder(store))
+	incomingRoutes.PATCH("/orders/:order_id", middleware.Permit("orders:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER), controller.UpdateOrder(store))
+	incomingRoutes.DELETE("/orders/:order_id", middleware.Permit("orders:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER), controller.DeleteOrder(store))
+	incomingRoutes.POST("/orders/:order_id/restore", middleware.Authorize(models.ROLE_ADMIN), controller.RestoreOrder(store))
 }
@@ -2,14 +2,19 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func TableRoutes(incomingRoutes *gin.Engine) {
+func TableRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/tables", controller.GetTables())
-	incomingRoutes.GET("/tables/:table_id", controller.GetTable())
-	incomingRoutes.POST("/tables", controller.CreateTable())
-	incomingRoutes.PATCH("/tables/:table_id", controller.UpdateTable())
+	incomingRoutes.GET("/tables", middleware.Permit("tables:read"), controller.GetTables(store))
+	incomingRoutes.GET("/tables/:table_id", middleware.Permit("tables:read"), controller.GetTable(store))
+	incomingRoutes.POST("/tables", middleware.Permit("tables:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.CreateTable(store))
+	incomingRoutes.PATCH("/tables/:table_id", middleware.Permit("tables:write", models.ROLE_ADMIN, models.ROLE_MANAGER, models.ROLE_WAITER), controller.UpdateTable(store))
+	incomingRoutes.DELETE("/tables/:table_id", middleware.Permit("tables:write", models.ROLE_ADMIN, models.ROLE_MANAGER), controller.DeleteTable(store))
+	incomingRoutes.POST("/tables/:table_id/restore", middleware.Authorize(models.ROLE_ADMIN), controller.RestoreTable(store))
 }
@@ -2,14 +2,25 @@ package routes
 
 import (
 	controller "go-restaurent-management-system/controllers"
+	"go-restaurent-management-system/middleware"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(incomingRoutes *gin.Engine) {
+func UserRoutes(incomingRoutes gin.IRouter, store *repository.Store) {
 
-	incomingRoutes.GET("/users/", controller.GetUsers())
-	incomingRoutes.GET("/users/user_id", controller.GetUser())
-	incomingRoutes.POST("/users/signup", controller.SignUp())
-	incomingRoutes.POST("/users/login", controller.Login())
+	incomingRoutes.GET("/users/", middleware.Authorize(models.ROLE_ADMIN, models.ROLE_MANAGER), controller.GetUsers(store))
+	incomingRoutes.GET("/users/:user_id", middleware.Authorize(models.ROLE_ADMIN, models.ROLE_MANAGER), controller.GetUser(store))
+	incomingRoutes.PATCH("/users/:user_id", middleware.Authorize(models.ROLE_ADMIN), controller.UpdateUser(store))
+	incomingRoutes.POST("/users/:user_id/deactivate", middleware.Authorize(models.ROLE_ADMIN), controller.DeactivateUser(store))
+	incomingRoutes.POST("/users/:user_id/reactivate", middleware.Authorize(models.ROLE_ADMIN), controller.ReactivateUser(store))
+	incomingRoutes.POST("/users/password/change", middleware.Authorize(), controller.ChangePassword(store))
+	incomingRoutes.POST("/users/logout", middleware.Authorize(), controller.Logout())
+	incomingRoutes.POST("/users/2fa/enroll", middleware.Authorize(models.ROLE_ADMIN, models.ROLE_MANAGER), controller.EnrollTwoFactor(store))
+	incomingRoutes.POST("/users/2fa/confirm", middleware.Authorize(models.ROLE_ADMIN, models.ROLE_MANAGER), controller.ConfirmTwoFactor(store))
+	incomingRoutes.POST("/users/2fa/disable", middleware.Authorize(models.ROLE_ADMIN, models.ROLE_MANAGER), controller.DisableTwoFactor(store))
+	incomingRoutes.POST("/users/:user_id/revoke", middleware.Authorize(models.ROLE_ADMIN), controller.RevokeUserSessions(store))
+	incomingRoutes.POST("/users/:user_id/unlock", middleware.Authorize(models.ROLE_ADMIN), controller.UnlockUser(store))
 }
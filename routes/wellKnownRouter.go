@@ -0,0 +1,12 @@
+package routes
+
+import (
+	controller "go-restaurent-management-system/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoutes(incomingRoutes gin.IRouter) {
+
+	incomingRoutes.GET("/.well-known/jwks.json", controller.GetJWKS())
+}
@@ -0,0 +1,85 @@
+package seed
+
+// the names the demo restaurant is made of , the generator picks from these lists in order so that the same seed always picks the same
+
+type branchTemplate struct {
+	name    string
+	address string
+	phone   string
+	// busy scales the number of orders , the branch in the centre sells more than the one in the suburbs
+	busy float64
+}
+
+var BRANCHES = []branchTemplate{
+	{name: "Harbour Street", address: "12 Harbour Street", phone: "+44 20 7946 0101", busy: 1.3},
+	{name: "Old Town", address: "4 Market Square", phone: "+44 20 7946 0202", busy: 1.0},
+	{name: "Riverside", address: "88 River Walk", phone: "+44 20 7946 0303", busy: 0.8},
+	{name: "Station Road", address: "1 Station Road", phone: "+44 20 7946 0404", busy: 0.7},
+}
+
+type foodTemplate struct {
+	name  string
+	price float64
+	// popularity weights how often the food is ordered compared to the others of its menu
+	popularity int
+}
+
+type menuTemplate struct {
+	name     string
+	category string
+	foods    []foodTemplate
+	// per_guest is the chance that a guest orders from this menu
+	per_guest float64
+	// sizes are the sizes the foods of the menu are sold in , the others are marked as not available
+	sizes []string
+}
+
+var MENUS = []menuTemplate{
+	{name: "Starters", category: "STARTER", per_guest: 0.45, sizes: []string{"M", "L"}, foods: []foodTemplate{
+		{"Tomato Soup", 5.50, 5},
+		{"Garlic Bread", 4.00, 8},
+		{"Caesar Salad", 7.50, 5},
+		{"Calamari", 8.50, 4},
+		{"Bruschetta", 6.00, 4},
+		{"Chicken Wings", 7.00, 6},
+	}},
+	{name: "Mains", category: "MAIN", per_guest: 0.95, sizes: []string{"S", "M", "L"}, foods: []foodTemplate{
+		{"Margherita Pizza", 11.00, 9},
+		{"Beef Burger", 13.50, 9},
+		{"Fish and Chips", 14.00, 7},
+		{"Spaghetti Carbonara", 12.50, 6},
+		{"Chicken Curry", 13.00, 6},
+		{"Ribeye Steak", 24.00, 3},
+		{"Mushroom Risotto", 12.00, 4},
+		{"Grilled Salmon", 18.50, 4},
+	}},
+	{name: "Desserts", category: "DESSERT", per_guest: 0.35, sizes: []string{"S", "M"}, foods: []foodTemplate{
+		{"Chocolate Brownie", 6.50, 7},
+		{"Cheesecake", 6.00, 5},
+		{"Tiramisu", 6.50, 5},
+		{"Ice Cream", 4.50, 6},
+	}},
+	{name: "Drinks", category: "DRINK", per_guest: 1.2, sizes: []string{"S", "M", "L"}, foods: []foodTemplate{
+		{"Sparkling Water", 2.50, 6},
+		{"Cola", 3.00, 8},
+		{"Lemonade", 3.50, 5},
+		{"Espresso", 2.50, 6},
+		{"Cappuccino", 3.50, 6},
+		{"House Red Wine", 6.50, 4},
+		{"Lager", 5.00, 7},
+	}},
+}
+
+type staffTemplate struct {
+	first_name string
+	last_name  string
+}
+
+// STAFF are handed out to the branches in turn , the roles come from STAFF_ROLES
+var STAFF = []staffTemplate{
+	{"Amelia", "Hughes"}, {"Oliver", "Patel"}, {"Isla", "Murphy"}, {"Noah", "Kowalski"}, {"Mia", "Okafor"},
+	{"Leo", "Rossi"}, {"Ava", "Schmidt"}, {"Jack", "Nguyen"}, {"Grace", "Silva"}, {"Arthur", "Cohen"},
+	{"Freya", "Ahmed"}, {"Oscar", "Larsen"}, {"Lily", "Dubois"}, {"Henry", "Tanaka"}, {"Ella", "Moreno"},
+	{"Theo", "Byrne"}, {"Ivy", "Novak"}, {"Finn", "Hassan"}, {"Rosie", "Jensen"}, {"Alfie", "Costa"},
+	{"Evie", "Walsh"}, {"Harry", "Kim"}, {"Poppy", "Fischer"}, {"Max", "Ortiz"},
+}
@@ -0,0 +1,389 @@
+package seed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"go-restaurent-management-system/models"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the defaults of restaurantctl seed
+const (
+	DEFAULT_SEED     = 1
+	DEFAULT_BRANCHES = 2
+	DEFAULT_WEEKS    = 6
+	// the default of -until is a fixed day , so running restaurantctl seed without flags gives the same data on any day
+	DEFAULT_UNTIL = "2025-06-29"
+	DEMO_PASSWORD = "demo-password"
+	EMAIL_DOMAIN  = "demo.restaurant"
+	// the picture of a food is looked up by its name , so the same food always gets the same picture
+	IMAGE_URL = "https://picsum.photos/seed/%s/640/480"
+	TABLES    = 12
+)
+
+// CATALOGUE_CREATED is when the branches , users , menus , foods and tables were set up , the orders start after it.
+// it doesn't move with the date range , so their ids and emails stay the same when the same seed is run with another -until
+var CATALOGUE_CREATED = time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// STAFF_ROLES are the users of every branch , the head office gets one ADMIN without a branch on top
+var STAFF_ROLES = []string{models.ROLE_MANAGER, models.ROLE_WAITER, models.ROLE_WAITER, models.ROLE_WAITER, models.ROLE_CHEF, models.ROLE_CHEF, models.ROLE_CASHIER}
+
+// how often the sizes of models.SIZES are picked , and what they cost compared to the price of the food
+var SIZE_WEIGHTS = []int{2, 6, 3}
+var SIZE_FACTORS = []float64{0.75, 1, 1.3}
+
+// Options decide what is generated , the same options always give the same data.
+// the catalogue only depends on Seed and Branches , the orders of a day only on Seed , the branch and the day
+type Options struct {
+	Seed     int64
+	Branches int
+	Weeks    int
+	// Until is the day the orders end , the last orders of that day are still waiting for their payment
+	Until time.Time
+	// Password_hash is stored for every user , the hash is made by the caller so that this package doesn't need bcrypt
+	Password_hash string
+}
+
+// Data is a whole demo restaurant , the records refer to each other by their ids like the ones the API creates
+type Data struct {
+	Branches    []models.Branch
+	Users       []models.User
+	Menus       []models.Menu
+	Foods       []models.Food
+	Tables      []models.Table
+	Orders      []models.Order
+	Order_items map[string][]models.OrderItem
+	Invoices    []models.Invoice
+}
+
+type generator struct {
+	rand *rand.Rand
+	opts Options
+	data *Data
+}
+
+// Generate builds the demo restaurant of opts , nothing is written. the ids are made from the seed as well ,
+// so generating the same options twice gives the same ids , and a later Until only adds the orders of the new days
+func Generate(opts Options) (*Data, error) {
+
+	if opts.Branches < 1 || opts.Branches > len(BRANCHES) {
+		return nil, fmt.Errorf("the number of branches has to be between 1 and %d", len(BRANCHES))
+	}
+
+	if opts.Weeks < 1 {
+		return nil, fmt.Errorf("the number of weeks has to be at least 1")
+	}
+
+	until := time.Date(opts.Until.Year(), opts.Until.Month(), opts.Until.Day(), 0, 0, 0, 0, time.UTC)
+	from := until.AddDate(0, 0, -7*opts.Weeks+1)
+
+	// the catalogue was set up before the first order
+	if !from.After(CATALOGUE_CREATED) {
+		return nil, fmt.Errorf("the orders have to start after %s , pick a later until or fewer weeks", CATALOGUE_CREATED.Format("2006-01-02"))
+	}
+
+	g := &generator{rand: rand.New(rand.NewSource(opts.Seed)), opts: opts, data: &Data{Order_items: map[string][]models.OrderItem{}}}
+	setUp := CATALOGUE_CREATED
+
+	admin := g.user(setUp, STAFF[len(STAFF)-1], models.ROLE_ADMIN, nil, "admin@"+EMAIL_DOMAIN)
+	g.data.Users = append(g.data.Users, admin)
+
+	staff := 0
+	menus := make([]map[string][]choice, opts.Branches)
+	tables := make([][]models.Table, opts.Branches)
+
+	for b := 0; b < opts.Branches; b++ {
+
+		template := BRANCHES[b]
+		branch := models.Branch{ID: g.objectId(setUp), Name: &template.name, Address: &template.address, Phone: &template.phone, Created_at: setUp, Updated_at: setUp, Version: 1}
+		branch.Branch_id = branch.ID.Hex()
+		g.data.Branches = append(g.data.Branches, branch)
+
+		slug := slugify(template.name)
+
+		for i, role := range STAFF_ROLES {
+			branchId := branch.Branch_id
+			email := fmt.Sprintf("%s%d.%s@%s", strings.ToLower(role), i+1, slug, EMAIL_DOMAIN)
+			g.data.Users = append(g.data.Users, g.user(setUp, STAFF[staff%(len(STAFF)-1)], role, &branchId, email))
+			staff++
+		}
+
+		menus[b] = g.catalogue(setUp, branch.Branch_id)
+		tables[b] = g.tables(setUp, branch.Branch_id)
+	}
+
+	// every day gets random numbers of its own , so the orders of a day are the same whatever the range around it is
+
+	for b, branch := range g.data.Branches {
+		for day := from; !day.After(until); day = day.AddDate(0, 0, 1) {
+			g.rand = rand.New(rand.NewSource(opts.Seed ^ int64(b+1)<<40 ^ day.Unix()))
+			g.day(day, until, BRANCHES[b].busy, branch.Branch_id, menus[b], tables[b])
+		}
+	}
+
+	return g.data, nil
+}
+
+// choice is a food of the menu with the weight it is picked with
+type choice struct {
+	food   models.Food
+	weight int
+}
+
+func (g *generator) catalogue(at time.Time, branchId string) map[string][]choice {
+
+	menu := map[string][]choice{}
+
+	for _, template := range MENUS {
+
+		m := models.Menu{ID: g.objectId(at), Name: template.name, Category: template.category, Created_at: at, Updated_at: at, Version: 1, Branch_id: branchId}
+		m.Menu_id = m.ID.Hex()
+		g.data.Menus = append(g.data.Menus, m)
+
+		for _, food := range template.foods {
+
+			// every branch prices the foods a little differently , rounded to 50 cents like a real menu
+			price := math.Round(food.price*(0.9+g.rand.Float64()*0.25)*2) / 2
+			name := food.name
+			image := fmt.Sprintf(IMAGE_URL, slugify(food.name))
+			menuId := m.Menu_id
+
+			f := models.Food{ID: g.objectId(at), Name: &name, Price: &price, Food_image: &image, Created_at: at, Updated_at: at, Version: 1, Menu_id: &menuId, Branch_id: branchId, Sizes: sizes(price, template.sizes)}
+			f.Food_id = f.ID.Hex()
+			g.data.Foods = append(g.data.Foods, f)
+
+			menu[template.name] = append(menu[template.name], choice{food: f, weight: food.popularity})
+		}
+	}
+
+	return menu
+}
+
+// sizes prices the sizes of a food from its price , the medium size is the price itself and isn't listed
+func sizes(price float64, sold []string) map[string]models.SizePrice {
+
+	sizes := map[string]models.SizePrice{}
+
+	for i, size := range models.SIZES {
+
+		available := false
+
+		for _, s := range sold {
+			available = available || s == size
+		}
+
+		if !available {
+			sizes[size] = models.SizePrice{Available: &available}
+			continue
+		}
+
+		if SIZE_FACTORS[i] != 1 {
+			sizePrice := math.Round(price*SIZE_FACTORS[i]*2) / 2
+			sizes[size] = models.SizePrice{Price: &sizePrice}
+		}
+	}
+
+	return sizes
+}
+
+func (g *generator) tables(at time.Time, branchId string) []models.Table {
+
+	tables := []models.Table{}
+
+	for number := 1; number <= TABLES; number++ {
+
+		// mostly tables for two and four , a few large ones at the end
+		guests := []int{2, 2, 4, 4, 4, 6}[g.rand.Intn(6)]
+		if number > TABLES-2 {
+			guests = 8
+		}
+
+		tableNumber := number
+
+		t := models.Table{ID: g.objectId(at), Number_of_guests: &guests, Table_number: &tableNumber, Created_at: at, Updated_at: at, Version: 1, Branch_id: branchId}
+		t.Table_id = t.ID.Hex()
+		tables = append(tables, t)
+	}
+
+	g.data.Tables = append(g.data.Tables, tables...)
+
+	return tables
+}
+
+// day adds the orders of one day , there are more of them at the weekend and most come in around lunch and dinner
+func (g *generator) day(day time.Time, until time.Time, busy float64, branchId string, menu map[string][]choice, tables []models.Table) {
+
+	perDay := []float64{18, 10, 11, 12, 15, 24, 28}[day.Weekday()] * busy
+	count := int(perDay*(0.8+g.rand.Float64()*0.4) + 0.5)
+
+	times := make([]time.Time, count)
+
+	for i := range times {
+		times[i] = g.orderTime(day)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	for _, at := range times {
+		table := tables[g.rand.Intn(len(tables))]
+		g.order(at, day.Equal(until), branchId, menu, table)
+	}
+}
+
+// orderTime is a time of the lunch or the dinner service , the kitchen is open from 11:30 to 22:30
+func (g *generator) orderTime(day time.Time) time.Time {
+
+	minutes := 19.5*60 + g.rand.NormFloat64()*60
+
+	if g.rand.Float64() < 0.35 {
+		minutes = 12.75*60 + g.rand.NormFloat64()*40
+	}
+
+	minutes = math.Max(11.5*60, math.Min(22.5*60, minutes))
+
+	return day.Add(time.Duration(minutes)*time.Minute + time.Duration(g.rand.Intn(60))*time.Second)
+}
+
+func (g *generator) order(at time.Time, lastDay bool, branchId string, menu map[string][]choice, table models.Table) {
+
+	tableId := table.Table_id
+
+	order := models.Order{ID: g.objectId(at), Order_date: at, Created_at: at, Updated_at: at, Version: 1, Table_id: &tableId, Branch_id: branchId}
+	order.Order_id = order.ID.Hex()
+
+	guests := 1 + g.rand.Intn(*table.Number_of_guests)
+	items := []models.OrderItem{}
+
+	// every guest orders from each menu with its chance , the drinks can come twice
+	for guest := 0; guest < guests; guest++ {
+		for _, template := range MENUS {
+
+			chance := template.per_guest
+
+			for chance > 0 {
+
+				if g.rand.Float64() < chance {
+					items = append(items, g.orderItem(at.Add(time.Duration(len(items))*time.Second), order.Order_id, branchId, g.pick(menu[template.name])))
+				}
+
+				chance--
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		items = append(items, g.orderItem(at, order.Order_id, branchId, g.pick(menu[MENUS[len(MENUS)-1].name])))
+	}
+
+	g.data.Orders = append(g.data.Orders, order)
+	g.data.Order_items[order.Order_id] = items
+
+	// the bill comes 40 to 100 minutes after the order , on the last day the late tables haven't paid yet
+	billed := at.Add(time.Duration(40+g.rand.Intn(60)) * time.Minute)
+	status := "PAID"
+	method := "CARD"
+
+	if g.rand.Float64() < 0.25 {
+		method = "CASH"
+	}
+
+	if lastDay && at.Hour() >= 19 {
+		status = "PENDING"
+	}
+
+	invoice := models.Invoice{ID: g.objectId(billed), Order_id: order.Order_id, Payment_method: &method, Payment_status: &status, Payment_due_date: billed.AddDate(0, 0, 1), Created_at: billed, Updated_at: billed, Version: 1, Branch_id: branchId}
+	invoice.Invoice_id = invoice.ID.Hex()
+
+	if status == "PENDING" {
+		invoice.Payment_method = nil
+	}
+
+	g.data.Invoices = append(g.data.Invoices, invoice)
+}
+
+func (g *generator) orderItem(at time.Time, orderId string, branchId string, food models.Food) models.OrderItem {
+
+	// only the sizes the food is sold in are picked
+	weights := make([]int, len(models.SIZES))
+
+	for i, size := range models.SIZES {
+		if _, ok := food.PriceOf(size); ok {
+			weights[i] = SIZE_WEIGHTS[i]
+		}
+	}
+
+	quantity := models.SIZES[g.weighted(weights)]
+	price, _ := food.PriceOf(quantity)
+	foodId := food.Food_id
+
+	item := models.OrderItem{ID: g.objectId(at), Quantity: &quantity, Created_at: at, Updated_at: at, Version: 1, Food_id: &foodId, Order_id: orderId, Unit_price: &price, Branch_id: branchId}
+	item.Order_item_id = item.ID.Hex()
+
+	return item
+}
+
+func (g *generator) user(at time.Time, person staffTemplate, role string, branchId *string, email string) models.User {
+
+	firstName, lastName := person.first_name, person.last_name
+	password := g.opts.Password_hash
+	// the phone numbers only have to be unique , they are counted up from the number of users
+	phone := fmt.Sprintf("+44 7700 9%05d", len(g.data.Users))
+
+	user := models.User{ID: g.objectId(at), First_name: &firstName, Last_name: &lastName, Password: &password, Email: &email, Phone: &phone, Role: &role, Branch_id: branchId, Created_at: at, Updated_at: at, Version: 1}
+	user.User_id = user.ID.Hex()
+
+	return user
+}
+
+func (g *generator) pick(choices []choice) models.Food {
+
+	weights := make([]int, len(choices))
+
+	for i, c := range choices {
+		weights[i] = c.weight
+	}
+
+	return choices[g.weighted(weights)].food
+}
+
+// weighted returns the index of a weight , picked in proportion to it
+func (g *generator) weighted(weights []int) int {
+
+	total := 0
+
+	for _, weight := range weights {
+		total += weight
+	}
+
+	n := g.rand.Intn(total)
+
+	for i, weight := range weights {
+		if n < weight {
+			return i
+		}
+		n -= weight
+	}
+
+	return len(weights) - 1
+}
+
+// objectId is an ObjectID of the time at , the rest of it comes from the seed instead of the machine and a counter
+func (g *generator) objectId(at time.Time) primitive.ObjectID {
+
+	var id primitive.ObjectID
+
+	binary.BigEndian.PutUint32(id[0:4], uint32(at.Unix()))
+	binary.BigEndian.PutUint64(id[4:12], g.rand.Uint64())
+
+	return id
+}
+
+func slugify(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), " ", "-")
+}
@@ -0,0 +1,128 @@
+package seed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func generate(t *testing.T, until string, weeks int) *Data {
+
+	day, err := time.Parse("2006-01-02", until)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Generate(Options{Seed: DEFAULT_SEED, Branches: DEFAULT_BRANCHES, Weeks: weeks, Until: day, Password_hash: "hash"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+
+	first, second := generate(t, DEFAULT_UNTIL, DEFAULT_WEEKS), generate(t, DEFAULT_UNTIL, DEFAULT_WEEKS)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("the same options gave different data")
+	}
+
+	if len(first.Orders) == 0 {
+		t.Fatalf("no orders were generated")
+	}
+}
+
+// a later until only adds the orders of the new days , so seeding again after a week doesn't collide with what is stored
+func TestGenerateWithALaterUntilKeepsTheEarlierData(t *testing.T) {
+
+	earlier := generate(t, DEFAULT_UNTIL, DEFAULT_WEEKS)
+	later := generate(t, "2025-07-06", DEFAULT_WEEKS+1)
+
+	if !reflect.DeepEqual(earlier.Branches, later.Branches) || !reflect.DeepEqual(earlier.Users, later.Users) || !reflect.DeepEqual(earlier.Menus, later.Menus) ||
+		!reflect.DeepEqual(earlier.Foods, later.Foods) || !reflect.DeepEqual(earlier.Tables, later.Tables) {
+		t.Fatalf("the catalogue changed with the until")
+	}
+
+	laterOrders := map[string]int{}
+	for i, order := range later.Orders {
+		laterOrders[order.Order_id] = i
+	}
+
+	// the orders of the last day were still open in the earlier data , the later data has them paid
+	lastDay, _ := time.Parse("2006-01-02", DEFAULT_UNTIL)
+
+	for _, order := range earlier.Orders {
+		i, ok := laterOrders[order.Order_id]
+
+		if !ok {
+			t.Fatalf("the order %s of %s is missing from the later data", order.Order_id, order.Order_date.Format("2006-01-02"))
+		}
+
+		if order.Order_date.Before(lastDay) && !reflect.DeepEqual(order, later.Orders[i]) {
+			t.Fatalf("the order %s changed with the until", order.Order_id)
+		}
+
+		if order.Order_date.Before(lastDay) && !reflect.DeepEqual(earlier.Order_items[order.Order_id], later.Order_items[order.Order_id]) {
+			t.Fatalf("the items of the order %s changed with the until", order.Order_id)
+		}
+	}
+}
+
+func TestGenerateHasUniqueIdsAndEmails(t *testing.T) {
+
+	data := generate(t, DEFAULT_UNTIL, DEFAULT_WEEKS)
+
+	ids := map[string]bool{}
+	unique := func(id string) {
+		t.Helper()
+
+		if ids[id] {
+			t.Fatalf("the id %s is there twice", id)
+		}
+
+		ids[id] = true
+	}
+
+	emails := map[string]bool{}
+
+	for _, user := range data.Users {
+		unique(user.User_id)
+
+		if emails[*user.Email] {
+			t.Fatalf("the email %s is there twice", *user.Email)
+		}
+
+		emails[*user.Email] = true
+	}
+
+	for _, branch := range data.Branches {
+		unique(branch.Branch_id)
+	}
+
+	for _, food := range data.Foods {
+		unique(food.Food_id)
+	}
+
+	for _, order := range data.Orders {
+		unique(order.Order_id)
+
+		for _, item := range data.Order_items[order.Order_id] {
+			unique(item.Order_item_id)
+		}
+	}
+
+	for _, invoice := range data.Invoices {
+		unique(invoice.Invoice_id)
+	}
+}
+
+func TestGenerateRefusesOrdersBeforeTheCatalogue(t *testing.T) {
+
+	if _, err := Generate(Options{Seed: DEFAULT_SEED, Branches: 1, Weeks: 1, Until: CATALOGUE_CREATED}); err == nil {
+		t.Fatalf("orders before the catalogue was set up were generated")
+	}
+}
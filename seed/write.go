@@ -0,0 +1,101 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-restaurent-management-system/models"
+	"go-restaurent-management-system/repository"
+)
+
+// Result is how many records of a collection were created , the ones that were already stored are skipped
+type Result struct {
+	Collection string
+	Created    int
+	Skipped    int
+}
+
+// Write stores the demo data , the records are looked up by their ids first.
+// seeding the same options again only fills in what is missing , the records that were changed through the API keep the changes
+func Write(ctx context.Context, store *repository.Store, data *Data) ([]Result, error) {
+
+	results := []Result{}
+
+	steps := []func() (Result, error){
+		func() (Result, error) {
+			return insert("branches", data.Branches, func(b *models.Branch) string { return b.Branch_id },
+				func(id string) error { _, err := store.Branches.Get(ctx, id); return err },
+				func(b *models.Branch) error { return store.Branches.Create(ctx, b) })
+		},
+		func() (Result, error) {
+			return insert("users", data.Users, func(u *models.User) string { return u.User_id },
+				func(id string) error { _, err := store.Users.Get(ctx, "", id); return err },
+				func(u *models.User) error { return store.Users.Create(ctx, u) })
+		},
+		func() (Result, error) {
+			return insert("menus", data.Menus, func(m *models.Menu) string { return m.Menu_id },
+				func(id string) error { _, err := store.Menus.Get(ctx, "", id, true); return err },
+				func(m *models.Menu) error { return store.Menus.Create(ctx, m) })
+		},
+		func() (Result, error) {
+			return insert("foods", data.Foods, func(f *models.Food) string { return f.Food_id },
+				func(id string) error { _, err := store.Foods.Get(ctx, "", id, true); return err },
+				func(f *models.Food) error { return store.Foods.Create(ctx, f) })
+		},
+		func() (Result, error) {
+			return insert("tables", data.Tables, func(t *models.Table) string { return t.Table_id },
+				func(id string) error { _, err := store.Tables.Get(ctx, "", id, true); return err },
+				func(t *models.Table) error { return store.Tables.Create(ctx, t) })
+		},
+		// an order is stored together with its items , like POST /orders does
+		func() (Result, error) {
+			return insert("orders", data.Orders, func(o *models.Order) string { return o.Order_id },
+				func(id string) error { _, err := store.Orders.Get(ctx, "", id, true); return err },
+				func(o *models.Order) error { return store.Orders.CreateWithItems(ctx, o, data.Order_items[o.Order_id]) })
+		},
+		func() (Result, error) {
+			return insert("invoices", data.Invoices, func(i *models.Invoice) string { return i.Invoice_id },
+				func(id string) error { _, err := store.Invoices.Get(ctx, "", id, true); return err },
+				func(i *models.Invoice) error { return store.Invoices.Create(ctx, i) })
+		},
+	}
+
+	for _, step := range steps {
+
+		result, err := step()
+		results = append(results, result)
+
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+func insert[T any](name string, records []T, id func(record *T) string, get func(id string) error, create func(record *T) error) (Result, error) {
+
+	result := Result{Collection: name}
+
+	for i := range records {
+
+		err := get(id(&records[i]))
+
+		if err == nil {
+			result.Skipped++
+			continue
+		}
+
+		if !errors.Is(err, repository.ErrNotFound) {
+			return result, fmt.Errorf("%s %s: %w", name, id(&records[i]), err)
+		}
+
+		if err := create(&records[i]); err != nil {
+			return result, fmt.Errorf("%s %s: %w", name, id(&records[i]), err)
+		}
+
+		result.Created++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"go-restaurent-management-system/config"
+	"go-restaurent-management-system/database"
+	"go-restaurent-management-system/migrations"
+	"go-restaurent-management-system/repository"
+	"go-restaurent-management-system/repository/mongorepo"
+	"go-restaurent-management-system/repository/sqlrepo"
+	"log"
+	"time"
+)
+
+// building the indexes of a large collection for the first time can take a while
+const STARTUP_TIMEOUT = 5 * time.Minute
+
+// Open connects to the backend of the configuration and prepares its indexes or tables ,
+// the returned function closes the connections again. the server and the commands in cmd/ all open their store here
+func Open(cfg *config.Config) (*repository.Store, func(ctx context.Context) error, error) {
+
+	startupCtx, cancelStartup := context.WithTimeout(context.Background(), STARTUP_TIMEOUT)
+	defer cancelStartup()
+
+	if cfg.Backend == config.BACKEND_SQLITE || cfg.Backend == config.BACKEND_POSTGRES {
+
+		db, err := sqlrepo.Open(startupCtx, cfg.Backend, cfg.Sql)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the tables are created on the first start , later starts only add what is missing
+
+		if err := sqlrepo.EnsureSchema(startupCtx, db); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		return sqlrepo.NewStore(db), func(ctx context.Context) error { return db.Close() }, nil
+	}
+
+	client, err := database.Connect(cfg.Mongo)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db := client.Database(cfg.Mongo.Database)
+
+	// the server doesn't migrate the database itself , it only points out that cmd/migrate still has work to do
+
+	if statuses, err := migrations.Statuses(startupCtx, db); err == nil {
+		for _, status := range statuses {
+			if status.Applied_at == nil {
+				log.Printf("schema migration %d %s is pending , run: go run ./cmd/migrate up", status.Version, status.Name)
+			}
+		}
+	}
+
+	// the indexes are checked on every start , the unique ones have to exist before the first write
+
+	if err := mongorepo.EnsureIndexes(startupCtx, db); err != nil {
+		client.Disconnect(context.Background())
+		return nil, nil, err
+	}
+
+	return mongorepo.NewStore(db), client.Disconnect, nil
+}